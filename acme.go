@@ -0,0 +1,303 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	acmeChallengeLabel = "_acme-challenge"
+
+	defaultACMETTL                = 60
+	defaultACMEPropagationTimeout = 2 * time.Minute
+	defaultACMEPollingInterval    = 5 * time.Second
+)
+
+// TXTResolver looks up TXT records. *net.Resolver satisfies the interface.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DNS01Solver solves ACME DNS-01 challenges (RFC 8555, section 8.4) for names hosted on rcode0.
+// Its Present, CleanUp and Timeout methods match the challenge provider interface of common
+// ACME clients like lego.
+type DNS01Solver struct {
+	client *Client
+
+	// TTL of the challenge TXT rrsets (defaults to 60)
+	TTL int
+
+	// Resolver used to wait until the challenge record is visible. If nil, Present does not wait.
+	Resolver TXTResolver
+
+	// PropagationTimeout and PollingInterval control the wait for the challenge record
+	PropagationTimeout time.Duration
+	PollingInterval    time.Duration
+
+	// Serializes the read-modify-write of the challenge rrsets
+	mu sync.Mutex
+
+	// Cache of the rcode0 zone of each challenge name
+	zones map[string]string
+}
+
+// NewDNS01Solver returns a DNS-01 solver using the given client
+func NewDNS01Solver(client *Client) *DNS01Solver {
+	return &DNS01Solver{
+		client:             client,
+		TTL:                defaultACMETTL,
+		PropagationTimeout: defaultACMEPropagationTimeout,
+		PollingInterval:    defaultACMEPollingInterval,
+		zones:              make(map[string]string),
+	}
+}
+
+// NewDNSResolver returns a TXTResolver querying the given name server (f.e. "193.0.2.53:53")
+// instead of the system resolver
+func NewDNSResolver(nameserver string) TXTResolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, nameserver)
+		},
+	}
+}
+
+// ChallengeRecordName returns the name of the challenge TXT rrset for the domain.
+// Wildcard domains share the challenge name of their base domain.
+func ChallengeRecordName(domain string) string {
+	return NormalizeName(acmeChallengeLabel + "." + strings.TrimPrefix(domain, "*."))
+}
+
+// ChallengeRecordValue returns the TXT value for the key authorization (RFC 8555, section 8.4)
+func ChallengeRecordValue(keyAuth string) string {
+	sum := sha256.Sum256([]byte(keyAuth))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Present adds the challenge value to the TXT rrset of the domain. Existing values (f.e. of a
+// wildcard and an apex challenge running at the same time) are kept. If a Resolver is set,
+// Present waits until the value is visible.
+func (s *DNS01Solver) Present(domain, token, keyAuth string) error {
+
+	ctx := context.Background()
+
+	name := ChallengeRecordName(domain)
+	value := ChallengeRecordValue(keyAuth)
+
+	err := s.modify(ctx, name, func(values []string) []string {
+		for _, v := range values {
+			if v == value {
+				return values
+			}
+		}
+		return append(values, value)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if s.Resolver == nil {
+		return nil
+	}
+
+	return s.WaitForRecord(ctx, domain, keyAuth)
+}
+
+// CleanUp removes the challenge value from the TXT rrset of the domain and deletes the
+// rrset once it is empty
+func (s *DNS01Solver) CleanUp(domain, token, keyAuth string) error {
+
+	value := ChallengeRecordValue(keyAuth)
+
+	return s.modify(context.Background(), ChallengeRecordName(domain), func(values []string) []string {
+		var kept []string
+		for _, v := range values {
+			if v != value {
+				kept = append(kept, v)
+			}
+		}
+		return kept
+	})
+}
+
+// Timeout returns the propagation timeout and polling interval
+func (s *DNS01Solver) Timeout() (timeout, interval time.Duration) {
+	return s.PropagationTimeout, s.PollingInterval
+}
+
+// WaitForRecord polls the Resolver until the challenge value of the domain is visible
+func (s *DNS01Solver) WaitForRecord(ctx context.Context, domain, keyAuth string) error {
+
+	if s.Resolver == nil {
+		return fmt.Errorf("no resolver configured")
+	}
+
+	name := ChallengeRecordName(domain)
+	value := ChallengeRecordValue(keyAuth)
+
+	timeout, interval := s.Timeout()
+	if timeout <= 0 {
+		timeout = defaultACMEPropagationTimeout
+	}
+	if interval <= 0 {
+		interval = defaultACMEPollingInterval
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		values, _ := s.Resolver.LookupTXT(ctx, name)
+		for _, v := range values {
+			if v == value {
+				return nil
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("challenge record %s not visible after %v", name, timeout)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// FindZone returns the rcode0 zone the name belongs to by walking up its labels
+func (s *DNS01Solver) FindZone(name string) (string, error) {
+
+	name = NormalizeName(name)
+
+	s.mu.Lock()
+	zone, ok := s.zones[name]
+	s.mu.Unlock()
+
+	if ok {
+		return zone, nil
+	}
+
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+
+	for i := 0; i < len(labels)-1; i++ {
+
+		candidate := strings.Join(labels[i:], ".")
+
+		z, err := s.client.Zones.Get(candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if z != nil && z.Domain != "" {
+			s.mu.Lock()
+			s.zones[name] = z.Domain
+			s.mu.Unlock()
+			return z.Domain, nil
+		}
+	}
+
+	return "", fmt.Errorf("no rcode0 zone found for %s", name)
+}
+
+// modify replaces the values of the challenge rrset with the result of update.
+// Records which were disabled stay disabled.
+func (s *DNS01Solver) modify(ctx context.Context, name string, update func([]string) []string) error {
+
+	zone, err := s.FindZone(name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var values []string
+
+	rrset, err := s.client.RRSet.Get(ctx, zone, name, "TXT")
+
+	switch {
+	case err == nil:
+		if values, err = rrset.TXT(); err != nil {
+			return err
+		}
+	case !IsRRSetNotFound(err):
+		return err
+	}
+
+	updated := update(values)
+
+	if equalStrings(values, updated) {
+		return nil
+	}
+
+	change := &RRSetChange{Name: name, Type: "TXT", TTL: s.TTL}
+
+	switch {
+	case len(updated) == 0:
+		change.ChangeType = ChangeTypeDELETE
+	case rrset == nil:
+		change.ChangeType = ChangeTypeADD
+		change.AddTXT(updated...)
+	default:
+		change.ChangeType = ChangeTypeUPDATE
+		change.AddTXT(updated...)
+		keepDisabled(change.Records, updated, rrset.Records, values)
+	}
+
+	status, err := s.client.RRSet.SubmitChangeSet(zone, []*RRSetChange{change})
+	if err != nil {
+		return err
+	}
+
+	if status.HasError() {
+		return fmt.Errorf("challenge record %s: %s", name, status.Message)
+	}
+
+	return nil
+}
+
+// keepDisabled marks the records which were disabled before as disabled again.
+// values holds the texts of the current records, texts those of the new records.
+func keepDisabled(records []*Record, texts []string, current []*Record, values []string) {
+
+	disabled := make(map[string]int)
+	for i, r := range current {
+		if r.Disabled {
+			disabled[values[i]]++
+		}
+	}
+
+	for i, text := range texts {
+		if disabled[text] > 0 {
+			disabled[text]--
+			records[i].Disabled = true
+		}
+	}
+}
+
+func equalStrings(a []string, b []string) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,189 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type staticResolver map[string][]string
+
+func (r staticResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return r[name], nil
+}
+
+func setupACME(t *testing.T, zone *fakeZone) (*DNS01Solver, func()) {
+
+	client, m, _, teardown := setup()
+
+	sampleZone, _ := json.Marshal(getSampleZone())
+
+	m.HandleFunc(RC0Zone, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+
+		if mux.Vars(r)["zone"] != "testzone1.at" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = fmt.Fprint(w, `{"message": "Zone not found"}`)
+			return
+		}
+
+		_, _ = fmt.Fprint(w, string(sampleZone))
+	})
+
+	m.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	return NewDNS01Solver(client), teardown
+}
+
+func TestChallengeRecord(t *testing.T) {
+
+	if got := ChallengeRecordName("*.WWW.testzone1.at"); got != "_acme-challenge.www.testzone1.at." {
+		t.Errorf("ChallengeRecordName returned %q", got)
+	}
+
+	// example from RFC 8555, section 8.1 and 8.4
+	keyAuth := "evaGxfADs6pSRb2LAv9IZf17Dt3juxGJ-PCt92wr-oA.nP1qzpXGymHBrUEepNY9HCsQk7K8KhOypzEt62jcerQ"
+
+	if got := ChallengeRecordValue(keyAuth); len(got) != 43 {
+		t.Errorf("ChallengeRecordValue returned %q, want 43 characters", got)
+	}
+}
+
+func TestDNS01Solver_FindZone(t *testing.T) {
+
+	solver, teardown := setupACME(t, &fakeZone{})
+	defer teardown()
+
+	zone, err := solver.FindZone("_acme-challenge.www.testzone1.at.")
+	if err != nil {
+		t.Fatalf("DNS01Solver.FindZone returned error: %v", err)
+	}
+
+	if zone != "testzone1.at" {
+		t.Errorf("DNS01Solver.FindZone returned %q, want %q", zone, "testzone1.at")
+	}
+
+	if _, err := solver.FindZone("_acme-challenge.testzone2.at."); err == nil {
+		t.Errorf("DNS01Solver.FindZone returned no error for a name without zone")
+	}
+}
+
+func TestDNS01Solver_PresentCleanUp(t *testing.T) {
+
+	zone := &fakeZone{}
+
+	solver, teardown := setupACME(t, zone)
+	defer teardown()
+
+	name := "_acme-challenge.testzone1.at."
+	apex := ChallengeRecordValue("apex")
+	wildcard := ChallengeRecordValue("wildcard")
+
+	if err := solver.Present("testzone1.at", "t1", "apex"); err != nil {
+		t.Fatalf("DNS01Solver.Present returned error: %v", err)
+	}
+
+	if err := solver.Present("*.testzone1.at", "t2", "wildcard"); err != nil {
+		t.Fatalf("DNS01Solver.Present returned error: %v", err)
+	}
+
+	rrset, err := solver.client.RRSet.Get(context.Background(), "testzone1.at", name, "TXT")
+	if err != nil {
+		t.Fatalf("RRSet.Get returned error: %v", err)
+	}
+
+	values, _ := rrset.TXT()
+	if want := []string{apex, wildcard}; !reflect.DeepEqual(values, want) {
+		t.Errorf("DNS01Solver.Present stored %v, want %v", values, want)
+	}
+
+	if got := zone.patches[1][0].ChangeType; got != ChangeTypeUPDATE {
+		t.Errorf("DNS01Solver.Present submitted %s, want %s", got, ChangeTypeUPDATE)
+	}
+
+	if err := solver.CleanUp("testzone1.at", "t1", "apex"); err != nil {
+		t.Fatalf("DNS01Solver.CleanUp returned error: %v", err)
+	}
+
+	rrset, _ = solver.client.RRSet.Get(context.Background(), "testzone1.at", name, "TXT")
+	if values, _ = rrset.TXT(); !reflect.DeepEqual(values, []string{wildcard}) {
+		t.Errorf("DNS01Solver.CleanUp kept %v, want %v", values, []string{wildcard})
+	}
+
+	if err := solver.CleanUp("*.testzone1.at", "t2", "wildcard"); err != nil {
+		t.Fatalf("DNS01Solver.CleanUp returned error: %v", err)
+	}
+
+	if _, err := solver.client.RRSet.Get(context.Background(), "testzone1.at", name, "TXT"); !IsRRSetNotFound(err) {
+		t.Errorf("DNS01Solver.CleanUp did not delete the empty rrset: %v", err)
+	}
+
+	if len(zone.patches) != 4 {
+		t.Errorf("DNS01Solver submitted %d change sets, want 4", len(zone.patches))
+	}
+}
+
+func TestDNS01Solver_KeepsDisabledRecords(t *testing.T) {
+
+	name := "_acme-challenge.testzone1.at."
+
+	zone := &fakeZone{rrsets: []*RRType{
+		{Name: name, Type: "TXT", TTL: 60, Records: []*Record{{Content: `"parked"`, Disabled: true}}},
+	}}
+
+	solver, teardown := setupACME(t, zone)
+	defer teardown()
+
+	if err := solver.Present("testzone1.at", "t1", "apex"); err != nil {
+		t.Fatalf("DNS01Solver.Present returned error: %v", err)
+	}
+
+	want := []*Record{{Content: `"parked"`, Disabled: true}, {Content: QuoteTXT(ChallengeRecordValue("apex"))}}
+
+	if got := zone.patches[0][0].Records; !reflect.DeepEqual(got, want) {
+		t.Errorf("DNS01Solver.Present submitted %+v, want %+v", got, want)
+	}
+
+	if err := solver.CleanUp("testzone1.at", "t1", "apex"); err != nil {
+		t.Fatalf("DNS01Solver.CleanUp returned error: %v", err)
+	}
+
+	want = []*Record{{Content: `"parked"`, Disabled: true}}
+
+	if got := zone.patches[1][0].Records; !reflect.DeepEqual(got, want) {
+		t.Errorf("DNS01Solver.CleanUp submitted %+v, want %+v", got, want)
+	}
+}
+
+func TestDNS01Solver_Wait(t *testing.T) {
+
+	solver, teardown := setupACME(t, &fakeZone{})
+	defer teardown()
+
+	solver.PropagationTimeout = 50 * time.Millisecond
+	solver.PollingInterval = 10 * time.Millisecond
+
+	solver.Resolver = staticResolver{}
+
+	if err := solver.Present("testzone1.at", "t1", "apex"); err == nil {
+		t.Errorf("DNS01Solver.Present returned no error for an invisible record")
+	}
+
+	solver.Resolver = staticResolver{
+		"_acme-challenge.testzone1.at.": {ChallengeRecordValue("apex")},
+	}
+
+	if err := solver.Present("testzone1.at", "t1", "apex"); err != nil {
+		t.Errorf("DNS01Solver.Present returned error: %v", err)
+	}
+}
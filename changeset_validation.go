@@ -0,0 +1,199 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Severity of a change set finding
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityInfo    Severity = "info"
+)
+
+// Change set finding codes
+const (
+	FindingInvalidRecord   = "invalid-record"
+	FindingDuplicateChange = "duplicate-change"
+	FindingCNAMEConflict   = "cname-conflict"
+	FindingDeleteMissing   = "delete-missing"
+	FindingAddExisting     = "add-existing"
+	FindingUpdateMissing   = "update-missing"
+	FindingApexSOA         = "apex-soa"
+	FindingApexNS          = "apex-ns"
+	FindingApexCNAME       = "apex-cname"
+	FindingOutOfZone       = "out-of-zone"
+)
+
+// ChangeSetFinding is a problem found in a change set before it is submitted
+type ChangeSetFinding struct {
+	Severity Severity
+	Code     string
+	Name     string
+	Type     string
+	Message  string
+}
+
+func (f *ChangeSetFinding) String() string {
+	return fmt.Sprintf("%s: %s %s: %s (%s)", f.Severity, f.Name, f.Type, f.Message, f.Code)
+}
+
+// ChangeSetFindings is the result of a change set validation
+type ChangeSetFindings []*ChangeSetFinding
+
+// HasErrors reports whether any finding has error severity
+func (f ChangeSetFindings) HasErrors() bool {
+
+	for _, finding := range f {
+		if finding.Severity == SeverityError {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateChangeSet checks a change set against the current content of the zone from
+// RRSetService.ListAll before anything is sent (see CheckChangeSet).
+func (s *RRSetService) ValidateChangeSet(ctx context.Context, zone string, changeSet []*RRSetChange) (ChangeSetFindings, error) {
+
+	current, err := s.ListAll(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return CheckChangeSet(zone, current, changeSet), nil
+}
+
+// CheckChangeSet checks a change set against the current rrsets of the zone. It reports
+// malformed records, several changes of the same rrset, CNAMEs next to other data at the
+// same name, deletes and updates of missing rrsets, adds of existing rrsets, changes to
+// the apex SOA and NS rrsets (which are managed by rcode0) and names outside of the zone.
+func CheckChangeSet(zone string, current []*RRType, changeSet []*RRSetChange) ChangeSetFindings {
+
+	var findings ChangeSetFindings
+
+	add := func(severity Severity, code string, name string, rrType string, format string, args ...interface{}) {
+		findings = append(findings, &ChangeSetFinding{
+			Severity: severity,
+			Code:     code,
+			Name:     name,
+			Type:     rrType,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	apex := NormalizeName(zone)
+
+	// types per name after the change set was applied
+	result := make(map[string]map[string]bool)
+	existing := make(map[rrsetKey]bool)
+
+	for _, rrset := range current {
+		k := rrsetKey{name: NormalizeName(rrset.Name), rrType: strings.ToUpper(rrset.Type)}
+		existing[k] = true
+		if result[k.name] == nil {
+			result[k.name] = make(map[string]bool)
+		}
+		result[k.name][k.rrType] = true
+	}
+
+	seen := make(map[rrsetKey]bool)
+
+	for _, change := range changeSet {
+
+		name, rrType := NormalizeName(change.Name), strings.ToUpper(change.Type)
+		k := rrsetKey{name: name, rrType: rrType}
+
+		if err := change.Validate(); err != nil {
+			add(SeverityError, FindingInvalidRecord, name, rrType, "%v", err)
+		}
+
+		if name != apex && !strings.HasSuffix(name, "."+apex) {
+			add(SeverityError, FindingOutOfZone, name, rrType, "name is outside of zone %s", apex)
+		}
+
+		if seen[k] {
+			add(SeverityError, FindingDuplicateChange, name, rrType, "rrset is changed more than once")
+		}
+		seen[k] = true
+
+		if name == apex {
+			switch rrType {
+			case "SOA":
+				add(SeverityError, FindingApexSOA, name, rrType, "the apex SOA is managed by rcode0")
+			case "NS":
+				add(SeverityWarning, FindingApexNS, name, rrType, "the apex NS rrset is managed by rcode0")
+			case "CNAME":
+				if change.ChangeType != ChangeTypeDELETE {
+					add(SeverityError, FindingApexCNAME, name, rrType, "a CNAME is not allowed at the zone apex")
+				}
+			}
+		}
+
+		switch change.ChangeType {
+
+		case ChangeTypeDELETE:
+			if !existing[k] {
+				add(SeverityWarning, FindingDeleteMissing, name, rrType, "rrset to delete does not exist")
+			}
+			if result[name] != nil {
+				delete(result[name], rrType)
+			}
+			existing[k] = false
+
+		case ChangeTypeADD, ChangeTypeUPDATE:
+			if change.ChangeType == ChangeTypeADD && existing[k] {
+				add(SeverityWarning, FindingAddExisting, name, rrType, "rrset to add already exists and is replaced")
+			}
+			if change.ChangeType == ChangeTypeUPDATE && !existing[k] {
+				add(SeverityInfo, FindingUpdateMissing, name, rrType, "rrset to update does not exist and is created")
+			}
+			if result[name] == nil {
+				result[name] = make(map[string]bool)
+			}
+			result[name][rrType] = true
+			existing[k] = true
+		}
+	}
+
+	// CNAME and other data (RFC 1034, section 3.6.2). DNSSEC types may coexist with a CNAME.
+	reported := make(map[string]bool)
+
+	for _, change := range changeSet {
+
+		name := NormalizeName(change.Name)
+		types := result[name]
+
+		if !types["CNAME"] || change.ChangeType == ChangeTypeDELETE || reported[name] {
+			continue
+		}
+
+		var others []string
+		for rrType := range types {
+			switch rrType {
+			case "CNAME", "RRSIG", "NSEC", "NSEC3":
+			default:
+				others = append(others, rrType)
+			}
+		}
+
+		if len(others) > 0 {
+			sort.Strings(others)
+			reported[name] = true
+			add(SeverityError, FindingCNAMEConflict, name, "CNAME", "CNAME and other data (%s) at the same name", strings.Join(others, ", "))
+		}
+	}
+
+	return findings
+}
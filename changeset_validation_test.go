@@ -0,0 +1,113 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"sort"
+	"testing"
+)
+
+func TestCheckChangeSet(t *testing.T) {
+
+	current := []*RRType{
+		{Name: "testzone1.at.", Type: "NS", TTL: 86400, Records: []*Record{{Content: "sec1.rcode0.net."}}},
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+		{Name: "mail.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.2"}}},
+	}
+
+	changeSet := []*RRSetChange{
+		{Name: "www.testzone1.at.", Type: "CNAME", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "web.testzone1.at."}}},
+		{Name: "ftp.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "10.10.0.3"}}},
+		{Name: "ftp.testzone1.at.", Type: "A", ChangeType: ChangeTypeUPDATE, TTL: 3600, Records: []*Record{{Content: "10.10.0.4"}}},
+		{Name: "gone.testzone1.at.", Type: "A", ChangeType: ChangeTypeDELETE},
+		{Name: "testzone1.at.", Type: "SOA", ChangeType: ChangeTypeUPDATE, TTL: 3600, Records: []*Record{{Content: "a. b. 1 2 3 4 5"}}},
+		{Name: "testzone1.at.", Type: "NS", ChangeType: ChangeTypeUPDATE, TTL: 3600, Records: []*Record{{Content: "ns1.example.net."}}},
+		{Name: "www.example.net.", Type: "A", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "10.10.0.5"}}},
+		{Name: "bad.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "not-an-ip"}}},
+	}
+
+	findings := CheckChangeSet("testzone1.at", current, changeSet)
+
+	var codes []string
+	for _, f := range findings {
+		codes = append(codes, f.Code)
+	}
+	sort.Strings(codes)
+
+	want := []string{
+		FindingApexNS,
+		FindingApexSOA,
+		FindingCNAMEConflict,
+		FindingDeleteMissing,
+		FindingDuplicateChange,
+		FindingInvalidRecord,
+		FindingOutOfZone,
+		FindingUpdateMissing,
+	}
+
+	if len(codes) != len(want) {
+		t.Fatalf("CheckChangeSet returned %v, want %v", codes, want)
+	}
+
+	for i := range want {
+		if codes[i] != want[i] {
+			t.Errorf("CheckChangeSet returned %v, want %v", codes, want)
+			break
+		}
+	}
+
+	if !findings.HasErrors() {
+		t.Errorf("CheckChangeSet findings have no errors")
+	}
+}
+
+func TestCheckChangeSet_CNAMEReplacement(t *testing.T) {
+
+	current := []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+	}
+
+	// Replacing an A rrset by a CNAME within the same change set is fine
+	changeSet := []*RRSetChange{
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeDELETE},
+		{Name: "www.testzone1.at.", Type: "CNAME", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "web.testzone1.at."}}},
+	}
+
+	if findings := CheckChangeSet("testzone1.at", current, changeSet); len(findings) != 0 {
+		t.Errorf("CheckChangeSet returned %v", findings)
+	}
+}
+
+func TestRRSetService_ValidateChangeSet(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	findings, err := client.RRSet.ValidateChangeSet(context.Background(), "testzone1.at", []*RRSetChange{
+		{Name: "www.testzone1.at.", Type: "TXT", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: `"hello"`}}},
+	})
+
+	if err != nil {
+		t.Fatalf("RRSet.ValidateChangeSet returned error: %v", err)
+	}
+
+	if len(findings) != 0 {
+		t.Errorf("RRSet.ValidateChangeSet returned %v", findings)
+	}
+
+	if len(zone.patches) != 0 {
+		t.Errorf("RRSet.ValidateChangeSet submitted %d change sets", len(zone.patches))
+	}
+}
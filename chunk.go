@@ -0,0 +1,198 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const defaultChangeSetChunkSize = 1000
+
+// ChangeSetChunkOptions controls how a change set is split into several PATCH requests
+type ChangeSetChunkOptions struct {
+
+	// Maximum number of rrsets per PATCH request (defaults to 1000)
+	ChunkSize int
+
+	// ContinueOnError submits the remaining chunks after a chunk failed
+	ContinueOnError bool
+
+	// Progress is called after each submitted chunk
+	Progress func(chunk *ChangeSetChunk, done int, total int)
+}
+
+// ChangeSetChunk is a part of a change set submitted with a single PATCH request
+type ChangeSetChunk struct {
+
+	// Index of the chunk, starting at 0
+	Index int
+
+	Changes []*RRSetChange
+
+	// Submitted is false for chunks skipped after an earlier chunk failed
+	Submitted bool
+
+	Status *StatusResponse
+	Err    error
+}
+
+// Failed reports whether the chunk was submitted and rejected
+func (c *ChangeSetChunk) Failed() bool {
+	return c.Submitted && c.Err != nil
+}
+
+// ChangeSetSummary reports the outcome of each chunk of a change set
+type ChangeSetSummary struct {
+	Chunks []*ChangeSetChunk
+}
+
+// Succeeded reports whether all chunks were submitted successfully
+func (s *ChangeSetSummary) Succeeded() bool {
+
+	for _, c := range s.Chunks {
+		if !c.Submitted || c.Err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Failed returns the chunks which were rejected
+func (s *ChangeSetSummary) Failed() []*ChangeSetChunk {
+
+	var failed []*ChangeSetChunk
+
+	for _, c := range s.Chunks {
+		if c.Failed() {
+			failed = append(failed, c)
+		}
+	}
+
+	return failed
+}
+
+// Skipped returns the chunks which were not submitted
+func (s *ChangeSetSummary) Skipped() []*ChangeSetChunk {
+
+	var skipped []*ChangeSetChunk
+
+	for _, c := range s.Chunks {
+		if !c.Submitted {
+			skipped = append(skipped, c)
+		}
+	}
+
+	return skipped
+}
+
+// ChangeSetChunkError is returned if at least one chunk of a change set failed
+type ChangeSetChunkError struct {
+	Summary *ChangeSetSummary
+}
+
+func (e *ChangeSetChunkError) Error() string {
+
+	var failed []string
+	for _, c := range e.Summary.Failed() {
+		failed = append(failed, fmt.Sprintf("chunk %d: %v", c.Index, c.Err))
+	}
+
+	return fmt.Sprintf("%d of %d change set chunks failed (%s), %d skipped",
+		len(failed), len(e.Summary.Chunks), strings.Join(failed, "; "), len(e.Summary.Skipped()))
+}
+
+// SubmitChangeSetInChunks submits a large change set with several PATCH requests.
+// Deletes are submitted before updates and updates before adds, so that an rrset can be
+// replaced by one of another type (f.e. a CNAME by an A rrset) across chunks.
+// Chunks are submitted in order; by default the remaining chunks are skipped after a
+// chunk failed. The returned summary is also available from a *ChangeSetChunkError.
+func (s *RRSetService) SubmitChangeSetInChunks(zone string, changeSet []*RRSetChange, options *ChangeSetChunkOptions) (*ChangeSetSummary, error) {
+
+	if options == nil {
+		options = &ChangeSetChunkOptions{}
+	}
+
+	size := options.ChunkSize
+	if size <= 0 {
+		size = defaultChangeSetChunkSize
+	}
+
+	summary := &ChangeSetSummary{}
+
+	for i, changes := range chunkChangeSet(orderChangeSet(changeSet), size) {
+		summary.Chunks = append(summary.Chunks, &ChangeSetChunk{Index: i, Changes: changes})
+	}
+
+	failed := false
+
+	for i, chunk := range summary.Chunks {
+
+		if failed && !options.ContinueOnError {
+			break
+		}
+
+		chunk.Submitted = true
+		chunk.Status, chunk.Err = s.patchChangeSet(zone, chunk.Changes)
+
+		if chunk.Err == nil && chunk.Status.HasError() {
+			chunk.Err = fmt.Errorf("%s", chunk.Status.Message)
+		}
+
+		if chunk.Err != nil {
+			failed = true
+		}
+
+		if options.Progress != nil {
+			options.Progress(chunk, i+1, len(summary.Chunks))
+		}
+	}
+
+	if !summary.Succeeded() {
+		return summary, &ChangeSetChunkError{Summary: summary}
+	}
+
+	return summary, nil
+}
+
+// changeTypeOrder defines the submit order of the change types
+var changeTypeOrder = map[string]int{
+	ChangeTypeDELETE: 0,
+	ChangeTypeUPDATE: 1,
+	ChangeTypeADD:    2,
+}
+
+// orderChangeSet returns the changes with deletes first, then updates and adds,
+// keeping the original order within each change type
+func orderChangeSet(changeSet []*RRSetChange) []*RRSetChange {
+
+	ordered := make([]*RRSetChange, len(changeSet))
+	copy(ordered, changeSet)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return changeTypeOrder[ordered[i].ChangeType] < changeTypeOrder[ordered[j].ChangeType]
+	})
+
+	return ordered
+}
+
+func chunkChangeSet(changeSet []*RRSetChange, size int) [][]*RRSetChange {
+
+	var chunks [][]*RRSetChange
+
+	for len(changeSet) > size {
+		chunks = append(chunks, changeSet[:size])
+		changeSet = changeSet[size:]
+	}
+
+	if len(changeSet) > 0 {
+		chunks = append(chunks, changeSet)
+	}
+
+	return chunks
+}
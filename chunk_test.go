@@ -0,0 +1,141 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func testChangeSet(n int) []*RRSetChange {
+
+	var changeSet []*RRSetChange
+
+	for i := 0; i < n; i++ {
+		changeSet = append(changeSet, &RRSetChange{
+			Name:       fmt.Sprintf("host%d.testzone1.at.", i),
+			Type:       "A",
+			ChangeType: ChangeTypeADD,
+			Records:    []*Record{{Content: "10.10.0.1"}},
+		})
+	}
+
+	return changeSet
+}
+
+func TestRRSetService_SubmitChangeSetInChunks(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var received [][]*RRSetChange
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+
+		body, _ := ioutil.ReadAll(r.Body)
+
+		var changes []*RRSetChange
+		_ = json.Unmarshal(body, &changes)
+		received = append(received, changes)
+
+		_, _ = fmt.Fprint(w, `{"status": "ok", "message": "RRsets updated"}`)
+	})
+
+	changeSet := testChangeSet(4)
+	changeSet = append(changeSet, &RRSetChange{Name: "host0.testzone1.at.", Type: "CNAME", ChangeType: ChangeTypeDELETE})
+
+	var progress []int
+
+	summary, err := client.RRSet.SubmitChangeSetInChunks("testzone1.at", changeSet, &ChangeSetChunkOptions{
+		ChunkSize: 2,
+		Progress: func(chunk *ChangeSetChunk, done int, total int) {
+			progress = append(progress, done)
+			if total != 3 {
+				t.Errorf("Progress reported %d chunks, want 3", total)
+			}
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("RRSet.SubmitChangeSetInChunks returned error: %v", err)
+	}
+
+	if !summary.Succeeded() || len(summary.Chunks) != 3 {
+		t.Errorf("RRSet.SubmitChangeSetInChunks returned %d chunks, succeeded %v", len(summary.Chunks), summary.Succeeded())
+	}
+
+	if len(received) != 3 || len(received[0]) != 2 || len(received[2]) != 1 {
+		t.Fatalf("RRSet.SubmitChangeSetInChunks sent %d requests", len(received))
+	}
+
+	if received[0][0].ChangeType != ChangeTypeDELETE {
+		t.Errorf("RRSet.SubmitChangeSetInChunks sent %s first, want delete", received[0][0].ChangeType)
+	}
+
+	if len(progress) != 3 || progress[2] != 3 {
+		t.Errorf("Progress was called with %v", progress)
+	}
+}
+
+func TestRRSetService_SubmitChangeSetInChunks_Failure(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	requests := 0
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"status": "failed", "message": "invalid rrset"}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"status": "ok", "message": "RRsets updated"}`)
+	})
+
+	summary, err := client.RRSet.SubmitChangeSetInChunks("testzone1.at", testChangeSet(5), &ChangeSetChunkOptions{ChunkSize: 2})
+
+	if _, ok := err.(*ChangeSetChunkError); !ok {
+		t.Fatalf("RRSet.SubmitChangeSetInChunks returned %v, want *ChangeSetChunkError", err)
+	}
+
+	if len(summary.Failed()) != 1 || summary.Failed()[0].Index != 1 {
+		t.Errorf("RRSet.SubmitChangeSetInChunks reported failed chunks %+v", summary.Failed())
+	}
+
+	if len(summary.Skipped()) != 1 || requests != 2 {
+		t.Errorf("RRSet.SubmitChangeSetInChunks skipped %d chunks after %d requests", len(summary.Skipped()), requests)
+	}
+}
+
+func TestRRSetService_SubmitChangeSet_Chunked(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	requests := 0
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = fmt.Fprint(w, `{"status": "ok", "message": "RRsets updated"}`)
+	})
+
+	client.ChangeSetChunkSize = 10
+
+	status, err := client.RRSet.SubmitChangeSet("testzone1.at", testChangeSet(25))
+	if err != nil {
+		t.Fatalf("RRSet.SubmitChangeSet returned error: %v", err)
+	}
+
+	if status.HasError() || requests != 3 {
+		t.Errorf("RRSet.SubmitChangeSet sent %d requests, want 3", requests)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -47,9 +48,23 @@ type Client struct {
 	// User agent used when communicating with the rcode0 API.
 	UserAgent string
 
+	// ValidateChangeSets rejects malformed records in RRSetService.SubmitChangeSet
+	// before they are sent to the API (see RRSetChange.Validate).
+	ValidateChangeSets bool
+
+	// ChangeSetChunkSize is the maximum number of rrsets RRSetService.SubmitChangeSet sends
+	// in a single PATCH request. Zero disables chunking.
+	ChangeSetChunkSize int
+
+	// RRSetCacheTTL enables caching of a zone's rrset listing for RRSetService.Get. Zero disables the cache.
+	RRSetCacheTTL time.Duration
+
 	// HTTP client used to communicate with the API.
 	client *http.Client
 
+	// Cached rrset listings used by RRSetService.Get
+	rrsetCache rrsetCache
+
 	// Reuse a single struct instead of allocating one for each service on the heap.
 	common service
 
@@ -6,11 +6,16 @@
 package rc0go
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/gorilla/mux"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -103,4 +108,61 @@ func getSampleRRSet() map[string]interface{} {
 			},
 		},
 	}
-}
\ No newline at end of file
+}
+
+// fakeZone is an in-memory stand-in for the rrsets of a zone on the rcode0 API
+type fakeZone struct {
+	mu      sync.Mutex
+	rrsets  []*RRType
+	patches [][]*RRSetChange
+
+	// failPatch lets the PATCH request with the given number (starting at 1) fail
+	failPatch func(n int, changeSet []*RRSetChange) bool
+}
+
+func (z *fakeZone) handle(w http.ResponseWriter, r *http.Request) {
+
+	z.mu.Lock()
+	defer z.mu.Unlock()
+
+	if r.Method == "GET" {
+		data := getTestDataPaginated(reflect.TypeOf(RRType{}))
+		data["data"] = z.rrsets
+		dat, _ := json.Marshal(data)
+		_, _ = fmt.Fprint(w, string(dat))
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	var changeSet []*RRSetChange
+	_ = json.Unmarshal(body, &changeSet)
+
+	z.patches = append(z.patches, changeSet)
+
+	if z.failPatch != nil && z.failPatch(len(z.patches), changeSet) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = fmt.Fprint(w, `{"status": "failed", "message": "rejected by fake zone"}`)
+		return
+	}
+
+	for _, change := range changeSet {
+
+		index := -1
+		for i, rrset := range z.rrsets {
+			if strings.EqualFold(rrset.Name, change.Name) && strings.EqualFold(rrset.Type, change.Type) {
+				index = i
+			}
+		}
+
+		if index >= 0 {
+			z.rrsets = append(z.rrsets[:index], z.rrsets[index+1:]...)
+		}
+
+		if change.ChangeType != ChangeTypeDELETE {
+			z.rrsets = append(z.rrsets, &RRType{Name: change.Name, Type: change.Type, TTL: change.TTL, Records: change.Records})
+		}
+	}
+
+	_, _ = fmt.Fprint(w, `{"status": "ok", "message": "RRsets updated"}`)
+}
@@ -0,0 +1,93 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// rc0ddns keeps the A/AAAA rrsets of hosts with changing addresses up to date:
+//
+//	$ RC0_API_KEY=YOUR_API_KEY rc0ddns -zone example.at -hosts office,lab \
+//	      -ipv4 https://api.ipify.org -ipv6 iface:eth0 -state /var/lib/rc0ddns.json
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/ddns"
+)
+
+func main() {
+
+	zone := flag.String("zone", "", "zone of the hostnames")
+	hosts := flag.String("hosts", "@", "comma separated hostnames, relative to the zone")
+	ttl := flag.Int("ttl", 300, "TTL of the rrsets")
+	ipv4 := flag.String("ipv4", "", "IPv4 detector (iface:<name>, http(s)://<url> or cmd:<command>)")
+	ipv6 := flag.String("ipv6", "", "IPv6 detector (iface:<name>, http(s)://<url> or cmd:<command>)")
+	state := flag.String("state", "", "state file to avoid redundant API calls")
+	interval := flag.Duration("interval", 5*time.Minute, "update interval")
+	once := flag.Bool("once", false, "update once and exit")
+	flag.Parse()
+
+	if !*once && *interval <= 0 {
+		log.Fatalf("invalid interval %s: must be positive", *interval)
+	}
+
+	client, err := rc0go.NewClient(os.Getenv("RC0_API_KEY"))
+	if err != nil {
+		log.Fatalf("failed to initialize rcodezero client: %v", err)
+	}
+
+	if strings.Contains(os.Getenv("RC0_BASE_URL"), "rcodezero.at/api/") {
+		client.BaseURL, _ = url.Parse(os.Getenv("RC0_BASE_URL"))
+	}
+
+	updater := &ddns.Updater{
+		RRSet:     client.RRSet,
+		Zone:      *zone,
+		Hostnames: strings.Split(*hosts, ","),
+		TTL:       *ttl,
+		StateFile: *state,
+		Logf:      log.Printf,
+	}
+
+	if *ipv4 != "" {
+		if updater.IPv4, err = ddns.ParseDetector(*ipv4, false); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *ipv6 != "" {
+		if updater.IPv6, err = ddns.ParseDetector(*ipv6, true); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	if *once {
+		if _, err := updater.Update(ctx); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := updater.Run(ctx, *interval); err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
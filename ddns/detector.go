@@ -0,0 +1,184 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Detector detects the current public address of the host
+type Detector interface {
+	Detect(ctx context.Context) (net.IP, error)
+}
+
+// DetectorFunc adapts a function to the Detector interface
+type DetectorFunc func(ctx context.Context) (net.IP, error)
+
+// Detect calls f(ctx)
+func (f DetectorFunc) Detect(ctx context.Context) (net.IP, error) {
+	return f(ctx)
+}
+
+// InterfaceDetector returns the first global unicast address of a network interface
+type InterfaceDetector struct {
+
+	// Name of the interface, f.e. "eth0"
+	Name string
+
+	// IPv6 selects IPv6 instead of IPv4 addresses
+	IPv6 bool
+}
+
+// Detect returns the address of the interface
+func (d *InterfaceDetector) Detect(ctx context.Context) (net.IP, error) {
+
+	iface, err := net.InterfaceByName(d.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() {
+			continue
+		}
+
+		if (ipNet.IP.To4() == nil) == d.IPv6 {
+			return ipNet.IP, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no global %s address on interface %s", family(d.IPv6), d.Name)
+}
+
+// HTTPDetector asks an HTTP echo endpoint (f.e. "https://api.ipify.org") for the address.
+// The endpoint has to answer with the plain address.
+type HTTPDetector struct {
+	URL string
+
+	// IPv6 expects an IPv6 instead of an IPv4 address, other answers are rejected
+	IPv6 bool
+
+	// Client used for the request, defaults to a client with a timeout of 30 seconds
+	Client *http.Client
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// Detect returns the address reported by the endpoint
+func (d *HTTPDetector) Detect(ctx context.Context) (net.IP, error) {
+
+	client := d.Client
+	if client == nil {
+		client = defaultHTTPClient
+	}
+
+	req, err := http.NewRequest("GET", d.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s", d.URL, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseIP(string(body), d.URL, d.IPv6)
+}
+
+// CommandDetector runs a command which prints the address
+type CommandDetector struct {
+	Command string
+	Args    []string
+
+	// IPv6 expects an IPv6 instead of an IPv4 address, other output is rejected
+	IPv6 bool
+}
+
+// Detect returns the address printed by the command
+func (d *CommandDetector) Detect(ctx context.Context) (net.IP, error) {
+
+	out, err := exec.CommandContext(ctx, d.Command, d.Args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", d.Command, err)
+	}
+
+	return parseIP(string(out), d.Command, d.IPv6)
+}
+
+func parseIP(s string, source string, ipv6 bool) (net.IP, error) {
+
+	ip := net.ParseIP(strings.TrimSpace(s))
+	if ip == nil {
+		return nil, fmt.Errorf("%s returned no valid address: %q", source, strings.TrimSpace(s))
+	}
+
+	if (ip.To4() == nil) != ipv6 {
+		return nil, fmt.Errorf("%s returned %s, want an %s address", source, ip, family(ipv6))
+	}
+
+	return ip, nil
+}
+
+func family(ipv6 bool) string {
+	if ipv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// ParseDetector returns the detector described by spec, one of
+// "iface:<name>", "http:<url>" / "https:<url>" or "cmd:<command> [args...]"
+func ParseDetector(spec string, ipv6 bool) (Detector, error) {
+
+	i := strings.Index(spec, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid detector %q", spec)
+	}
+
+	kind, arg := spec[:i], spec[i+1:]
+
+	switch kind {
+
+	case "iface":
+		return &InterfaceDetector{Name: arg, IPv6: ipv6}, nil
+
+	case "http", "https":
+		return &HTTPDetector{URL: spec, IPv6: ipv6}, nil
+
+	case "cmd":
+		fields := strings.Fields(arg)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("invalid detector %q: command missing", spec)
+		}
+		return &CommandDetector{Command: fields[0], Args: fields[1:], IPv6: ipv6}, nil
+	}
+
+	return nil, fmt.Errorf("invalid detector %q: unknown kind %q", spec, kind)
+}
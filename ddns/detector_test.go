@@ -0,0 +1,104 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestHTTPDetector(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "192.0.2.10\n")
+	}))
+	defer server.Close()
+
+	ip, err := (&HTTPDetector{URL: server.URL}).Detect(context.Background())
+	if err != nil {
+		t.Fatalf("HTTPDetector.Detect returned error: %v", err)
+	}
+
+	if !ip.Equal(net.ParseIP("192.0.2.10")) {
+		t.Errorf("HTTPDetector.Detect returned %s, want 192.0.2.10", ip)
+	}
+}
+
+func TestHTTPDetector_Invalid(t *testing.T) {
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "<html>not an address</html>")
+	}))
+	defer server.Close()
+
+	if _, err := (&HTTPDetector{URL: server.URL}).Detect(context.Background()); err == nil {
+		t.Errorf("HTTPDetector.Detect returned no error for an invalid response")
+	}
+
+	ipv4 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, "192.0.2.10\n")
+	}))
+	defer ipv4.Close()
+
+	if _, err := (&HTTPDetector{URL: ipv4.URL, IPv6: true}).Detect(context.Background()); err == nil {
+		t.Errorf("HTTPDetector.Detect returned no error for an IPv4 address, want IPv6")
+	}
+}
+
+func TestCommandDetector(t *testing.T) {
+
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+
+	ip, err := (&CommandDetector{Command: "echo", Args: []string{"2001:db8::10"}, IPv6: true}).Detect(context.Background())
+	if err != nil {
+		t.Fatalf("CommandDetector.Detect returned error: %v", err)
+	}
+
+	if !ip.Equal(net.ParseIP("2001:db8::10")) {
+		t.Errorf("CommandDetector.Detect returned %s, want 2001:db8::10", ip)
+	}
+
+	if _, err := (&CommandDetector{Command: "echo", Args: []string{"2001:db8::10"}}).Detect(context.Background()); err == nil {
+		t.Errorf("CommandDetector.Detect returned no error for an IPv6 address, want IPv4")
+	}
+}
+
+func TestParseDetector(t *testing.T) {
+
+	tests := []struct {
+		spec string
+		want Detector
+	}{
+		{"iface:eth0", &InterfaceDetector{Name: "eth0", IPv6: true}},
+		{"https://api6.ipify.org", &HTTPDetector{URL: "https://api6.ipify.org", IPv6: true}},
+		{"cmd:dig +short myip.opendns.com", &CommandDetector{Command: "dig", Args: []string{"+short", "myip.opendns.com"}, IPv6: true}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseDetector(test.spec, true)
+		if err != nil {
+			t.Errorf("ParseDetector(%q) returned error: %v", test.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseDetector(%q) returned %+v, want %+v", test.spec, got, test.want)
+		}
+	}
+
+	for _, spec := range []string{"eth0", "ftp:host", "cmd:"} {
+		if _, err := ParseDetector(spec, false); err == nil {
+			t.Errorf("ParseDetector(%q) returned no error", spec)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package ddns keeps the A/AAAA rrsets of hosts with changing addresses up to date.
+package ddns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultTTL = 300
+
+// Updater updates the A and AAAA rrsets of the hostnames to the detected addresses
+type Updater struct {
+
+	// RRSet service of the rcode0 client
+	RRSet rc0go.RRSetServiceInterface
+
+	// Zone the hostnames belong to, f.e. "example.at"
+	Zone string
+
+	// Hostnames to update. Names without trailing dot are relative to the zone, "@" is the apex.
+	Hostnames []string
+
+	// TTL of the rrsets (defaults to 300)
+	TTL int
+
+	// IPv4 and IPv6 detect the current addresses. A nil detector skips the address family.
+	IPv4 Detector
+	IPv6 Detector
+
+	// StateFile remembers the last submitted addresses. If the detected addresses did not
+	// change, no API request is made at all.
+	StateFile string
+
+	// Logf receives progress and error messages (optional)
+	Logf func(format string, args ...interface{})
+}
+
+// State holds the last known address per rrset, keyed by "<name> <type>"
+type State map[string]string
+
+// Update detects the current addresses and updates all rrsets which differ.
+// The submitted changes are returned. If only one address family could be detected, the
+// other one is left alone and the detection failure is returned along with the changes.
+func (u *Updater) Update(ctx context.Context) ([]*rc0go.RRSetChange, error) {
+
+	if u.IPv4 == nil && u.IPv6 == nil {
+		return nil, fmt.Errorf("no address detector configured")
+	}
+
+	addrs := make(map[string]string)
+
+	// a failing address family does not stop the update of the other one
+	var failures []string
+
+	if u.IPv4 != nil {
+		ip, err := u.IPv4.Detect(ctx)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("detecting IPv4 address: %v", err))
+		case ip.To4() == nil:
+			failures = append(failures, fmt.Sprintf("IPv4 detector returned %s", ip))
+		default:
+			addrs["A"] = ip.To4().String()
+		}
+	}
+
+	if u.IPv6 != nil {
+		ip, err := u.IPv6.Detect(ctx)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("detecting IPv6 address: %v", err))
+		case ip.To4() != nil:
+			failures = append(failures, fmt.Sprintf("IPv6 detector returned %s", ip))
+		default:
+			addrs["AAAA"] = ip.String()
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+
+	var failed error
+	if len(failures) > 0 {
+		failed = fmt.Errorf("%s", strings.Join(failures, "; "))
+	}
+
+	state, err := u.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	if u.upToDate(state, addrs) {
+		return nil, failed
+	}
+
+	rrsets, err := u.RRSet.ListAll(ctx, u.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]*rc0go.RRType)
+	for _, rrset := range rrsets {
+		current[stateKey(rrset.Name, rrset.Type)] = rrset
+	}
+
+	ttl := u.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	var changeSet []*rc0go.RRSetChange
+
+	for _, name := range u.names() {
+		for _, rrType := range []string{"A", "AAAA"} {
+
+			addr, ok := addrs[rrType]
+			if !ok {
+				continue
+			}
+
+			key := stateKey(name, rrType)
+			state[key] = addr
+
+			rrset := current[key]
+			if rrset != nil && rrset.TTL == ttl && len(rrset.Records) == 1 &&
+				!rrset.Records[0].Disabled && net.ParseIP(rrset.Records[0].Content).Equal(net.ParseIP(addr)) {
+				continue
+			}
+
+			change := &rc0go.RRSetChange{
+				Name:       name,
+				Type:       rrType,
+				ChangeType: rc0go.ChangeTypeUPDATE,
+				TTL:        ttl,
+				Records:    []*rc0go.Record{{Content: addr}},
+			}
+
+			if rrset == nil {
+				change.ChangeType = rc0go.ChangeTypeADD
+			}
+
+			changeSet = append(changeSet, change)
+		}
+	}
+
+	if len(changeSet) > 0 {
+
+		status, err := u.RRSet.SubmitChangeSet(u.Zone, changeSet)
+		if err != nil {
+			return nil, err
+		}
+
+		if status.HasError() {
+			return nil, fmt.Errorf("zone %s: %s", u.Zone, status.Message)
+		}
+
+		for _, change := range changeSet {
+			u.logf("%s %s set to %s", change.Name, change.Type, change.Records[0].Content)
+		}
+	}
+
+	if err := u.saveState(state); err != nil {
+		return changeSet, err
+	}
+
+	return changeSet, failed
+}
+
+// Run updates the rrsets immediately and then in the given interval until the context is done.
+// Errors are logged and retried in the next interval.
+func (u *Updater) Run(ctx context.Context, interval time.Duration) error {
+
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s: must be positive", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := u.Update(ctx); err != nil {
+			u.logf("update failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// upToDate reports whether the state file already holds the addresses for all hostnames
+func (u *Updater) upToDate(state State, addrs map[string]string) bool {
+
+	if u.StateFile == "" {
+		return false
+	}
+
+	for _, name := range u.names() {
+		for rrType, addr := range addrs {
+			if state[stateKey(name, rrType)] != addr {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// names returns the fully qualified hostnames
+func (u *Updater) names() []string {
+
+	origin := rc0go.NormalizeName(u.Zone)
+
+	var names []string
+
+	for _, name := range u.Hostnames {
+		switch {
+		case name == "@" || name == "":
+			name = origin
+		case !strings.HasSuffix(name, "."):
+			name = name + "." + origin
+		}
+		names = append(names, rc0go.NormalizeName(name))
+	}
+
+	return names
+}
+
+func (u *Updater) loadState() (State, error) {
+
+	state := make(State)
+
+	if u.StateFile == "" {
+		return state, nil
+	}
+
+	data, err := ioutil.ReadFile(u.StateFile)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("state file %s: %v", u.StateFile, err)
+	}
+
+	return state, nil
+}
+
+// saveState writes the state file atomically
+func (u *Updater) saveState(state State) error {
+
+	if u.StateFile == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(u.StateFile), ".ddns-state-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), u.StateFile)
+}
+
+func (u *Updater) logf(format string, args ...interface{}) {
+	if u.Logf != nil {
+		u.Logf(format, args...)
+	}
+}
+
+func stateKey(name string, rrType string) string {
+	return rc0go.NormalizeName(name) + " " + strings.ToUpper(rrType)
+}
@@ -0,0 +1,190 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package ddns
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRRSet serves the rrsets of a zone from memory
+type fakeRRSet struct {
+	rc0go.RRSetServiceInterface
+
+	rrsets  []*rc0go.RRType
+	lists   int
+	patches [][]*rc0go.RRSetChange
+}
+
+func (f *fakeRRSet) ListAll(ctx context.Context, zone string) ([]*rc0go.RRType, error) {
+	f.lists++
+	return f.rrsets, nil
+}
+
+func (f *fakeRRSet) SubmitChangeSet(zone string, changeSet []*rc0go.RRSetChange) (*rc0go.StatusResponse, error) {
+
+	f.patches = append(f.patches, changeSet)
+
+	for _, change := range changeSet {
+		var kept []*rc0go.RRType
+		for _, rrset := range f.rrsets {
+			if rrset.Name != change.Name || rrset.Type != change.Type {
+				kept = append(kept, rrset)
+			}
+		}
+		f.rrsets = append(kept, &rc0go.RRType{Name: change.Name, Type: change.Type, TTL: change.TTL, Records: change.Records})
+	}
+
+	return &rc0go.StatusResponse{Status: "ok"}, nil
+}
+
+func staticIP(addr *string) Detector {
+	return DetectorFunc(func(ctx context.Context) (net.IP, error) {
+		return net.ParseIP(*addr), nil
+	})
+}
+
+func TestUpdater_Update(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "ddns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rrset := &fakeRRSet{rrsets: []*rc0go.RRType{
+		{Name: "office.testzone1.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "192.0.2.1"}}},
+		{Name: "lab.testzone1.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "192.0.2.2"}}},
+	}}
+
+	ipv4 := "192.0.2.2"
+
+	updater := &Updater{
+		RRSet:     rrset,
+		Zone:      "testzone1.at",
+		Hostnames: []string{"office", "lab.testzone1.at."},
+		TTL:       300,
+		IPv4:      staticIP(&ipv4),
+		StateFile: filepath.Join(dir, "state.json"),
+	}
+
+	changes, err := updater.Update(context.Background())
+	if err != nil {
+		t.Fatalf("Updater.Update returned error: %v", err)
+	}
+
+	want := []*rc0go.RRSetChange{
+		{Name: "office.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 300, Records: []*rc0go.Record{{Content: "192.0.2.2"}}},
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Updater.Update submitted %+v, want %+v", changes, want)
+	}
+
+	// unchanged address: the state file prevents any API call
+	if changes, err = updater.Update(context.Background()); err != nil || changes != nil {
+		t.Errorf("Updater.Update returned %+v, %v for an unchanged address", changes, err)
+	}
+
+	if rrset.lists != 1 {
+		t.Errorf("Updater.Update listed the rrsets %d times, want 1", rrset.lists)
+	}
+
+	ipv4 = "192.0.2.3"
+
+	if changes, err = updater.Update(context.Background()); err != nil || len(changes) != 2 {
+		t.Errorf("Updater.Update returned %+v, %v for a changed address", changes, err)
+	}
+
+	if len(rrset.patches) != 2 {
+		t.Errorf("Updater.Update submitted %d change sets, want 2", len(rrset.patches))
+	}
+}
+
+func TestUpdater_UpdateAdd(t *testing.T) {
+
+	rrset := &fakeRRSet{}
+	ipv6 := "2001:db8::1"
+
+	updater := &Updater{
+		RRSet:     rrset,
+		Zone:      "testzone1.at",
+		Hostnames: []string{"@"},
+		IPv6:      staticIP(&ipv6),
+	}
+
+	changes, err := updater.Update(context.Background())
+	if err != nil {
+		t.Fatalf("Updater.Update returned error: %v", err)
+	}
+
+	want := []*rc0go.RRSetChange{
+		{Name: "testzone1.at.", Type: "AAAA", ChangeType: rc0go.ChangeTypeADD, TTL: defaultTTL, Records: []*rc0go.Record{{Content: "2001:db8::1"}}},
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Updater.Update submitted %+v, want %+v", changes, want)
+	}
+
+	ipv6 = "192.0.2.1"
+
+	if _, err := updater.Update(context.Background()); err == nil {
+		t.Errorf("Updater.Update returned no error for an IPv4 address from the IPv6 detector")
+	}
+}
+
+func TestUpdater_UpdatePartialFailure(t *testing.T) {
+
+	rrset := &fakeRRSet{}
+	ipv4 := "192.0.2.1"
+
+	updater := &Updater{
+		RRSet:     rrset,
+		Zone:      "testzone1.at",
+		Hostnames: []string{"@"},
+		IPv4:      staticIP(&ipv4),
+		IPv6: DetectorFunc(func(ctx context.Context) (net.IP, error) {
+			return nil, fmt.Errorf("no route")
+		}),
+	}
+
+	changes, err := updater.Update(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "IPv6") {
+		t.Errorf("Updater.Update returned error %v, want the IPv6 detection failure", err)
+	}
+
+	want := []*rc0go.RRSetChange{
+		{Name: "testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeADD, TTL: 300, Records: []*rc0go.Record{{Content: "192.0.2.1"}}},
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Updater.Update submitted %+v, want %+v", changes, want)
+	}
+
+	updater.IPv4 = updater.IPv6
+
+	if changes, err := updater.Update(context.Background()); err == nil || changes != nil {
+		t.Errorf("Updater.Update returned %+v, %v without any address", changes, err)
+	}
+}
+
+func TestUpdater_RunInvalidInterval(t *testing.T) {
+
+	ipv4 := "192.0.2.1"
+	updater := &Updater{RRSet: &fakeRRSet{}, Zone: "testzone1.at", Hostnames: []string{"@"}, IPv4: staticIP(&ipv4)}
+
+	if err := updater.Run(context.Background(), 0); err == nil {
+		t.Errorf("Updater.Run with interval 0 returned no error")
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// RRSetDiff holds the minimal change set to get from the current to the desired rrsets
+type RRSetDiff struct {
+
+	// Changes to submit with RRSetService.SubmitChangeSet
+	Changes []*RRSetChange
+
+	entries []*rrsetDiffEntry
+}
+
+type rrsetDiffEntry struct {
+	current *RRType
+	desired *RRType
+	change  *RRSetChange
+}
+
+// DiffRRSets compares the current rrsets of a zone (f.e. from RRSetService.ListAll) with the desired ones
+// and returns the minimal change set: ChangeTypeADD for new rrsets, ChangeTypeUPDATE for rrsets whose
+// TTL or records differ and ChangeTypeDELETE for rrsets which are not desired anymore.
+//
+// Names and record content are normalized before comparison (see NormalizeContent), so differences
+// in case, trailing dots or IPv6 notation do not result in updates. The order of records is ignored.
+func DiffRRSets(current []*RRType, desired []*RRType) *RRSetDiff {
+
+	diff := &RRSetDiff{}
+
+	currentIndex := indexRRSets(current)
+	desiredIndex := indexRRSets(desired)
+
+	var keys []rrsetKey
+	for k := range currentIndex {
+		keys = append(keys, k)
+	}
+	for k := range desiredIndex {
+		if _, ok := currentIndex[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(keys[j])
+	})
+
+	for _, k := range keys {
+
+		c, d := currentIndex[k], desiredIndex[k]
+
+		var change *RRSetChange
+
+		switch {
+
+		case c == nil:
+			change = &RRSetChange{
+				Name:       k.name,
+				Type:       k.rrType,
+				ChangeType: ChangeTypeADD,
+				TTL:        d.TTL,
+				Records:    copyRecords(d.Records),
+			}
+
+		case d == nil:
+			change = &RRSetChange{
+				Name:       k.name,
+				Type:       k.rrType,
+				ChangeType: ChangeTypeDELETE,
+			}
+
+		case c.TTL != d.TTL || !EqualRecords(k.rrType, c.Records, d.Records):
+			change = &RRSetChange{
+				Name:       k.name,
+				Type:       k.rrType,
+				ChangeType: ChangeTypeUPDATE,
+				TTL:        d.TTL,
+				Records:    copyRecords(d.Records),
+			}
+
+		default:
+			continue
+		}
+
+		diff.Changes = append(diff.Changes, change)
+		diff.entries = append(diff.entries, &rrsetDiffEntry{current: c, desired: d, change: change})
+	}
+
+	return diff
+}
+
+// IsEmpty reports whether current and desired rrsets are equal
+func (d *RRSetDiff) IsEmpty() bool {
+	return len(d.Changes) == 0
+}
+
+// String returns a human-readable diff. Removed records are prefixed with "-", added ones with "+"
+// and TTL changes are shown with "~".
+func (d *RRSetDiff) String() string {
+
+	var b strings.Builder
+
+	for _, e := range d.entries {
+
+		name, rrType := e.change.Name, e.change.Type
+
+		switch e.change.ChangeType {
+
+		case ChangeTypeADD:
+			writeDiffRecords(&b, "+", name, e.desired.TTL, rrType, e.desired.Records, nil)
+
+		case ChangeTypeDELETE:
+			writeDiffRecords(&b, "-", name, e.current.TTL, rrType, e.current.Records, nil)
+
+		case ChangeTypeUPDATE:
+			if e.current.TTL != e.desired.TTL {
+				fmt.Fprintf(&b, "~ %s %s TTL %d -> %d\n", name, rrType, e.current.TTL, e.desired.TTL)
+			}
+			writeDiffRecords(&b, "-", name, e.current.TTL, rrType, e.current.Records, e.desired.Records)
+			writeDiffRecords(&b, "+", name, e.desired.TTL, rrType, e.desired.Records, e.current.Records)
+		}
+	}
+
+	return b.String()
+}
+
+// writeDiffRecords writes the records which are not part of except
+func writeDiffRecords(b *strings.Builder, prefix string, name string, ttl int, rrType string, records []*Record, except []*Record) {
+
+	skip := recordSet(rrType, except)
+
+	for _, r := range records {
+
+		if skip[recordKey(rrType, r)] {
+			continue
+		}
+
+		disabled := ""
+		if r.Disabled {
+			disabled = " (disabled)"
+		}
+
+		fmt.Fprintf(b, "%s %s %d %s %s%s\n", prefix, name, ttl, rrType, r.Content, disabled)
+	}
+}
+
+// EqualRecords reports whether both record lists contain the same records after normalization,
+// regardless of their order
+func EqualRecords(rrType string, a []*Record, b []*Record) bool {
+
+	sa, sb := recordSet(rrType, a), recordSet(rrType, b)
+
+	if len(sa) != len(sb) {
+		return false
+	}
+
+	for k := range sa {
+		if !sb[k] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// NormalizeContent returns the canonical form of the record content for the given type.
+// Domain names are lower cased and made absolute, IP addresses are written in their
+// shortest form and whitespace between fields is collapsed. TXT content is only trimmed.
+func NormalizeContent(rrType string, content string) string {
+
+	rrType = strings.ToUpper(rrType)
+	content = strings.TrimSpace(content)
+
+	switch rrType {
+
+	case "A", "AAAA":
+		if ip := net.ParseIP(content); ip != nil {
+			return ip.String()
+		}
+		return content
+
+	case "TXT", "SPF":
+		return content
+	}
+
+	fields := strings.Fields(content)
+
+	for _, i := range zoneFileNameFields[rrType] {
+		if i < len(fields) {
+			fields[i] = canonicalOrigin(fields[i])
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// NormalizeName returns the domain name in lower case with a trailing dot
+func NormalizeName(name string) string {
+	return canonicalOrigin(name)
+}
+
+type rrsetKey struct {
+	name   string
+	rrType string
+}
+
+func (k rrsetKey) less(o rrsetKey) bool {
+
+	if c := compareCanonicalNames(k.name, o.name); c != 0 {
+		return c < 0
+	}
+
+	return typeOrder(k.rrType) < typeOrder(o.rrType)
+}
+
+func indexRRSets(rrsets []*RRType) map[rrsetKey]*RRType {
+
+	index := make(map[rrsetKey]*RRType, len(rrsets))
+
+	for _, rrset := range rrsets {
+		index[rrsetKey{name: NormalizeName(rrset.Name), rrType: strings.ToUpper(rrset.Type)}] = rrset
+	}
+
+	return index
+}
+
+func recordKey(rrType string, r *Record) string {
+	return fmt.Sprintf("%t %s", r.Disabled, NormalizeContent(rrType, r.Content))
+}
+
+func recordSet(rrType string, records []*Record) map[string]bool {
+
+	set := make(map[string]bool, len(records))
+
+	for _, r := range records {
+		set[recordKey(rrType, r)] = true
+	}
+
+	return set
+}
+
+func copyRecords(records []*Record) []*Record {
+
+	if records == nil {
+		return nil
+	}
+
+	c := make([]*Record, len(records))
+
+	for i, r := range records {
+		record := *r
+		c[i] = &record
+	}
+
+	return c
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffRRSets(t *testing.T) {
+
+	current := []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}, {Content: "10.10.0.2"}}},
+		{Name: "ipv6.testzone1.at.", Type: "AAAA", TTL: 3600, Records: []*Record{{Content: "2001:0db8:0000:0000:0000:0000:0000:0001"}}},
+		{Name: "ftp.testzone1.at.", Type: "CNAME", TTL: 3600, Records: []*Record{{Content: "WWW.testzone1.at."}}},
+		{Name: "old.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.9"}}},
+		{Name: "testzone1.at.", Type: "MX", TTL: 3600, Records: []*Record{{Content: "10 mail.testzone1.at."}}},
+	}
+
+	desired := []*RRType{
+		{Name: "WWW.testzone1.at", Type: "a", TTL: 3600, Records: []*Record{{Content: "10.10.0.2"}, {Content: "10.10.0.1"}}},
+		{Name: "ipv6.testzone1.at.", Type: "AAAA", TTL: 3600, Records: []*Record{{Content: "2001:db8::1"}}},
+		{Name: "ftp.testzone1.at.", Type: "CNAME", TTL: 3600, Records: []*Record{{Content: "www.testzone1.at"}}},
+		{Name: "new.testzone1.at.", Type: "A", TTL: 300, Records: []*Record{{Content: "10.10.0.3"}}},
+		{Name: "testzone1.at.", Type: "MX", TTL: 300, Records: []*Record{{Content: "10 mail.testzone1.at."}, {Content: "20 mx2.testzone1.at."}}},
+	}
+
+	diff := DiffRRSets(current, desired)
+
+	want := []*RRSetChange{
+		{Name: "testzone1.at.", Type: "MX", ChangeType: ChangeTypeUPDATE, TTL: 300, Records: []*Record{{Content: "10 mail.testzone1.at."}, {Content: "20 mx2.testzone1.at."}}},
+		{Name: "new.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 300, Records: []*Record{{Content: "10.10.0.3"}}},
+		{Name: "old.testzone1.at.", Type: "A", ChangeType: ChangeTypeDELETE},
+	}
+
+	if !reflect.DeepEqual(diff.Changes, want) {
+		t.Errorf("DiffRRSets returned %+v, want %+v", diff.Changes, want)
+	}
+
+	wantString := "~ testzone1.at. MX TTL 3600 -> 300\n" +
+		"+ testzone1.at. 300 MX 20 mx2.testzone1.at.\n" +
+		"+ new.testzone1.at. 300 A 10.10.0.3\n" +
+		"- old.testzone1.at. 3600 A 10.10.0.9\n"
+
+	if got := diff.String(); got != wantString {
+		t.Errorf("RRSetDiff.String returned\n%s\nwant\n%s", got, wantString)
+	}
+}
+
+func TestDiffRRSets_Empty(t *testing.T) {
+
+	rrsets := []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+	}
+
+	if diff := DiffRRSets(rrsets, rrsets); !diff.IsEmpty() {
+		t.Errorf("DiffRRSets returned %+v for equal rrsets", diff.Changes)
+	}
+}
+
+func TestDiffRRSets_Disabled(t *testing.T) {
+
+	current := []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+	}
+
+	desired := []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1", Disabled: true}}},
+	}
+
+	diff := DiffRRSets(current, desired)
+
+	if len(diff.Changes) != 1 || diff.Changes[0].ChangeType != ChangeTypeUPDATE {
+		t.Errorf("DiffRRSets returned %+v, want a single update", diff.Changes)
+	}
+}
+
+func TestNormalizeContent(t *testing.T) {
+
+	tests := []struct {
+		rrType  string
+		content string
+		want    string
+	}{
+		{"A", " 10.10.0.1 ", "10.10.0.1"},
+		{"AAAA", "2001:DB8:0:0::1", "2001:db8::1"},
+		{"CNAME", "WWW.testzone1.at", "www.testzone1.at."},
+		{"MX", "10   Mail.testzone1.at.", "10 mail.testzone1.at."},
+		{"SRV", "10 60 5060 SIP.testzone1.at", "10 60 5060 sip.testzone1.at."},
+		{"TXT", "\"Hello World\"", "\"Hello World\""},
+	}
+
+	for _, test := range tests {
+		if got := NormalizeContent(test.rrType, test.content); got != test.want {
+			t.Errorf("NormalizeContent(%q, %q) returned %q, want %q", test.rrType, test.content, got, test.want)
+		}
+	}
+}
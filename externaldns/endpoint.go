@@ -0,0 +1,120 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package externaldns
+
+import (
+	"github.com/nic-at/rc0go"
+	"strings"
+)
+
+// Endpoint is the ExternalDNS representation of an rrset
+type Endpoint struct {
+	DNSName          string                     `json:"dnsName,omitempty"`
+	Targets          []string                   `json:"targets,omitempty"`
+	RecordType       string                     `json:"recordType,omitempty"`
+	SetIdentifier    string                     `json:"setIdentifier,omitempty"`
+	RecordTTL        int64                      `json:"recordTTL,omitempty"`
+	Labels           map[string]string          `json:"labels,omitempty"`
+	ProviderSpecific []ProviderSpecificProperty `json:"providerSpecific,omitempty"`
+}
+
+// ProviderSpecificProperty holds a provider specific endpoint setting
+type ProviderSpecificProperty struct {
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// Changes holds the endpoints ExternalDNS wants to create, update and delete
+type Changes struct {
+	Create    []*Endpoint `json:"create,omitempty"`
+	UpdateOld []*Endpoint `json:"updateOld,omitempty"`
+	UpdateNew []*Endpoint `json:"updateNew,omitempty"`
+	Delete    []*Endpoint `json:"delete,omitempty"`
+}
+
+// DomainFilter tells ExternalDNS which domains the provider manages
+type DomainFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+// supportedTypes are the record types exchanged with ExternalDNS
+var supportedTypes = map[string]bool{
+	"A": true, "AAAA": true, "CNAME": true, "TXT": true, "MX": true, "SRV": true, "NS": true, "PTR": true, "NAPTR": true,
+}
+
+// toEndpoint converts the rrset to an endpoint. Target names are written without trailing dot.
+func toEndpoint(rrset *rc0go.RRType) *Endpoint {
+
+	e := &Endpoint{
+		DNSName:    strings.TrimSuffix(strings.ToLower(rrset.Name), "."),
+		RecordType: strings.ToUpper(rrset.Type),
+		RecordTTL:  int64(rrset.TTL),
+	}
+
+	for _, r := range rrset.Records {
+		if !r.Disabled {
+			e.Targets = append(e.Targets, toTarget(e.RecordType, r.Content))
+		}
+	}
+
+	return e
+}
+
+// toRRSetChange converts the endpoint to a change of the given type
+func toRRSetChange(e *Endpoint, changeType string, defaultTTL int) *rc0go.RRSetChange {
+
+	change := &rc0go.RRSetChange{
+		Name:       rc0go.NormalizeName(e.DNSName),
+		Type:       strings.ToUpper(e.RecordType),
+		ChangeType: changeType,
+		TTL:        int(e.RecordTTL),
+	}
+
+	if change.TTL <= 0 {
+		change.TTL = defaultTTL
+	}
+
+	if changeType == rc0go.ChangeTypeDELETE {
+		return change
+	}
+
+	for _, target := range e.Targets {
+		change.Records = append(change.Records, &rc0go.Record{Content: toContent(change.Type, target)})
+	}
+
+	return change
+}
+
+// toTarget converts rcode0 record content to an ExternalDNS target
+func toTarget(rrType string, content string) string {
+
+	switch rrType {
+	case "CNAME", "NS", "PTR", "MX", "SRV":
+		return strings.TrimSuffix(content, ".")
+	}
+
+	return content
+}
+
+// toContent converts an ExternalDNS target to rcode0 record content. Target names are made
+// absolute, TXT targets are quoted unless they are already.
+func toContent(rrType string, target string) string {
+
+	switch rrType {
+
+	case "CNAME", "NS", "PTR", "MX", "SRV":
+		return rc0go.NormalizeName(target)
+
+	case "TXT":
+		if strings.HasPrefix(target, `"`) {
+			return target
+		}
+		return rc0go.QuoteTXT(target)
+	}
+
+	return target
+}
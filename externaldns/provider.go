@@ -0,0 +1,300 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package externaldns implements an ExternalDNS webhook provider
+// (https://kubernetes-sigs.github.io/external-dns/) for rcode0.
+package externaldns
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultTTL       = 300
+	defaultBatchSize = 100
+)
+
+// Provider manages the rrsets of rcode0 zones on behalf of ExternalDNS
+type Provider struct {
+	client *rc0go.Client
+
+	// Domains managed by the provider, f.e. "example.at" or "k8s.example.at".
+	// If empty, all zones of the account are managed.
+	Domains []string
+
+	// ExcludeDomains are subdomains which are never touched
+	ExcludeDomains []string
+
+	// OwnerID enables the ownership check: changes to names whose TXT registry record
+	// belongs to another ExternalDNS owner are refused
+	OwnerID string
+
+	// DefaultTTL is used for endpoints without TTL (defaults to 300)
+	DefaultTTL int
+
+	// BatchSize is the maximum number of rrsets per PATCH request (defaults to 100)
+	BatchSize int
+}
+
+// NewProvider returns a provider using the given client
+func NewProvider(client *rc0go.Client) *Provider {
+	return &Provider{
+		client:     client,
+		DefaultTTL: defaultTTL,
+		BatchSize:  defaultBatchSize,
+	}
+}
+
+// DomainFilter returns the managed domains
+func (p *Provider) DomainFilter(ctx context.Context) (*DomainFilter, error) {
+
+	if len(p.Domains) > 0 {
+		return &DomainFilter{Include: p.Domains, Exclude: p.ExcludeDomains}, nil
+	}
+
+	zones, err := p.zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DomainFilter{Include: zones, Exclude: p.ExcludeDomains}, nil
+}
+
+// Records returns the endpoints of all managed rrsets
+func (p *Provider) Records(ctx context.Context) ([]*Endpoint, error) {
+
+	zones, err := p.zones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []*Endpoint
+
+	for _, zone := range zones {
+
+		rrsets, err := p.client.RRSet.ListAll(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rrset := range rrsets {
+
+			e := toEndpoint(rrset)
+
+			if !supportedTypes[e.RecordType] || len(e.Targets) == 0 || !p.matches(e.DNSName) {
+				continue
+			}
+
+			endpoints = append(endpoints, e)
+		}
+	}
+
+	return endpoints, nil
+}
+
+// AdjustEndpoints normalizes the endpoints the way Records returns them, so ExternalDNS
+// does not see differences which are none
+func (p *Provider) AdjustEndpoints(endpoints []*Endpoint) []*Endpoint {
+
+	for _, e := range endpoints {
+
+		e.DNSName = strings.TrimSuffix(strings.ToLower(e.DNSName), ".")
+		e.RecordType = strings.ToUpper(e.RecordType)
+
+		if e.RecordTTL <= 0 {
+			e.RecordTTL = int64(p.defaultTTL())
+		}
+
+		for i, target := range e.Targets {
+			e.Targets[i] = toTarget(e.RecordType, toContent(e.RecordType, target))
+		}
+	}
+
+	return endpoints
+}
+
+// ApplyChanges submits the changes zone by zone in batches of BatchSize rrsets.
+// Deletes are submitted before updates and creates.
+func (p *Provider) ApplyChanges(ctx context.Context, changes *Changes) error {
+
+	zones, err := p.zones(ctx)
+	if err != nil {
+		return err
+	}
+
+	changeSets := make(map[string][]*rc0go.RRSetChange)
+
+	add := func(endpoints []*Endpoint, changeType string) error {
+		for _, e := range endpoints {
+
+			if !p.matches(e.DNSName) {
+				return fmt.Errorf("%s is outside of the managed domains", e.DNSName)
+			}
+
+			zone := findZone(zones, e.DNSName)
+			if zone == "" {
+				return fmt.Errorf("no zone found for %s", e.DNSName)
+			}
+
+			changeSets[zone] = append(changeSets[zone], toRRSetChange(e, changeType, p.defaultTTL()))
+		}
+		return nil
+	}
+
+	if err := add(changes.Delete, rc0go.ChangeTypeDELETE); err != nil {
+		return err
+	}
+
+	if err := add(changes.UpdateNew, rc0go.ChangeTypeUPDATE); err != nil {
+		return err
+	}
+
+	if err := add(changes.Create, rc0go.ChangeTypeADD); err != nil {
+		return err
+	}
+
+	var names []string
+	for zone := range changeSets {
+		names = append(names, zone)
+	}
+	sort.Strings(names)
+
+	for _, zone := range names {
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if p.OwnerID != "" {
+			if err := p.checkOwnership(ctx, zone, changeSets[zone]); err != nil {
+				return err
+			}
+		}
+
+		batchSize := p.BatchSize
+		if batchSize <= 0 {
+			batchSize = defaultBatchSize
+		}
+
+		_, err := p.client.RRSet.SubmitChangeSetInChunks(zone, changeSets[zone], &rc0go.ChangeSetChunkOptions{ChunkSize: batchSize})
+		if err != nil {
+			return fmt.Errorf("zone %s: %v", zone, err)
+		}
+	}
+
+	return nil
+}
+
+// checkOwnership refuses changes of names owned by another ExternalDNS instance
+func (p *Provider) checkOwnership(ctx context.Context, zone string, changeSet []*rc0go.RRSetChange) error {
+
+	rrsets, err := p.client.RRSet.ListAll(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	owners := RegistryOwners(rrsets)
+
+	for _, change := range changeSet {
+		if owner, ok := owners[change.Name]; ok && owner != p.OwnerID {
+			return fmt.Errorf("refusing to %s %s %s owned by %q", change.ChangeType, change.Name, change.Type, owner)
+		}
+	}
+
+	return nil
+}
+
+// zones returns the names of the managed zones of the account
+func (p *Provider) zones(ctx context.Context) ([]string, error) {
+
+	names, err := rc0go.ZoneNames(ctx, p.client.Zones)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []string
+
+	for _, name := range names {
+		if p.managesZone(name) {
+			zones = append(zones, strings.TrimSuffix(strings.ToLower(name), "."))
+		}
+	}
+
+	return zones, nil
+}
+
+// managesZone reports whether the zone contains or is below one of the managed domains
+func (p *Provider) managesZone(zone string) bool {
+
+	if len(p.Domains) == 0 {
+		return true
+	}
+
+	zone = rc0go.NormalizeName(zone)
+
+	for _, domain := range p.Domains {
+		domain = rc0go.NormalizeName(domain)
+		if isSubdomain(zone, domain) || isSubdomain(domain, zone) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matches reports whether the name is within the managed and outside of the excluded domains
+func (p *Provider) matches(name string) bool {
+
+	name = rc0go.NormalizeName(name)
+
+	for _, domain := range p.ExcludeDomains {
+		if isSubdomain(name, rc0go.NormalizeName(domain)) {
+			return false
+		}
+	}
+
+	if len(p.Domains) == 0 {
+		return true
+	}
+
+	for _, domain := range p.Domains {
+		if isSubdomain(name, rc0go.NormalizeName(domain)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Provider) defaultTTL() int {
+	if p.DefaultTTL <= 0 {
+		return defaultTTL
+	}
+	return p.DefaultTTL
+}
+
+// findZone returns the most specific zone of the name
+func findZone(zones []string, name string) string {
+
+	name = rc0go.NormalizeName(name)
+
+	found := ""
+	for _, zone := range zones {
+		if isSubdomain(name, rc0go.NormalizeName(zone)) && len(zone) > len(found) {
+			found = zone
+		}
+	}
+
+	return found
+}
+
+// isSubdomain reports whether name equals domain or is below it (both fully qualified)
+func isSubdomain(name string, domain string) bool {
+	return name == domain || strings.HasSuffix(name, "."+domain)
+}
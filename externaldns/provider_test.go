@@ -0,0 +1,132 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package externaldns
+
+import (
+	"context"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/rc0test"
+	"reflect"
+	"testing"
+)
+
+func setup() (*Provider, *rc0test.Server) {
+
+	server := rc0test.NewServer()
+
+	server.AddZone("testzone1.at",
+		&rc0go.RRType{Name: "testzone1.at.", Type: "SOA", TTL: 3600, Records: []*rc0go.Record{{Content: "sec1.rcode0.net. rcode0.nic.at. 1 10800 3600 604800 3600"}}},
+		&rc0go.RRType{Name: "www.testzone1.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.80"}}},
+		&rc0go.RRType{Name: "web.k8s.testzone1.at.", Type: "CNAME", TTL: 60, Records: []*rc0go.Record{{Content: "lb.testzone1.at."}}},
+		&rc0go.RRType{Name: "cname-web.k8s.testzone1.at.", Type: "TXT", TTL: 60, Records: []*rc0go.Record{{Content: `"heritage=external-dns,external-dns/owner=cluster1"`}}},
+	)
+
+	server.AddZone("testzone2.at",
+		&rc0go.RRType{Name: "www.testzone2.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.81"}}},
+	)
+
+	return NewProvider(server.Client()), server
+}
+
+func TestProvider_Records(t *testing.T) {
+
+	provider, server := setup()
+	defer server.Close()
+
+	provider.Domains = []string{"k8s.testzone1.at"}
+
+	endpoints, err := provider.Records(context.Background())
+	if err != nil {
+		t.Fatalf("Provider.Records returned error: %v", err)
+	}
+
+	want := []*Endpoint{
+		{DNSName: "web.k8s.testzone1.at", RecordType: "CNAME", RecordTTL: 60, Targets: []string{"lb.testzone1.at"}},
+		{DNSName: "cname-web.k8s.testzone1.at", RecordType: "TXT", RecordTTL: 60, Targets: []string{`"heritage=external-dns,external-dns/owner=cluster1"`}},
+	}
+
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("Provider.Records returned %+v, want %+v", endpoints, want)
+	}
+}
+
+func TestProvider_ApplyChanges(t *testing.T) {
+
+	provider, server := setup()
+	defer server.Close()
+
+	provider.BatchSize = 1
+
+	changes := &Changes{
+		Create: []*Endpoint{
+			{DNSName: "api.testzone2.at", RecordType: "A", Targets: []string{"10.10.0.82"}},
+			{DNSName: "a-api.testzone2.at", RecordType: "TXT", Targets: []string{"heritage=external-dns,external-dns/owner=cluster1"}},
+		},
+		UpdateOld: []*Endpoint{{DNSName: "www.testzone1.at", RecordType: "A", RecordTTL: 300, Targets: []string{"10.10.0.80"}}},
+		UpdateNew: []*Endpoint{{DNSName: "www.testzone1.at", RecordType: "A", RecordTTL: 600, Targets: []string{"10.10.0.90"}}},
+	}
+
+	if err := provider.ApplyChanges(context.Background(), changes); err != nil {
+		t.Fatalf("Provider.ApplyChanges returned error: %v", err)
+	}
+
+	if got := len(server.Patches("testzone2.at")); got != 2 {
+		t.Errorf("Provider.ApplyChanges submitted %d batches for testzone2.at, want 2", got)
+	}
+
+	want := []*rc0go.RRType{
+		{Name: "a-api.testzone2.at.", Type: "TXT", TTL: defaultTTL, Records: []*rc0go.Record{{Content: `"heritage=external-dns,external-dns/owner=cluster1"`}}},
+		{Name: "api.testzone2.at.", Type: "A", TTL: defaultTTL, Records: []*rc0go.Record{{Content: "10.10.0.82"}}},
+		{Name: "www.testzone2.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.81"}}},
+	}
+
+	if got := server.RRSets("testzone2.at"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Provider.ApplyChanges left %+v, want %+v", got, want)
+	}
+
+	www := server.Patches("testzone1.at")[0][0]
+	if www.ChangeType != rc0go.ChangeTypeUPDATE || www.TTL != 600 || www.Records[0].Content != "10.10.0.90" {
+		t.Errorf("Provider.ApplyChanges submitted %+v", www)
+	}
+}
+
+func TestProvider_ApplyChanges_Ownership(t *testing.T) {
+
+	provider, server := setup()
+	defer server.Close()
+
+	provider.OwnerID = "cluster2"
+
+	changes := &Changes{
+		Delete: []*Endpoint{{DNSName: "web.k8s.testzone1.at", RecordType: "CNAME", Targets: []string{"lb.testzone1.at"}}},
+	}
+
+	if err := provider.ApplyChanges(context.Background(), changes); err == nil {
+		t.Errorf("Provider.ApplyChanges returned no error for a name owned by another owner")
+	}
+
+	provider.OwnerID = "cluster1"
+
+	if err := provider.ApplyChanges(context.Background(), changes); err != nil {
+		t.Errorf("Provider.ApplyChanges returned error: %v", err)
+	}
+}
+
+func TestProvider_ApplyChanges_OutsideDomains(t *testing.T) {
+
+	provider, server := setup()
+	defer server.Close()
+
+	provider.Domains = []string{"k8s.testzone1.at"}
+
+	changes := &Changes{
+		Create: []*Endpoint{{DNSName: "www.testzone2.at", RecordType: "A", Targets: []string{"10.10.0.1"}}},
+	}
+
+	if err := provider.ApplyChanges(context.Background(), changes); err == nil {
+		t.Errorf("Provider.ApplyChanges returned no error for a name outside of the domains")
+	}
+}
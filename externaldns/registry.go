@@ -0,0 +1,75 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package externaldns
+
+import (
+	"github.com/nic-at/rc0go"
+	"strings"
+)
+
+// ParseOwnership parses the content of an ExternalDNS TXT registry record, f.e.
+// "heritage=external-dns,external-dns/owner=default,external-dns/resource=ingress/default/web"
+// and returns the owner
+func ParseOwnership(content string) (owner string, ok bool) {
+
+	text, err := rc0go.UnquoteTXT(content)
+	if err != nil {
+		text = content
+	}
+
+	heritage := false
+
+	for _, field := range strings.Split(text, ",") {
+
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch kv[0] {
+		case "heritage":
+			heritage = kv[1] == "external-dns"
+		case "external-dns/owner":
+			owner = kv[1]
+		}
+	}
+
+	return owner, heritage && owner != ""
+}
+
+// RegistryOwners returns the owners of the names with TXT registry records. Registry records
+// either have the name of the record they own or a "<type>-" prefix on the first label
+// (f.e. "cname-www.example.at."); both names are mapped to the owner.
+func RegistryOwners(rrsets []*rc0go.RRType) map[string]string {
+
+	owners := make(map[string]string)
+
+	for _, rrset := range rrsets {
+
+		if !strings.EqualFold(rrset.Type, "TXT") {
+			continue
+		}
+
+		for _, r := range rrset.Records {
+
+			owner, ok := ParseOwnership(r.Content)
+			if !ok {
+				continue
+			}
+
+			name := rc0go.NormalizeName(rrset.Name)
+			owners[name] = owner
+
+			if i := strings.Index(name, "-"); i > 0 && i < strings.Index(name, ".") {
+				if supportedTypes[strings.ToUpper(name[:i])] {
+					owners[name[i+1:]] = owner
+				}
+			}
+		}
+	}
+
+	return owners
+}
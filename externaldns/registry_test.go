@@ -0,0 +1,43 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package externaldns
+
+import (
+	"github.com/nic-at/rc0go"
+	"reflect"
+	"testing"
+)
+
+func TestParseOwnership(t *testing.T) {
+
+	owner, ok := ParseOwnership(`"heritage=external-dns,external-dns/owner=cluster1,external-dns/resource=ingress/default/web"`)
+	if !ok || owner != "cluster1" {
+		t.Errorf("ParseOwnership returned %q, %v, want %q, true", owner, ok, "cluster1")
+	}
+
+	if _, ok := ParseOwnership(`"v=spf1 -all"`); ok {
+		t.Errorf("ParseOwnership accepted a record without heritage")
+	}
+}
+
+func TestRegistryOwners(t *testing.T) {
+
+	rrsets := []*rc0go.RRType{
+		{Name: "a-web.testzone1.at.", Type: "TXT", Records: []*rc0go.Record{{Content: `"heritage=external-dns,external-dns/owner=cluster1"`}}},
+		{Name: "api.testzone1.at.", Type: "TXT", Records: []*rc0go.Record{{Content: `"heritage=external-dns,external-dns/owner=cluster2"`}}},
+		{Name: "mail.testzone1.at.", Type: "TXT", Records: []*rc0go.Record{{Content: `"v=spf1 mx -all"`}}},
+	}
+
+	want := map[string]string{
+		"a-web.testzone1.at.": "cluster1",
+		"web.testzone1.at.":   "cluster1",
+		"api.testzone1.at.":   "cluster2",
+	}
+
+	if got := RegistryOwners(rrsets); !reflect.DeepEqual(got, want) {
+		t.Errorf("RegistryOwners returned %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package externaldns
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// MediaType is the content type of the ExternalDNS webhook protocol
+const MediaType = "application/external.dns.webhook+json;version=1"
+
+// Handler returns the HTTP handler implementing the ExternalDNS webhook protocol:
+//
+//	GET  /                 negotiation, returns the domain filter
+//	GET  /records          returns all managed endpoints
+//	POST /records          applies changes
+//	POST /adjustendpoints  normalizes endpoints
+//	GET  /healthz          health check
+func (p *Provider) Handler() http.Handler {
+
+	router := mux.NewRouter()
+
+	router.HandleFunc("/", p.negotiate).Methods("GET")
+	router.HandleFunc("/records", p.getRecords).Methods("GET")
+	router.HandleFunc("/records", p.applyChanges).Methods("POST")
+	router.HandleFunc("/adjustendpoints", p.adjustEndpoints).Methods("POST")
+	router.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}).Methods("GET")
+
+	return router
+}
+
+func (p *Provider) negotiate(w http.ResponseWriter, r *http.Request) {
+
+	filter, err := p.DomainFilter(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, filter)
+}
+
+func (p *Provider) getRecords(w http.ResponseWriter, r *http.Request) {
+
+	endpoints, err := p.Records(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if endpoints == nil {
+		endpoints = []*Endpoint{}
+	}
+
+	writeJSON(w, endpoints)
+}
+
+func (p *Provider) applyChanges(w http.ResponseWriter, r *http.Request) {
+
+	var changes Changes
+
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := p.ApplyChanges(r.Context(), &changes); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (p *Provider) adjustEndpoints(w http.ResponseWriter, r *http.Request) {
+
+	var endpoints []*Endpoint
+
+	if err := json.NewDecoder(r.Body).Decode(&endpoints); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, p.AdjustEndpoints(endpoints))
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+
+	w.Header().Set("Content-Type", MediaType)
+	w.Header().Set("Vary", "Content-Type")
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package externaldns
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+
+	provider, server := setup()
+	defer server.Close()
+
+	webhook := httptest.NewServer(provider.Handler())
+	defer webhook.Close()
+
+	// negotiation
+	resp, err := http.Get(webhook.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var filter DomainFilter
+	_ = json.NewDecoder(resp.Body).Decode(&filter)
+	resp.Body.Close()
+
+	if got := resp.Header.Get("Content-Type"); got != MediaType {
+		t.Errorf("negotiation returned content type %q, want %q", got, MediaType)
+	}
+
+	if want := []string{"testzone1.at", "testzone2.at"}; !reflect.DeepEqual(filter.Include, want) {
+		t.Errorf("negotiation returned %+v, want %+v", filter.Include, want)
+	}
+
+	// records
+	resp, err = http.Get(webhook.URL + "/records")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var endpoints []*Endpoint
+	_ = json.NewDecoder(resp.Body).Decode(&endpoints)
+	resp.Body.Close()
+
+	if len(endpoints) != 4 {
+		t.Errorf("GET /records returned %d endpoints, want 4", len(endpoints))
+	}
+
+	// adjust endpoints
+	resp, err = http.Post(webhook.URL+"/adjustendpoints", MediaType,
+		strings.NewReader(`[{"dnsName": "API.testzone1.at.", "recordType": "cname", "targets": ["lb.testzone1.at."]}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	endpoints = nil
+	_ = json.NewDecoder(resp.Body).Decode(&endpoints)
+	resp.Body.Close()
+
+	want := []*Endpoint{{DNSName: "api.testzone1.at", RecordType: "CNAME", RecordTTL: defaultTTL, Targets: []string{"lb.testzone1.at"}}}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("POST /adjustendpoints returned %+v, want %+v", endpoints, want)
+	}
+
+	// apply changes
+	resp, err = http.Post(webhook.URL+"/records", MediaType,
+		strings.NewReader(`{"create": [{"dnsName": "api.testzone1.at", "recordType": "CNAME", "targets": ["lb.testzone1.at"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("POST /records returned %s, want 204", resp.Status)
+	}
+
+	if got := len(server.Patches("testzone1.at")); got != 1 {
+		t.Errorf("POST /records submitted %d change sets, want 1", got)
+	}
+
+	// invalid changes
+	resp, err = http.Post(webhook.URL+"/records", MediaType, strings.NewReader(`{"create": [`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /records returned %s for invalid JSON, want 400", resp.Status)
+	}
+}
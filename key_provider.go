@@ -0,0 +1,325 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/crypto/scrypt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// KeyProvider supplies the keys for encrypted TXT records. New records are encrypted with
+// the current key; records encrypted with older keys are decrypted with the key matching
+// the key id stored in the record (see KeyID).
+type KeyProvider interface {
+
+	// CurrentKey returns the key used for encryption and its id
+	CurrentKey() (keyID string, key []byte, err error)
+
+	// KeyByID returns the key with the given id
+	KeyByID(keyID string) ([]byte, error)
+}
+
+// singleKeyProvider provides one key loaded on first use
+type singleKeyProvider struct {
+	load func() ([]byte, error)
+
+	once sync.Once
+	key  []byte
+	err  error
+}
+
+func (p *singleKeyProvider) CurrentKey() (string, []byte, error) {
+
+	p.once.Do(func() {
+		p.key, p.err = p.load()
+		if p.err == nil {
+			p.err = checkKeyLength(p.key)
+		}
+	})
+
+	if p.err != nil {
+		return "", nil, p.err
+	}
+
+	return KeyID(p.key), p.key, nil
+}
+
+func (p *singleKeyProvider) KeyByID(keyID string) ([]byte, error) {
+
+	id, key, err := p.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if id != keyID {
+		return nil, fmt.Errorf("unknown TXT encryption key %s", keyID)
+	}
+
+	return key, nil
+}
+
+// StaticKey returns a KeyProvider for the given raw key (16, 24 or 32 bytes)
+func StaticKey(key []byte) KeyProvider {
+	return &singleKeyProvider{load: func() ([]byte, error) {
+		return key, nil
+	}}
+}
+
+// EnvKey returns a KeyProvider for a hex or base64 encoded key read from the environment variable
+func EnvKey(name string) KeyProvider {
+	return &singleKeyProvider{load: func() ([]byte, error) {
+
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		return decodeKey(value)
+	}}
+}
+
+// FileKey returns a KeyProvider for a key read from the file. The file contains either the
+// hex or base64 encoded key or the raw key; the encoded forms take precedence.
+func FileKey(path string) KeyProvider {
+	return &singleKeyProvider{load: func() ([]byte, error) {
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if key, err := decodeKey(string(data)); err == nil {
+			return key, nil
+		}
+
+		if checkKeyLength(data) == nil {
+			return data, nil
+		}
+
+		return nil, fmt.Errorf("key file %s does not contain a valid key", path)
+	}}
+}
+
+// Parameters of the scrypt key derivation for passphrases
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// PassphraseKey returns a KeyProvider for a 32 byte key derived from the passphrase with scrypt.
+// The salt must be stored alongside the configuration, as the same passphrase and salt
+// always result in the same key.
+func PassphraseKey(passphrase string, salt []byte) KeyProvider {
+	return &singleKeyProvider{load: func() ([]byte, error) {
+
+		if passphrase == "" {
+			return nil, fmt.Errorf("passphrase is empty")
+		}
+
+		if len(salt) < 8 {
+			return nil, fmt.Errorf("salt must be at least 8 bytes")
+		}
+
+		return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, 32)
+	}}
+}
+
+// keyRing combines the current key with previous ones
+type keyRing struct {
+	current  KeyProvider
+	previous []KeyProvider
+}
+
+// NewKeyRing returns a KeyProvider which encrypts with the current key and decrypts with
+// the current or any of the previous keys. Use it during a key rotation until all records
+// are re-encrypted with RRSetService.RotateEncryptedTXT.
+func NewKeyRing(current KeyProvider, previous ...KeyProvider) KeyProvider {
+	return &keyRing{current: current, previous: previous}
+}
+
+func (r *keyRing) CurrentKey() (string, []byte, error) {
+	return r.current.CurrentKey()
+}
+
+func (r *keyRing) KeyByID(keyID string) ([]byte, error) {
+
+	for _, p := range append([]KeyProvider{r.current}, r.previous...) {
+		if key, err := p.KeyByID(keyID); err == nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown TXT encryption key %s", keyID)
+}
+
+// LegacyKeyProvider is a KeyProvider which also knows the key of records in the legacy
+// "ENC:" format. These records carry no key id and cannot be checked for the right key.
+type LegacyKeyProvider interface {
+	KeyProvider
+
+	// LegacyKey returns the key of records in the legacy format
+	LegacyKey() ([]byte, error)
+}
+
+// legacyKeyProvider adds a legacy key to a KeyProvider
+type legacyKeyProvider struct {
+	KeyProvider
+	legacy KeyProvider
+}
+
+// WithLegacyKey returns a KeyProvider which decrypts records in the legacy "ENC:" format with
+// the current key of legacy, f.e. WithLegacyKey(NewKeyRing(newKey, oldKey), oldKey)
+func WithLegacyKey(provider KeyProvider, legacy KeyProvider) KeyProvider {
+	return &legacyKeyProvider{KeyProvider: provider, legacy: legacy}
+}
+
+func (p *legacyKeyProvider) LegacyKey() ([]byte, error) {
+	_, key, err := p.legacy.CurrentKey()
+	return key, err
+}
+
+// EncryptTXTWithProvider encrypts the content of all records of the change with the current key of the provider
+func (s *RRSetService) EncryptTXTWithProvider(provider KeyProvider, rrType *RRSetChange) error {
+
+	keyID, key, err := provider.CurrentKey()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range rrType.Records {
+
+		content, err := encryptTXTValue(keyID, key, c.Content)
+		if err != nil {
+			return err
+		}
+
+		c.Content = content
+	}
+
+	return nil
+}
+
+// DecryptTXTWithProvider decrypts all encrypted records of the rrset with the key matching the
+// key id of each record. Records in the legacy "ENC:" format are decrypted with the legacy key
+// (see WithLegacyKey) or, without one, with the current key.
+func (s *RRSetService) DecryptTXTWithProvider(provider KeyProvider, rrType *RRType) error {
+
+	for _, c := range rrType.Records {
+
+		if !IsEncryptedTXT(c.Content) {
+			continue
+		}
+
+		text, err := decryptTXTWithProvider(provider, c.Content, false)
+		if err != nil {
+			return fmt.Errorf("rrset %s %s: %v", rrType.Name, rrType.Type, err)
+		}
+
+		c.Content = text
+	}
+
+	return nil
+}
+
+// RotateEncryptedTXT re-encrypts all encrypted TXT records of the zone which are not encrypted
+// with the current key of the provider (including records in the legacy "ENC:" format) and
+// submits them. The submitted changes are returned. As a wrong key cannot be detected for
+// legacy records, they are only rotated with an explicit legacy key (see WithLegacyKey);
+// otherwise nothing is submitted and an error is returned.
+func (s *RRSetService) RotateEncryptedTXT(ctx context.Context, zone string, provider KeyProvider) ([]*RRSetChange, error) {
+
+	currentID, currentKey, err := provider.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+
+	outdated := func(content string) bool {
+		if !IsEncryptedTXT(content) {
+			return false
+		}
+		keyID, ok := EncryptedTXTKeyID(content)
+		return !ok || keyID != currentID
+	}
+
+	return reencryptTXT(ctx, s, zone, outdated, func(content string) (string, error) {
+
+		plaintext, err := decryptTXTWithProvider(provider, content, true)
+		if err != nil {
+			return "", err
+		}
+
+		return encryptTXTValue(currentID, currentKey, plaintext)
+	})
+}
+
+// decryptTXTWithProvider decrypts the content. requireLegacyKey refuses to decrypt legacy
+// records without an explicit legacy key.
+func decryptTXTWithProvider(provider KeyProvider, content string, requireLegacyKey bool) (string, error) {
+
+	var key []byte
+	var err error
+
+	if keyID, ok := EncryptedTXTKeyID(content); ok {
+		key, err = provider.KeyByID(keyID)
+	} else if legacy, ok := provider.(LegacyKeyProvider); ok {
+		key, err = legacy.LegacyKey()
+	} else if requireLegacyKey {
+		err = fmt.Errorf("record in legacy \"ENC:\" format has no key id, a legacy key is required (see WithLegacyKey)")
+	} else {
+		_, key, err = provider.CurrentKey()
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	text, err := encryptedTXTText(content)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(text, legacyEncryptedTXTPrefix) {
+		return decryptLegacyTXT(key, strings.TrimPrefix(text, legacyEncryptedTXTPrefix))
+	}
+
+	return decryptTXTValue(key, text)
+}
+
+// decodeKey decodes a hex or base64 encoded key
+func decodeKey(value string) ([]byte, error) {
+
+	value = strings.TrimSpace(value)
+
+	if key, err := hex.DecodeString(value); err == nil && checkKeyLength(key) == nil {
+		return key, nil
+	}
+
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := encoding.DecodeString(value); err == nil && checkKeyLength(key) == nil {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("key is neither a hex nor a base64 encoded AES key")
+}
+
+func checkKeyLength(key []byte) error {
+
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	}
+
+	return fmt.Errorf("invalid TXT encryption key length %d (must be 16, 24 or 32 bytes)", len(key))
+}
@@ -0,0 +1,198 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeyProviders(t *testing.T) {
+
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i * 7)
+	}
+
+	dir, err := ioutil.TempDir("", "rc0go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	rawFile := filepath.Join(dir, "raw.key")
+	hexFile := filepath.Join(dir, "hex.key")
+	_ = ioutil.WriteFile(rawFile, key, 0600)
+	_ = ioutil.WriteFile(hexFile, []byte(hex.EncodeToString(key)+"\n"), 0600)
+
+	os.Setenv("RC0GO_TEST_TXT_KEY", base64.StdEncoding.EncodeToString(key))
+	defer os.Unsetenv("RC0GO_TEST_TXT_KEY")
+
+	providers := map[string]KeyProvider{
+		"static":   StaticKey(key),
+		"env":      EnvKey("RC0GO_TEST_TXT_KEY"),
+		"raw file": FileKey(rawFile),
+		"hex file": FileKey(hexFile),
+	}
+
+	for name, p := range providers {
+
+		keyID, got, err := p.CurrentKey()
+		if err != nil {
+			t.Errorf("%s: CurrentKey returned error: %v", name, err)
+			continue
+		}
+
+		if string(got) != string(key) || keyID != KeyID(key) {
+			t.Errorf("%s: CurrentKey returned %x (%s)", name, got, keyID)
+		}
+
+		if _, err := p.KeyByID("00000000"); err == nil {
+			t.Errorf("%s: KeyByID returned no error for an unknown key id", name)
+		}
+	}
+
+	for name, p := range map[string]KeyProvider{
+		"short":       StaticKey([]byte("short")),
+		"missing env": EnvKey("RC0GO_TEST_TXT_KEY_MISSING"),
+		"no file":     FileKey(filepath.Join(dir, "missing.key")),
+		"no salt":     PassphraseKey("passphrase", nil),
+	} {
+		if _, _, err := p.CurrentKey(); err == nil {
+			t.Errorf("%s: CurrentKey returned no error", name)
+		}
+	}
+}
+
+func TestPassphraseKey(t *testing.T) {
+
+	salt := []byte("rc0go-test-salt")
+
+	_, a, err := PassphraseKey("correct horse battery staple", salt).CurrentKey()
+	if err != nil {
+		t.Fatalf("PassphraseKey returned error: %v", err)
+	}
+
+	_, b, _ := PassphraseKey("correct horse battery staple", salt).CurrentKey()
+	_, c, _ := PassphraseKey("another passphrase", salt).CurrentKey()
+
+	if len(a) != 32 || string(a) != string(b) || string(a) == string(c) {
+		t.Errorf("PassphraseKey derived %x, %x and %x", a, b, c)
+	}
+}
+
+func TestRRSetService_RotateEncryptedTXT(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	oldKey := StaticKey([]byte("0123456789abcdef0123456789abcdef"))
+	newKey := StaticKey([]byte("fedcba9876543210fedcba9876543210"))
+
+	change := &RRSetChange{Name: "secret.testzone1.at.", Type: "TXT", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "old secret"}}}
+	if err := client.RRSet.EncryptTXTWithProvider(oldKey, change); err != nil {
+		t.Fatalf("RRSet.EncryptTXTWithProvider returned error: %v", err)
+	}
+
+	_, legacyKey, _ := oldKey.CurrentKey()
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: change.Name, Type: "TXT", TTL: 3600, Records: change.Records},
+			{Name: "legacy.testzone1.at.", Type: "TXT", TTL: 3600, Records: []*Record{{Content: legacyEncryptTXT(legacyKey, "legacy secret")}}},
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	ring := NewKeyRing(newKey, oldKey)
+
+	// Records encrypted with the old key can be decrypted during the rotation
+	rrset := &RRType{Name: change.Name, Type: "TXT", Records: copyRecords(change.Records)}
+	if err := client.RRSet.DecryptTXTWithProvider(ring, rrset); err != nil || rrset.Records[0].Content != "old secret" {
+		t.Errorf("RRSet.DecryptTXTWithProvider returned %q, %v", rrset.Records[0].Content, err)
+	}
+
+	// The legacy record has no key id and is only rotated with an explicit legacy key
+	rotated, err := client.RRSet.RotateEncryptedTXT(context.Background(), "testzone1.at", ring)
+	if err == nil || len(rotated) != 0 || len(zone.patches) != 0 {
+		t.Fatalf("RRSet.RotateEncryptedTXT without legacy key returned %d changes, %v", len(rotated), err)
+	}
+
+	rotated, err = client.RRSet.RotateEncryptedTXT(context.Background(), "testzone1.at", WithLegacyKey(ring, oldKey))
+	if err != nil {
+		t.Fatalf("RRSet.RotateEncryptedTXT returned error: %v", err)
+	}
+
+	if len(rotated) != 2 {
+		t.Errorf("RRSet.RotateEncryptedTXT re-encrypted %d rrsets, want 2", len(rotated))
+	}
+
+	newID, _, _ := newKey.CurrentKey()
+
+	for _, rrset := range zone.rrsets {
+
+		if keyID, _ := EncryptedTXTKeyID(rrset.Records[0].Content); keyID != newID {
+			t.Errorf("rrset %s is encrypted with key %s, want %s", rrset.Name, keyID, newID)
+		}
+
+		if err := client.RRSet.DecryptTXTWithProvider(newKey, rrset); err != nil {
+			t.Errorf("RRSet.DecryptTXTWithProvider returned error: %v", err)
+		}
+	}
+
+	texts := make(map[string]string)
+	for _, rrset := range zone.rrsets {
+		texts[rrset.Name] = rrset.Records[0].Content
+	}
+
+	if texts["legacy.testzone1.at."] != "legacy secret" || texts[change.Name] != "old secret" {
+		t.Errorf("rotated records decrypt to %q", texts)
+	}
+}
+
+func TestRRSetService_RotateEncryptedTXTLegacyKey(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	legacyKey := StaticKey([]byte("0123456789abcdef0123456789abcdef"))
+	currentKey := StaticKey([]byte("fedcba9876543210fedcba9876543210"))
+	newKey := StaticKey([]byte("00112233445566778899aabbccddeeff"))
+
+	_, rawLegacyKey, _ := legacyKey.CurrentKey()
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: "legacy.testzone1.at.", Type: "TXT", TTL: 3600, Records: []*Record{{Content: legacyEncryptTXT(rawLegacyKey, "legacy secret")}}},
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	// the legacy record was written under a key which is not the current one
+	if _, err := client.RRSet.RotateEncryptedTXT(context.Background(), "testzone1.at", NewKeyRing(newKey, currentKey)); err == nil {
+		t.Fatal("RRSet.RotateEncryptedTXT rotated a legacy record without legacy key")
+	}
+
+	if len(zone.patches) != 0 {
+		t.Fatalf("RRSet.RotateEncryptedTXT submitted %d change sets", len(zone.patches))
+	}
+
+	if _, err := client.RRSet.RotateEncryptedTXT(context.Background(), "testzone1.at", WithLegacyKey(NewKeyRing(newKey, currentKey), legacyKey)); err != nil {
+		t.Fatalf("RRSet.RotateEncryptedTXT returned error: %v", err)
+	}
+
+	rrset := zone.rrsets[0]
+	if err := client.RRSet.DecryptTXTWithProvider(newKey, rrset); err != nil || rrset.Records[0].Content != "legacy secret" {
+		t.Errorf("rotated legacy record decrypts to %q, %v", rrset.Records[0].Content, err)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package lint checks the rrsets of rcode0 zones against DNS best practices
+package lint
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"sort"
+	"strings"
+)
+
+// Severity of a finding, named after the SARIF result levels
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+// Finding is a single rule violation
+type Finding struct {
+	RuleID   string   `json:"rule_id"`
+	Severity Severity `json:"severity"`
+	Zone     string   `json:"zone"`
+	Name     string   `json:"name,omitempty"`
+	Type     string   `json:"type,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// String returns f.e. "example.at: warning caa-missing: example.at. CAA: ..."
+func (f *Finding) String() string {
+
+	if f.Name == "" {
+		return fmt.Sprintf("%s: %s %s: %s", f.Zone, f.Severity, f.RuleID, f.Message)
+	}
+
+	return fmt.Sprintf("%s: %s %s: %s %s: %s", f.Zone, f.Severity, f.RuleID, f.Name, f.Type, f.Message)
+}
+
+// ZoneData is the input of the rules
+type ZoneData struct {
+	Zone   *rc0go.Zone
+	RRSets []*rc0go.RRType
+
+	origin string
+	names  map[string][]*rc0go.RRType
+}
+
+// NewZoneData indexes the rrsets of the zone for the rules
+func NewZoneData(zone *rc0go.Zone, rrsets []*rc0go.RRType) *ZoneData {
+
+	d := &ZoneData{
+		Zone:   zone,
+		RRSets: rrsets,
+		origin: rc0go.NormalizeName(zone.Domain),
+		names:  make(map[string][]*rc0go.RRType),
+	}
+
+	for _, rrset := range rrsets {
+		name := rc0go.NormalizeName(rrset.Name)
+		d.names[name] = append(d.names[name], rrset)
+	}
+
+	return d
+}
+
+// Origin returns the normalized zone name, f.e. "example.at."
+func (d *ZoneData) Origin() string {
+	return d.origin
+}
+
+// Lookup returns the rrset with the given name and type or nil
+func (d *ZoneData) Lookup(name string, rrType string) *rc0go.RRType {
+
+	for _, rrset := range d.names[rc0go.NormalizeName(name)] {
+		if strings.EqualFold(rrset.Type, rrType) {
+			return rrset
+		}
+	}
+
+	return nil
+}
+
+// Exists reports whether the name has rrsets, directly or through a wildcard
+func (d *ZoneData) Exists(name string) bool {
+
+	name = rc0go.NormalizeName(name)
+
+	if len(d.names[name]) > 0 {
+		return true
+	}
+
+	for n := name; strings.HasSuffix(n, d.origin) && n != d.origin; {
+		n = n[strings.Index(n, ".")+1:]
+		if len(d.names["*."+n]) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// InZone reports whether the name belongs to the zone
+func (d *ZoneData) InZone(name string) bool {
+	name = rc0go.NormalizeName(name)
+	return name == d.origin || strings.HasSuffix(name, "."+d.origin)
+}
+
+// Rule is a named check
+type Rule struct {
+	ID          string
+	Severity    Severity
+	Description string
+
+	// Check returns the messages of the violations, the linter fills in rule ID, severity and zone
+	Check func(d *ZoneData) []*Finding
+}
+
+// Linter runs rules over zones
+type Linter struct {
+	Zones rc0go.ZoneManagementServiceInterface
+	RRSet rc0go.RRSetServiceInterface
+	Rules []*Rule
+
+	// Suppressions maps zone names (with or without trailing dot) to rule IDs which are
+	// not reported for the zone. The zone "*" applies to all zones.
+	Suppressions map[string][]string
+}
+
+// NewLinter returns a linter with the default rules
+func NewLinter(client *rc0go.Client) *Linter {
+	return &Linter{
+		Zones:        client.Zones,
+		RRSet:        client.RRSet,
+		Rules:        DefaultRules(),
+		Suppressions: make(map[string][]string),
+	}
+}
+
+// Suppress hides the findings of the rules for the zone
+func (l *Linter) Suppress(zone string, ruleIDs ...string) {
+
+	if l.Suppressions == nil {
+		l.Suppressions = make(map[string][]string)
+	}
+
+	if zone != "*" {
+		zone = rc0go.NormalizeName(zone)
+	}
+
+	l.Suppressions[zone] = append(l.Suppressions[zone], ruleIDs...)
+}
+
+// Lint fetches the zones and their rrsets and returns the findings of all zones
+func (l *Linter) Lint(ctx context.Context, zones ...string) ([]*Finding, error) {
+
+	var findings []*Finding
+
+	for _, name := range zones {
+
+		zone, err := l.Zones.Get(name)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %v", name, err)
+		}
+
+		if zone == nil || zone.Domain == "" {
+			zone = &rc0go.Zone{Domain: name}
+		}
+
+		rrsets, err := l.RRSet.ListAll(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %v", name, err)
+		}
+
+		findings = append(findings, l.Check(zone, rrsets)...)
+	}
+
+	return findings, nil
+}
+
+// Check runs the rules over the rrsets of the zone without fetching anything
+func (l *Linter) Check(zone *rc0go.Zone, rrsets []*rc0go.RRType) []*Finding {
+
+	d := NewZoneData(zone, rrsets)
+
+	var findings []*Finding
+
+	for _, rule := range l.Rules {
+
+		if l.suppressed(d.origin, rule.ID) {
+			continue
+		}
+
+		for _, f := range rule.Check(d) {
+			f.RuleID = rule.ID
+			f.Severity = rule.Severity
+			f.Zone = strings.TrimSuffix(d.origin, ".")
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return severityRank(findings[i].Severity) < severityRank(findings[j].Severity)
+	})
+
+	return findings
+}
+
+func (l *Linter) suppressed(origin string, ruleID string) bool {
+
+	for zone, ids := range l.Suppressions {
+
+		if zone != "*" && rc0go.NormalizeName(zone) != origin {
+			continue
+		}
+
+		for _, id := range ids {
+			if id == ruleID {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func severityRank(s Severity) int {
+	switch s {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	}
+	return 2
+}
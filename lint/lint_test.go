@@ -0,0 +1,67 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lint
+
+import (
+	"context"
+	"github.com/nic-at/rc0go/rc0test"
+	"reflect"
+	"testing"
+)
+
+func TestLinter_Lint(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	server.AddZone("example.at",
+		rc0test.RRSet("example.at.", "TXT", `"v=spf1 -all"`),
+		rc0test.RRSet("example.at.", "CAA", `0 issue ";"`),
+	)
+	server.AddZone("example.com")
+
+	l := NewLinter(server.Client())
+	l.Suppress("example.com", RuleSPFMissing, RuleCAAMissing)
+	l.Suppress("*", RuleDMARCMissing)
+
+	findings, err := l.Lint(context.Background(), "example.at", "example.com")
+	if err != nil {
+		t.Fatalf("Lint returned error: %v", err)
+	}
+
+	if len(findings) != 0 {
+		t.Errorf("Lint returned %v, want no findings", findings)
+	}
+
+	l.Suppressions = nil
+
+	findings, err = l.Lint(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Lint returned error: %v", err)
+	}
+
+	want := []*Finding{
+		{RuleID: RuleSPFMissing, Severity: SeverityWarning, Zone: "example.com", Name: "example.com.", Type: "TXT", Message: findings[0].Message},
+		{RuleID: RuleDMARCMissing, Severity: SeverityWarning, Zone: "example.com", Name: "_dmarc.example.com.", Type: "TXT", Message: findings[1].Message},
+		{RuleID: RuleCAAMissing, Severity: SeverityNote, Zone: "example.com", Name: "example.com.", Type: "CAA", Message: findings[2].Message},
+	}
+
+	if !reflect.DeepEqual(findings, want) {
+		t.Errorf("Lint returned %v, want %v", findings, want)
+	}
+
+	if got := findings[2].String(); got != "example.com: note caa-missing: example.com. CAA: "+findings[2].Message {
+		t.Errorf("String returned %q", got)
+	}
+
+	// zone names in the map need no trailing dot
+	l.Suppressions = map[string][]string{"Example.com": {RuleSPFMissing, RuleDMARCMissing, RuleCAAMissing}}
+
+	if findings, err = l.Lint(context.Background(), "example.com"); err != nil || len(findings) != 0 {
+		t.Errorf("Lint returned %v, %v, want no findings", findings, err)
+	}
+
+}
@@ -0,0 +1,145 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lint
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "rc0lint"
+	toolURI      = "https://github.com/nic-at/rc0go"
+)
+
+// WriteJSON writes the findings as JSON array
+func WriteJSON(w io.Writer, findings []*Finding) error {
+
+	if findings == nil {
+		findings = []*Finding{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(findings)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level Severity `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     Severity        `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+// WriteSARIF writes the findings as SARIF 2.1.0 log with a single run, f.e. for code scanning
+// dashboards. The rules describe the rule IDs of the findings.
+func WriteSARIF(w io.Writer, rules []*Rule, findings []*Finding) error {
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           toolName,
+			InformationURI: toolURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	index := make(map[string]int)
+
+	for _, rule := range rules {
+		index[rule.ID] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   rule.ID,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: rule.Severity},
+		})
+	}
+
+	for _, f := range findings {
+
+		ruleIndex, ok := index[f.RuleID]
+		if !ok {
+			ruleIndex = -1
+		}
+
+		name := f.Name
+		if name == "" {
+			name = f.Zone
+		}
+
+		parts := []string{f.Zone, name}
+		if f.Type != "" {
+			parts = append(parts, f.Type)
+		}
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.RuleID,
+			RuleIndex: ruleIndex,
+			Level:     f.Severity,
+			Message:   sarifMessage{Text: f.Message},
+			Locations: []sarifLocation{{LogicalLocations: []sarifLogicalLocation{{
+				Name:               name,
+				FullyQualifiedName: strings.Join(parts, "/"),
+				Kind:               "resource",
+			}}}},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(&sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
@@ -0,0 +1,82 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func testFindings() []*Finding {
+	return []*Finding{
+		{RuleID: RuleMXCNAME, Severity: SeverityError, Zone: "example.at", Name: "example.at.", Type: "MX", Message: "exchange mail.example.at. is a CNAME"},
+		{RuleID: "custom", Severity: SeverityNote, Zone: "example.at", Message: "zone level"},
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, testFindings()); err != nil {
+		t.Fatalf("WriteJSON returned error: %v", err)
+	}
+
+	var got []map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteJSON wrote invalid JSON: %v", err)
+	}
+
+	if len(got) != 2 || got[0]["rule_id"] != RuleMXCNAME || got[0]["severity"] != "error" || got[1]["name"] != "" {
+		t.Errorf("WriteJSON wrote %s", buf.String())
+	}
+
+	buf.Reset()
+	_ = WriteJSON(&buf, nil)
+	if buf.String() != "[]\n" {
+		t.Errorf("WriteJSON without findings wrote %q", buf.String())
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, DefaultRules(), testFindings()); err != nil {
+		t.Fatalf("WriteSARIF returned error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("WriteSARIF wrote invalid JSON: %v", err)
+	}
+
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("WriteSARIF wrote %s", buf.String())
+	}
+
+	run := log.Runs[0]
+
+	if len(run.Tool.Driver.Rules) != len(DefaultRules()) {
+		t.Errorf("got %d rules, want %d", len(run.Tool.Driver.Rules), len(DefaultRules()))
+	}
+
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(run.Results))
+	}
+
+	mx := run.Results[0]
+	if run.Tool.Driver.Rules[mx.RuleIndex].ID != RuleMXCNAME || mx.Level != SeverityError {
+		t.Errorf("result %+v does not reference its rule", mx)
+	}
+
+	if fqn := mx.Locations[0].LogicalLocations[0].FullyQualifiedName; fqn != "example.at/example.at./MX" {
+		t.Errorf("got location %q", fqn)
+	}
+
+	if run.Results[1].RuleIndex != -1 || run.Results[1].Locations[0].LogicalLocations[0].FullyQualifiedName != "example.at/example.at" {
+		t.Errorf("got result %+v for an unknown rule", run.Results[1])
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lint
+
+import (
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"strings"
+)
+
+// Rule IDs of the default rules
+const (
+	RuleSPFMissing      = "spf-missing"
+	RuleSPFMultiple     = "spf-multiple"
+	RuleSPFType         = "spf-rr-type"
+	RuleDMARCMissing    = "dmarc-missing"
+	RuleCNAMEDangling   = "cname-dangling"
+	RuleMXCNAME         = "mx-cname"
+	RuleCAAMissing      = "caa-missing"
+	RuleDisabledRecords = "disabled-records"
+)
+
+// DefaultRules returns all rules of this package
+func DefaultRules() []*Rule {
+	return []*Rule{
+		{
+			ID:          RuleSPFMissing,
+			Severity:    SeverityWarning,
+			Description: "The zone apex has no SPF TXT record",
+			Check:       checkSPFMissing,
+		},
+		{
+			ID:          RuleSPFMultiple,
+			Severity:    SeverityError,
+			Description: "A name has more than one SPF TXT record, which is a permanent error (RFC 7208 4.5)",
+			Check:       checkSPFMultiple,
+		},
+		{
+			ID:          RuleSPFType,
+			Severity:    SeverityWarning,
+			Description: "A name has an rrset of the obsolete SPF type, which duplicates the TXT record (RFC 7208 3.1)",
+			Check:       checkSPFType,
+		},
+		{
+			ID:          RuleDMARCMissing,
+			Severity:    SeverityWarning,
+			Description: "The zone has no DMARC record at _dmarc",
+			Check:       checkDMARCMissing,
+		},
+		{
+			ID:          RuleCNAMEDangling,
+			Severity:    SeverityError,
+			Description: "A CNAME points to a name within the zone which does not exist",
+			Check:       checkCNAMEDangling,
+		},
+		{
+			ID:          RuleMXCNAME,
+			Severity:    SeverityError,
+			Description: "An MX record points to a CNAME (RFC 2181 10.3)",
+			Check:       checkMXCNAME,
+		},
+		{
+			ID:          RuleCAAMissing,
+			Severity:    SeverityNote,
+			Description: "The zone apex has no CAA rrset restricting certificate authorities",
+			Check:       checkCAAMissing,
+		},
+		{
+			ID:          RuleDisabledRecords,
+			Severity:    SeverityNote,
+			Description: "An rrset contains disabled records",
+			Check:       checkDisabledRecords,
+		},
+	}
+}
+
+func checkSPFMissing(d *ZoneData) []*Finding {
+
+	if len(spfRecords(d.Lookup(d.Origin(), "TXT"))) > 0 {
+		return nil
+	}
+
+	return []*Finding{{Name: d.Origin(), Type: "TXT", Message: "no \"v=spf1\" record, add f.e. \"v=spf1 -all\" if the zone sends no mail"}}
+}
+
+func checkSPFMultiple(d *ZoneData) []*Finding {
+
+	var findings []*Finding
+
+	for _, rrset := range d.RRSets {
+		if !strings.EqualFold(rrset.Type, "TXT") {
+			continue
+		}
+		if n := len(spfRecords(rrset)); n > 1 {
+			findings = append(findings, &Finding{Name: rrset.Name, Type: rrset.Type, Message: fmt.Sprintf("%d \"v=spf1\" records", n)})
+		}
+	}
+
+	return findings
+}
+
+func checkSPFType(d *ZoneData) []*Finding {
+
+	var findings []*Finding
+
+	for _, rrset := range d.RRSets {
+		if strings.EqualFold(rrset.Type, "SPF") {
+			findings = append(findings, &Finding{Name: rrset.Name, Type: rrset.Type, Message: "SPF rrset type is obsolete, publish the policy as TXT only"})
+		}
+	}
+
+	return findings
+}
+
+func checkDMARCMissing(d *ZoneData) []*Finding {
+
+	name := "_dmarc." + d.Origin()
+
+	for _, text := range texts(d.Lookup(name, "TXT")) {
+		if rc0go.HasTXTVersion(text, "v=DMARC1") {
+			return nil
+		}
+	}
+
+	return []*Finding{{Name: name, Type: "TXT", Message: "no \"v=DMARC1\" record"}}
+}
+
+func checkCNAMEDangling(d *ZoneData) []*Finding {
+
+	var findings []*Finding
+
+	for _, rrset := range d.RRSets {
+
+		if !strings.EqualFold(rrset.Type, "CNAME") {
+			continue
+		}
+
+		for _, r := range rrset.Records {
+			if d.InZone(r.Content) && !d.Exists(r.Content) {
+				findings = append(findings, &Finding{Name: rrset.Name, Type: rrset.Type, Message: fmt.Sprintf("target %s does not exist", rc0go.NormalizeName(r.Content))})
+			}
+		}
+	}
+
+	return findings
+}
+
+func checkMXCNAME(d *ZoneData) []*Finding {
+
+	var findings []*Finding
+
+	for _, rrset := range d.RRSets {
+
+		if !strings.EqualFold(rrset.Type, "MX") {
+			continue
+		}
+
+		for _, r := range rrset.Records {
+
+			fields := strings.Fields(r.Content)
+			if len(fields) != 2 {
+				continue
+			}
+
+			if d.Lookup(fields[1], "CNAME") != nil {
+				findings = append(findings, &Finding{Name: rrset.Name, Type: rrset.Type, Message: fmt.Sprintf("exchange %s is a CNAME", rc0go.NormalizeName(fields[1]))})
+			}
+		}
+	}
+
+	return findings
+}
+
+func checkCAAMissing(d *ZoneData) []*Finding {
+
+	if d.Lookup(d.Origin(), "CAA") != nil {
+		return nil
+	}
+
+	return []*Finding{{Name: d.Origin(), Type: "CAA", Message: "no CAA rrset, any certificate authority may issue certificates"}}
+}
+
+func checkDisabledRecords(d *ZoneData) []*Finding {
+
+	var findings []*Finding
+
+	for _, rrset := range d.RRSets {
+
+		disabled := 0
+		for _, r := range rrset.Records {
+			if r.Disabled {
+				disabled++
+			}
+		}
+
+		if disabled > 0 {
+			findings = append(findings, &Finding{Name: rrset.Name, Type: rrset.Type, Message: fmt.Sprintf("%d of %d record(s) disabled", disabled, len(rrset.Records))})
+		}
+	}
+
+	return findings
+}
+
+// texts returns the unquoted TXT records of the rrset, skipping malformed ones
+func texts(rrset *rc0go.RRType) []string {
+
+	if rrset == nil {
+		return nil
+	}
+
+	var result []string
+
+	for _, r := range rrset.Records {
+		if r.Disabled {
+			continue
+		}
+		if text, err := rc0go.UnquoteTXT(r.Content); err == nil {
+			result = append(result, text)
+		}
+	}
+
+	return result
+}
+
+func spfRecords(rrset *rc0go.RRType) []string {
+
+	var result []string
+
+	for _, text := range texts(rrset) {
+		if rc0go.HasTXTVersion(text, "v=spf1") {
+			result = append(result, text)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,115 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package lint
+
+import (
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/rc0test"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func ruleIDs(findings []*Finding) []string {
+	var ids []string
+	for _, f := range findings {
+		ids = append(ids, f.RuleID+" "+f.Name)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestDefaultRules(t *testing.T) {
+
+	disabled := rc0test.RRSet("old.example.at.", "A", "10.10.0.1", "10.10.0.2")
+	disabled.Records[1].Disabled = true
+
+	tests := []struct {
+		name   string
+		rrsets []*rc0go.RRType
+		want   []string
+	}{
+		{
+			name: "clean",
+			rrsets: []*rc0go.RRType{
+				rc0test.RRSet("example.at.", "TXT", `"v=spf1 mx -all"`, `"google-site-verification=abc"`),
+				rc0test.RRSet("_dmarc.example.at.", "TXT", `"v=DMARC1; p=reject"`),
+				rc0test.RRSet("example.at.", "CAA", `0 issue "letsencrypt.org"`),
+				rc0test.RRSet("example.at.", "MX", "10 mail.example.at."),
+				rc0test.RRSet("mail.example.at.", "A", "10.10.0.1"),
+				rc0test.RRSet("www.example.at.", "CNAME", "example.at."),
+				rc0test.RRSet("api.example.at.", "CNAME", "foo.apps.example.at."),
+				rc0test.RRSet("*.apps.example.at.", "A", "10.10.0.2"),
+				rc0test.RRSet("cdn.example.at.", "CNAME", "example.cdn.net."),
+			},
+		},
+		{
+			name:   "empty zone",
+			rrsets: nil,
+			want: []string{
+				"caa-missing example.at.",
+				"dmarc-missing _dmarc.example.at.",
+				"spf-missing example.at.",
+			},
+		},
+		{
+			name: "violations",
+			rrsets: []*rc0go.RRType{
+				rc0test.RRSet("example.at.", "TXT", `"v=spf1 mx -all"`, `"v=spf1 include:_spf.example.net ~all"`),
+				rc0test.RRSet("example.at.", "SPF", `"v=spf1 mx -all"`),
+				rc0test.RRSet("_dmarc.example.at.", "TXT", `"v=DMARC2; p=none"`),
+				rc0test.RRSet("example.at.", "CAA", `0 issue "letsencrypt.org"`),
+				rc0test.RRSet("example.at.", "MX", "10 mail.example.at.", "20 mx.example.net."),
+				rc0test.RRSet("mail.example.at.", "CNAME", "mailhost.example.at."),
+				rc0test.RRSet("mailhost.example.at.", "A", "10.10.0.1"),
+				rc0test.RRSet("shop.example.at.", "CNAME", "gone.example.at."),
+				disabled,
+			},
+			want: []string{
+				"cname-dangling shop.example.at.",
+				"disabled-records old.example.at.",
+				"dmarc-missing _dmarc.example.at.",
+				"mx-cname example.at.",
+				"spf-multiple example.at.",
+				"spf-rr-type example.at.",
+			},
+		},
+	}
+
+	for _, test := range tests {
+
+		l := &Linter{Rules: DefaultRules()}
+
+		got := ruleIDs(l.Check(&rc0go.Zone{Domain: "example.at"}, test.rrsets))
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got findings %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestZoneData_Exists(t *testing.T) {
+
+	d := NewZoneData(&rc0go.Zone{Domain: "example.at"}, []*rc0go.RRType{
+		rc0test.RRSet("www.example.at.", "A", "10.10.0.1"),
+		rc0test.RRSet("*.apps.example.at.", "A", "10.10.0.2"),
+	})
+
+	tests := map[string]bool{
+		"WWW.example.at":         true,
+		"a.apps.example.at.":     true,
+		"a.b.apps.example.at.":   true,
+		"apps.example.at.":       false,
+		"mail.example.at.":       false,
+		"www.other.example.net.": false,
+	}
+
+	for name, want := range tests {
+		if got := d.Exists(name); got != want {
+			t.Errorf("Exists(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
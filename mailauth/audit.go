@@ -0,0 +1,234 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"sort"
+	"strings"
+)
+
+// Severity of an audit issue
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNote    Severity = "note"
+)
+
+// minimum and recommended RSA key sizes (RFC 8301 3.2)
+const (
+	minDKIMKeyBits         = 1024
+	recommendedDKIMKeyBits = 2048
+)
+
+// Issue is a weak or broken email authentication setting
+type Issue struct {
+	Zone     string
+	Name     string
+	Severity Severity
+	Message  string
+}
+
+// String returns f.e. "example.at: warning _dmarc.example.at.: p=none only monitors"
+func (i *Issue) String() string {
+	return fmt.Sprintf("%s: %s %s: %s", i.Zone, i.Severity, i.Name, i.Message)
+}
+
+// Audit returns the issues of the zone's records
+func (z *ZoneRecords) Audit() []*Issue {
+
+	var issues []*Issue
+
+	add := func(name string, severity Severity, format string, args ...interface{}) {
+		issues = append(issues, &Issue{Zone: z.Zone, Name: name, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	apex := fqdn(z.Zone)
+	names := z.names()
+
+	for _, e := range z.Errors {
+		add(e.Name, SeverityError, "invalid record %q: %v", e.Text, e.Err)
+	}
+
+	if len(z.SPF[apex]) == 0 {
+		add(apex, SeverityWarning, "no SPF record")
+	}
+
+	for _, name := range names {
+
+		records := z.SPF[name]
+		if len(records) > 1 {
+			add(name, SeverityError, "%d SPF records, receivers treat this as permanent error", len(records))
+		}
+
+		for _, spf := range records {
+
+			all := spf.All()
+
+			switch {
+			case all != nil && (all.Qualifier == "" || all.Qualifier == "+"):
+				add(name, SeverityError, "%s authorizes every host", all)
+			case all != nil && all.Qualifier == "?":
+				add(name, SeverityWarning, "?all gives no verdict for other hosts")
+			case all == nil && spf.Redirect == "":
+				add(name, SeverityWarning, "no \"all\" mechanism or redirect, other hosts are neutral")
+			}
+
+			for _, m := range spf.Mechanisms {
+				if m.Name == "ptr" {
+					add(name, SeverityWarning, "%s is deprecated (RFC 7208 5.5)", m)
+				}
+			}
+
+			if n := spf.Lookups(); n > MaxSPFLookups {
+				add(name, SeverityError, "%d DNS lookups, more than %d", n, MaxSPFLookups)
+			}
+		}
+	}
+
+	if len(z.DMARC[DMARCName(z.Zone)]) == 0 {
+		add(DMARCName(z.Zone), SeverityWarning, "no DMARC record")
+	}
+
+	for _, name := range names {
+
+		records := z.DMARC[name]
+		if len(records) > 1 {
+			add(name, SeverityError, "%d DMARC records, receivers ignore all of them", len(records))
+		}
+
+		for _, d := range records {
+
+			if d.Policy == "none" {
+				add(name, SeverityWarning, "p=none only monitors")
+			} else if d.SubdomainPolicy == "none" {
+				add(name, SeverityWarning, "sp=none leaves subdomains unprotected")
+			}
+
+			if d.Pct() < 100 {
+				add(name, SeverityNote, "pct=%d applies the policy to part of the messages only", d.Pct())
+			}
+
+			if len(d.RUA) == 0 {
+				add(name, SeverityNote, "no aggregate report address (rua)")
+			}
+		}
+	}
+
+	for _, name := range names {
+
+		for _, d := range z.DKIM[name] {
+
+			if d.PublicKey == "" {
+				add(name, SeverityNote, "key was revoked")
+				continue
+			}
+
+			if d.Testing() {
+				add(name, SeverityNote, "testing mode (t=y)")
+			}
+
+			bits, err := d.KeyBits()
+			switch {
+			case err != nil:
+				add(name, SeverityError, "invalid public key: %v", err)
+			case !strings.EqualFold(d.KeyType, "ed25519") && bits < minDKIMKeyBits:
+				add(name, SeverityError, "%d bit RSA key, at least %d bits are required", bits, minDKIMKeyBits)
+			case !strings.EqualFold(d.KeyType, "ed25519") && bits < recommendedDKIMKeyBits:
+				add(name, SeverityNote, "%d bit RSA key, %d bits are recommended", bits, recommendedDKIMKeyBits)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if len(z.MTASTS[name]) > 1 {
+			add(name, SeverityError, "%d MTA-STS records, senders ignore all of them", len(z.MTASTS[name]))
+		}
+	}
+
+	for _, name := range names {
+		if len(z.TLSRPT[name]) > 1 {
+			add(name, SeverityError, "%d TLS-RPT records, senders ignore all of them", len(z.TLSRPT[name]))
+		}
+	}
+
+	if len(z.MTASTS[MTASTSName(z.Zone)]) > 0 && len(z.TLSRPT[TLSRPTName(z.Zone)]) == 0 {
+		add(TLSRPTName(z.Zone), SeverityNote, "MTA-STS without TLS-RPT, delivery failures are not reported")
+	}
+
+	return issues
+}
+
+// Auditor audits the email authentication records of several zones
+type Auditor struct {
+	Zones rc0go.ZoneManagementServiceInterface
+	RRSet rc0go.RRSetServiceInterface
+}
+
+// NewAuditor returns an auditor using the given client
+func NewAuditor(client *rc0go.Client) *Auditor {
+	return &Auditor{Zones: client.Zones, RRSet: client.RRSet}
+}
+
+// Audit returns the issues of the zones, or of all zones of the account if none are given
+func (a *Auditor) Audit(ctx context.Context, zones ...string) ([]*Issue, error) {
+
+	if len(zones) == 0 {
+		var err error
+		if zones, err = rc0go.ZoneNames(ctx, a.Zones); err != nil {
+			return nil, err
+		}
+	}
+
+	var issues []*Issue
+
+	for _, zone := range zones {
+
+		records, err := Load(ctx, a.RRSet, zone)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %v", zone, err)
+		}
+
+		issues = append(issues, records.Audit()...)
+	}
+
+	return issues, nil
+}
+
+// names returns the owner names of all records, sorted
+func (z *ZoneRecords) names() []string {
+
+	seen := make(map[string]bool)
+
+	for name := range z.SPF {
+		seen[name] = true
+	}
+	for name := range z.DKIM {
+		seen[name] = true
+	}
+	for name := range z.DMARC {
+		seen[name] = true
+	}
+	for name := range z.MTASTS {
+		seen[name] = true
+	}
+	for name := range z.TLSRPT {
+		seen[name] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,123 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"context"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/rc0test"
+	"reflect"
+	"testing"
+)
+
+func issueStrings(issues []*Issue) []string {
+	var s []string
+	for _, i := range issues {
+		s = append(s, i.String())
+	}
+	return s
+}
+
+func TestZoneRecords_Audit(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		rrsets []*rc0go.RRType
+		want   []string
+	}{
+		{
+			name: "strict",
+			rrsets: []*rc0go.RRType{
+				rc0test.TXTRRSet("example.at.", "v=spf1 mx -all"),
+				rc0test.TXTRRSet("_dmarc.example.at.", "v=DMARC1; p=reject; rua=mailto:dmarc@example.at"),
+				rc0test.TXTRRSet("mail._domainkey.example.at.", "v=DKIM1; p="+rsaKey(t, 2048)),
+				rc0test.TXTRRSet("ed._domainkey.example.at.", "v=DKIM1; k=ED25519; p=11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="),
+			},
+		},
+		{
+			name: "missing",
+			want: []string{
+				"example.at: warning example.at.: no SPF record",
+				"example.at: warning _dmarc.example.at.: no DMARC record",
+			},
+		},
+		{
+			name: "weak",
+			rrsets: []*rc0go.RRType{
+				rc0test.TXTRRSet("example.at.", "v=spf1 mx ptr +all"),
+				rc0test.TXTRRSet("www.example.at.", "v=spf1 a", "v=spf1 ?all"),
+				rc0test.TXTRRSet("_dmarc.example.at.", "v=DMARC1; p=none; pct=20"),
+				rc0test.TXTRRSet("old._domainkey.example.at.", "v=DKIM1; p="+rsaKey(t, 512)),
+				rc0test.TXTRRSet("mid._domainkey.example.at.", "v=DKIM1; t=y; p="+rsaKey(t, 1024)),
+				rc0test.TXTRRSet("gone._domainkey.example.at.", "v=DKIM1; p="),
+				rc0test.TXTRRSet("_mta-sts.example.at.", "v=STSv1; id=1"),
+				rc0test.TXTRRSet("_smtp._tls.example.at.", "v=TLSRPTv1; rua=example"),
+			},
+			want: []string{
+				"example.at: error example.at.: +all authorizes every host",
+				"example.at: warning example.at.: ptr is deprecated (RFC 7208 5.5)",
+				"example.at: error www.example.at.: 2 SPF records, receivers treat this as permanent error",
+				"example.at: warning www.example.at.: no \"all\" mechanism or redirect, other hosts are neutral",
+				"example.at: warning www.example.at.: ?all gives no verdict for other hosts",
+				"example.at: warning _dmarc.example.at.: p=none only monitors",
+				"example.at: note _dmarc.example.at.: pct=20 applies the policy to part of the messages only",
+				"example.at: note _dmarc.example.at.: no aggregate report address (rua)",
+				"example.at: note gone._domainkey.example.at.: key was revoked",
+				"example.at: note mid._domainkey.example.at.: testing mode (t=y)",
+				"example.at: note mid._domainkey.example.at.: 1024 bit RSA key, 2048 bits are recommended",
+				"example.at: error old._domainkey.example.at.: 512 bit RSA key, at least 1024 bits are required",
+			},
+		},
+		{
+			name: "too many lookups",
+			rrsets: []*rc0go.RRType{
+				rc0test.TXTRRSet("example.at.", "v=spf1 a mx include:a.example include:b.example include:c.example include:d.example include:e.example include:f.example include:g.example include:h.example include:i.example -all"),
+				rc0test.TXTRRSet("_dmarc.example.at.", "v=DMARC1; p=quarantine; sp=none; rua=mailto:dmarc@example.at"),
+				rc0test.TXTRRSet("_mta-sts.example.at.", "v=STSv1; id=1"),
+			},
+			want: []string{
+				"example.at: error example.at.: 11 DNS lookups, more than 10",
+				"example.at: warning _dmarc.example.at.: sp=none leaves subdomains unprotected",
+				"example.at: note _smtp._tls.example.at.: MTA-STS without TLS-RPT, delivery failures are not reported",
+			},
+		},
+	}
+
+	for _, test := range tests {
+
+		got := issueStrings(Extract("example.at", test.rrsets).Audit())
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Audit returned\n%q\nwant\n%q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAuditor_Audit(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	server.AddZone("example.at",
+		rc0test.TXTRRSet("example.at.", "v=spf1 -all"),
+		rc0test.TXTRRSet("_dmarc.example.at.", "v=DMARC1; p=reject; rua=mailto:dmarc@example.at"),
+	)
+	server.AddZone("example.com",
+		rc0test.TXTRRSet("example.com.", "v=spf1 +all"),
+		rc0test.TXTRRSet("_dmarc.example.com.", "v=DMARC1; p=reject; rua=mailto:dmarc@example.com"),
+	)
+
+	issues, err := NewAuditor(server.Client()).Audit(context.Background())
+	if err != nil {
+		t.Fatalf("Audit returned error: %v", err)
+	}
+
+	want := []string{"example.com: error example.com.: +all authorizes every host"}
+
+	if got := issueStrings(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("Audit returned %q, want %q", got, want)
+	}
+}
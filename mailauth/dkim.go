@@ -0,0 +1,171 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// DKIMVersion is the optional version tag of DKIM key records
+const DKIMVersion = "v=DKIM1"
+
+// DKIM is a parsed DKIM key record (RFC 6376 3.6.1)
+type DKIM struct {
+
+	// KeyType is "rsa" (the default if empty) or "ed25519"
+	KeyType string
+
+	// PublicKey is the base64 encoded key, empty if the key was revoked
+	PublicKey string
+
+	HashAlgorithms []string
+	ServiceTypes   []string
+
+	// Flags, f.e. "y" for testing mode
+	Flags []string
+
+	Notes string
+
+	// Extra holds unknown tags, which are kept for formatting
+	Extra []Tag
+}
+
+// DKIMName returns the owner name of a DKIM key, f.e. "mail._domainkey.example.at."
+func DKIMName(selector string, domain string) string {
+	return fqdn(selector + "._domainkey." + domain)
+}
+
+// ParseDKIM parses the text of a DKIM key record
+func ParseDKIM(text string) (*DKIM, error) {
+
+	tags, err := parseTags(text)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DKIM{}
+	hasKey := false
+
+	for i, t := range tags {
+
+		switch t.Name {
+		case "v":
+			if i != 0 || t.Value != "DKIM1" {
+				return nil, fmt.Errorf("invalid version %q", t.Value)
+			}
+		case "k":
+			d.KeyType = t.Value
+		case "p":
+			d.PublicKey = strings.Join(strings.Fields(t.Value), "")
+			hasKey = true
+		case "h":
+			d.HashAlgorithms = splitList(t.Value, ":")
+		case "s":
+			d.ServiceTypes = splitList(t.Value, ":")
+		case "t":
+			d.Flags = splitList(t.Value, ":")
+		case "n":
+			d.Notes = t.Value
+		default:
+			d.Extra = append(d.Extra, t)
+		}
+	}
+
+	if !hasKey {
+		return nil, fmt.Errorf("record has no \"p\" tag")
+	}
+
+	return d, nil
+}
+
+// Version implements Record
+func (d *DKIM) Version() string {
+	return DKIMVersion
+}
+
+// String returns the text of the record
+func (d *DKIM) String() string {
+
+	tags := []Tag{{Name: "v", Value: "DKIM1"}}
+
+	if d.KeyType != "" {
+		tags = append(tags, Tag{Name: "k", Value: d.KeyType})
+	}
+	if len(d.HashAlgorithms) > 0 {
+		tags = append(tags, Tag{Name: "h", Value: strings.Join(d.HashAlgorithms, ":")})
+	}
+	if len(d.ServiceTypes) > 0 {
+		tags = append(tags, Tag{Name: "s", Value: strings.Join(d.ServiceTypes, ":")})
+	}
+	if len(d.Flags) > 0 {
+		tags = append(tags, Tag{Name: "t", Value: strings.Join(d.Flags, ":")})
+	}
+	if d.Notes != "" {
+		tags = append(tags, Tag{Name: "n", Value: d.Notes})
+	}
+
+	tags = append(tags, d.Extra...)
+	tags = append(tags, Tag{Name: "p", Value: d.PublicKey})
+
+	return formatTags(tags)
+}
+
+// KeyBits returns the size of the public key in bits
+func (d *DKIM) KeyBits() (int, error) {
+
+	if d.PublicKey == "" {
+		return 0, fmt.Errorf("key was revoked")
+	}
+
+	der, err := base64.StdEncoding.DecodeString(d.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	switch strings.ToLower(d.KeyType) {
+
+	case "", "rsa":
+		key, err := x509.ParsePKIXPublicKey(der)
+		if err != nil {
+			// some signers publish a PKCS #1 key
+			if k, err1 := x509.ParsePKCS1PublicKey(der); err1 == nil {
+				return k.N.BitLen(), nil
+			}
+			return 0, err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return 0, fmt.Errorf("key is not an RSA key")
+		}
+		return rsaKey.N.BitLen(), nil
+
+	case "ed25519":
+		return len(der) * 8, nil
+	}
+
+	return 0, fmt.Errorf("unknown key type %q", d.KeyType)
+}
+
+// Testing reports whether the "y" flag is set
+func (d *DKIM) Testing() bool {
+
+	for _, f := range d.Flags {
+		if f == "y" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *DKIM) matches(text string) bool {
+	_, err := ParseDKIM(text)
+	return err == nil
+}
@@ -0,0 +1,155 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"strconv"
+	"strings"
+)
+
+// DMARCVersion starts every DMARC record
+const DMARCVersion = "v=DMARC1"
+
+// DMARC is a parsed DMARC record (RFC 7489 6.3)
+type DMARC struct {
+
+	// Policy and SubdomainPolicy are "none", "quarantine" or "reject"
+	Policy          string
+	SubdomainPolicy string
+
+	// Percent of messages the policy applies to, nil means 100
+	Percent *int
+
+	// RUA and RUF are the report URIs, f.e. "mailto:dmarc@example.at"
+	RUA []string
+	RUF []string
+
+	// ADKIM and ASPF are the alignment modes "r" (relaxed) or "s" (strict)
+	ADKIM string
+	ASPF  string
+
+	FailureOptions string
+	ReportInterval int
+
+	// Extra holds unknown tags, which are kept for formatting
+	Extra []Tag
+}
+
+// DMARCName returns the owner name of the DMARC record, f.e. "_dmarc.example.at."
+func DMARCName(domain string) string {
+	return fqdn("_dmarc." + domain)
+}
+
+// ParseDMARC parses the text of a DMARC record
+func ParseDMARC(text string) (*DMARC, error) {
+
+	tags, err := parseVersioned(text, "DMARC1")
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DMARC{}
+
+	for _, t := range tags {
+
+		switch t.Name {
+		case "p":
+			d.Policy = strings.ToLower(t.Value)
+		case "sp":
+			d.SubdomainPolicy = strings.ToLower(t.Value)
+		case "pct":
+			pct, err := strconv.Atoi(t.Value)
+			if err != nil || pct < 0 || pct > 100 {
+				return nil, fmt.Errorf("invalid pct %q", t.Value)
+			}
+			d.Percent = &pct
+		case "rua":
+			d.RUA = splitList(t.Value, ",")
+		case "ruf":
+			d.RUF = splitList(t.Value, ",")
+		case "adkim":
+			d.ADKIM = strings.ToLower(t.Value)
+		case "aspf":
+			d.ASPF = strings.ToLower(t.Value)
+		case "fo":
+			d.FailureOptions = t.Value
+		case "ri":
+			ri, err := strconv.Atoi(t.Value)
+			if err != nil || ri < 0 {
+				return nil, fmt.Errorf("invalid ri %q", t.Value)
+			}
+			d.ReportInterval = ri
+		default:
+			d.Extra = append(d.Extra, t)
+		}
+	}
+
+	if d.Policy == "" {
+		return nil, fmt.Errorf("record has no \"p\" tag")
+	}
+
+	for _, p := range []string{d.Policy, d.SubdomainPolicy} {
+		if p != "" && p != "none" && p != "quarantine" && p != "reject" {
+			return nil, fmt.Errorf("invalid policy %q", p)
+		}
+	}
+
+	return d, nil
+}
+
+// Version implements Record
+func (d *DMARC) Version() string {
+	return DMARCVersion
+}
+
+// String returns the text of the record
+func (d *DMARC) String() string {
+
+	tags := []Tag{{Name: "v", Value: "DMARC1"}, {Name: "p", Value: d.Policy}}
+
+	if d.SubdomainPolicy != "" {
+		tags = append(tags, Tag{Name: "sp", Value: d.SubdomainPolicy})
+	}
+	if d.Percent != nil {
+		tags = append(tags, Tag{Name: "pct", Value: strconv.Itoa(*d.Percent)})
+	}
+	if len(d.RUA) > 0 {
+		tags = append(tags, Tag{Name: "rua", Value: strings.Join(d.RUA, ",")})
+	}
+	if len(d.RUF) > 0 {
+		tags = append(tags, Tag{Name: "ruf", Value: strings.Join(d.RUF, ",")})
+	}
+	if d.ADKIM != "" {
+		tags = append(tags, Tag{Name: "adkim", Value: d.ADKIM})
+	}
+	if d.ASPF != "" {
+		tags = append(tags, Tag{Name: "aspf", Value: d.ASPF})
+	}
+	if d.FailureOptions != "" {
+		tags = append(tags, Tag{Name: "fo", Value: d.FailureOptions})
+	}
+	if d.ReportInterval > 0 {
+		tags = append(tags, Tag{Name: "ri", Value: strconv.Itoa(d.ReportInterval)})
+	}
+
+	return formatTags(append(tags, d.Extra...))
+}
+
+// Pct returns the percentage of messages the policy applies to
+func (d *DMARC) Pct() int {
+
+	if d.Percent == nil {
+		return 100
+	}
+
+	return *d.Percent
+}
+
+func (d *DMARC) matches(text string) bool {
+	return rc0go.HasTXTVersion(text, DMARCVersion)
+}
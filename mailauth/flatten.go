@@ -0,0 +1,566 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFlattenTTL   = 3600
+	defaultMaxSPFLength = 450
+	maxFlattenDepth     = 10
+	flattenPartPrefix   = "_spf-flat"
+	defaultSourcePrefix = "_spf-source."
+)
+
+// partName matches the parts written by the flattener; other "_spfN" records are left alone
+var partName = regexp.MustCompile(`^_spf-flat[0-9]+\.`)
+
+// SPFResolver looks up the names of SPF mechanisms. *net.Resolver satisfies the interface.
+type SPFResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// Flattener replaces the include, a and mx mechanisms of an SPF record by the ip4 and ip6
+// networks they resolve to, so that receivers need no further lookups. Since flattening
+// loses the includes, the unflattened record is kept at Source and the result is written
+// to Name. A result longer than MaxLength is split into the records "_spf-flat1.<Name>",
+// "_spf-flat2.<Name>", ... which are included by Name.
+type Flattener struct {
+	RRSet    rc0go.RRSetServiceInterface
+	Resolver SPFResolver
+
+	Zone string
+
+	// Name of the flattened record (defaults to the zone apex)
+	Name string
+
+	// Source is the name of the unflattened record (defaults to "_spf-source.<Zone>")
+	Source string
+
+	// TTL of new rrsets (defaults to 3600)
+	TTL int
+
+	// MaxLength of a single SPF record text (defaults to 450)
+	MaxLength int
+
+	// Logf receives progress and error messages (optional)
+	Logf func(format string, args ...interface{})
+}
+
+// FlattenPlan is the result of flattening the source record
+type FlattenPlan struct {
+
+	// Records holds the texts of the flattened record and its parts by owner name
+	Records map[string]string
+
+	// Diff holds the changes to the zone, empty if it is up to date
+	Diff *rc0go.RRSetDiff
+}
+
+// Plan reads the source record and the current flattened records and returns the changes
+// without submitting them
+func (f *Flattener) Plan(ctx context.Context) (*FlattenPlan, error) {
+
+	rrsets, err := f.RRSet.ListAll(ctx, f.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	name, source := f.names()
+
+	var sourceSPF *SPF
+	var currentSets []*rc0go.RRType
+	current := make(map[string]*rc0go.RRType)
+
+	for _, rrset := range rrsets {
+
+		if !strings.EqualFold(rrset.Type, "TXT") {
+			continue
+		}
+
+		n := rc0go.NormalizeName(rrset.Name)
+
+		switch {
+		case n == source:
+			records := Extract(f.Zone, []*rc0go.RRType{rrset})
+			if len(records.Errors) > 0 {
+				return nil, records.Errors[0]
+			}
+			if spfs := records.SPF[n]; len(spfs) == 1 {
+				sourceSPF = spfs[0]
+			}
+		case n == name || (partName.MatchString(n) && n[strings.Index(n, ".")+1:] == name):
+			current[n] = rrset
+			currentSets = append(currentSets, rrset)
+		}
+	}
+
+	if sourceSPF == nil {
+		return nil, fmt.Errorf("no single SPF record at %s", source)
+	}
+
+	flat, err := f.flatten(ctx, sourceSPF, strings.TrimSuffix(name, "."), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := f.split(name, flat)
+
+	// kept mechanisms and the includes of the parts still need lookups
+	main, err := ParseSPF(texts[name])
+	if err != nil {
+		return nil, err
+	}
+
+	if n := main.Lookups(); n > MaxSPFLookups {
+		return nil, fmt.Errorf("flattened record at %s needs %d DNS lookups, more than %d", name, n, MaxSPFLookups)
+	}
+
+	ttl := f.TTL
+	if ttl == 0 {
+		ttl = defaultFlattenTTL
+	}
+
+	// parts of an earlier, longer result are not desired anymore and get deleted
+	var desiredSets []*rc0go.RRType
+
+	for _, n := range sortedKeys(texts) {
+
+		spf, err := ParseSPF(texts[n])
+		if err != nil {
+			return nil, err
+		}
+
+		change := Change(current[n], n, 0, spf)
+		if change.TTL == 0 {
+			change.TTL = ttl
+		}
+
+		desiredSets = append(desiredSets, &rc0go.RRType{Name: change.Name, Type: change.Type, TTL: change.TTL, Records: change.Records})
+	}
+
+	return &FlattenPlan{Records: texts, Diff: rc0go.DiffRRSets(currentSets, desiredSets)}, nil
+}
+
+// Update flattens the source record and submits the changes if the result differs from the
+// records in the zone. It reports whether anything was submitted.
+func (f *Flattener) Update(ctx context.Context) (bool, error) {
+
+	plan, err := f.Plan(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if plan.Diff.IsEmpty() {
+		f.logf("%s is up to date", f.Zone)
+		return false, nil
+	}
+
+	status, err := f.RRSet.SubmitChangeSet(f.Zone, plan.Diff.Changes)
+	if err != nil {
+		return false, err
+	}
+
+	if status.HasError() {
+		return false, fmt.Errorf("zone %s: %s", f.Zone, status.Message)
+	}
+
+	f.logf("updated flattened SPF of %s:\n%s", f.Zone, plan.Diff)
+
+	return true, nil
+}
+
+// Run calls Update every interval until the context is done
+func (f *Flattener) Run(ctx context.Context, interval time.Duration) error {
+
+	if interval <= 0 {
+		return fmt.Errorf("invalid interval %s: must be positive", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := f.Update(ctx); err != nil {
+			f.logf("flattening failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// flatten returns the record with all resolvable pass mechanisms replaced by ip4 and ip6.
+// Mechanisms which cannot be flattened (f.e. exists, ptr or macros) are kept in place.
+func (f *Flattener) flatten(ctx context.Context, spf *SPF, domain string, depth int) (*SPF, error) {
+
+	if depth > maxFlattenDepth {
+		return nil, fmt.Errorf("more than %d nested redirects at %s", maxFlattenDepth, domain)
+	}
+
+	flat := &SPF{Exp: spf.Exp, Modifiers: spf.Modifiers}
+
+	networks := &networkSet{}
+
+	for _, m := range spf.Mechanisms {
+
+		if m.Name == "all" {
+			flat.Mechanisms = append(flat.Mechanisms, networks.mechanisms()...)
+			flat.Mechanisms = append(flat.Mechanisms, m)
+			return flat, nil
+		}
+
+		if (m.Qualifier == "" || m.Qualifier == "+") && !strings.Contains(m.Value, "%") {
+			ok, err := f.resolve(ctx, m, domain, networks, 0)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				continue
+			}
+		}
+
+		// the networks before the kept mechanism have to stay before it
+		flat.Mechanisms = append(flat.Mechanisms, networks.mechanisms()...)
+		flat.Mechanisms = append(flat.Mechanisms, m)
+		networks = &networkSet{}
+	}
+
+	flat.Mechanisms = append(flat.Mechanisms, networks.mechanisms()...)
+
+	if spf.Redirect != "" {
+
+		target, err := f.record(ctx, spf.Redirect)
+		if err != nil {
+			return nil, err
+		}
+
+		redirected, err := f.flatten(ctx, target, spf.Redirect, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		flat.Mechanisms = append(flat.Mechanisms, redirected.Mechanisms...)
+	}
+
+	return flat, nil
+}
+
+// resolve adds the networks of a pass mechanism to the set. It returns false if the
+// mechanism has to be kept as it is.
+func (f *Flattener) resolve(ctx context.Context, m *Mechanism, domain string, networks *networkSet, depth int) (bool, error) {
+
+	if depth > maxFlattenDepth {
+		return false, fmt.Errorf("more than %d nested includes at %s", maxFlattenDepth, m)
+	}
+
+	target := domain
+	if m.Value != "" {
+		target = m.Value
+	}
+
+	switch m.Name {
+
+	case "ip4", "ip6":
+		networks.add(m.Value, "")
+		return true, nil
+
+	case "a":
+		addrs, err := f.Resolver.LookupIPAddr(ctx, target)
+		if err != nil && !isNotFound(err) {
+			return false, fmt.Errorf("%s: %v", m, err)
+		}
+		for _, addr := range addrs {
+			networks.add(addr.IP.String(), m.CIDR)
+		}
+		return true, nil
+
+	case "mx":
+		mxs, err := f.Resolver.LookupMX(ctx, target)
+		if err != nil && !isNotFound(err) {
+			return false, fmt.Errorf("%s: %v", m, err)
+		}
+		for _, mx := range mxs {
+			addrs, err := f.Resolver.LookupIPAddr(ctx, mx.Host)
+			if err != nil && !isNotFound(err) {
+				return false, fmt.Errorf("%s: %s: %v", m, mx.Host, err)
+			}
+			for _, addr := range addrs {
+				networks.add(addr.IP.String(), m.CIDR)
+			}
+		}
+		return true, nil
+
+	case "include":
+		// an include matches if the included record passes, which only pass mechanisms can do.
+		// Records with other mechanisms before their end are kept as include. Without all,
+		// the redirect (RFC 7208, section 6.1) adds its networks to the record's own ones.
+		sub := &networkSet{}
+
+		for redirects := 0; ; redirects++ {
+
+			if redirects > maxFlattenDepth {
+				return false, fmt.Errorf("more than %d nested redirects at %s", maxFlattenDepth, target)
+			}
+
+			included, err := f.record(ctx, target)
+			if err != nil {
+				return false, err
+			}
+
+			for _, im := range included.Mechanisms {
+
+				if im.Name == "all" {
+					if im.Qualifier == "" || im.Qualifier == "+" {
+						return false, nil
+					}
+					break
+				}
+
+				if (im.Qualifier != "" && im.Qualifier != "+") || strings.Contains(im.Value, "%") {
+					return false, nil
+				}
+
+				ok, err := f.resolve(ctx, im, target, sub, depth+1)
+				if err != nil || !ok {
+					return false, err
+				}
+			}
+
+			if included.Redirect == "" || included.All() != nil {
+				break
+			}
+
+			target = included.Redirect
+		}
+
+		networks.merge(sub)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// record looks up the single SPF record of the name
+func (f *Flattener) record(ctx context.Context, name string) (*SPF, error) {
+
+	texts, err := f.Resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	var records []*SPF
+
+	for _, text := range texts {
+		if rc0go.HasTXTVersion(text, SPFVersion) {
+			spf, err := ParseSPF(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			records = append(records, spf)
+		}
+	}
+
+	if len(records) != 1 {
+		return nil, fmt.Errorf("%s: %d SPF records", name, len(records))
+	}
+
+	return records[0], nil
+}
+
+// split returns the record texts by owner name. If the flattened record is too long, its
+// networks are moved to parts which the record at name includes.
+func (f *Flattener) split(name string, flat *SPF) map[string]string {
+
+	maxLength := f.MaxLength
+	if maxLength <= 0 {
+		maxLength = defaultMaxSPFLength
+	}
+
+	if text := flat.String(); len(text) <= maxLength {
+		return map[string]string{name: text}
+	}
+
+	main := &SPF{Exp: flat.Exp, Modifiers: flat.Modifiers}
+
+	texts := make(map[string]string)
+
+	part := &SPF{}
+	flush := func() {
+		if len(part.Mechanisms) == 0 {
+			return
+		}
+		partName := fmt.Sprintf("%s%d.%s", flattenPartPrefix, len(texts)+1, name)
+		texts[partName] = part.String()
+		main.Mechanisms = append(main.Mechanisms, &Mechanism{Name: "include", Value: strings.TrimSuffix(partName, ".")})
+		part = &SPF{}
+	}
+
+	// Only pass networks are moved to parts: a fail match within an included record just
+	// lets the include not match. Other mechanisms stay in place and end the current part,
+	// so that the order of evaluation is kept.
+	for _, m := range flat.Mechanisms {
+
+		if (m.Name != "ip4" && m.Name != "ip6") || (m.Qualifier != "" && m.Qualifier != "+") {
+			flush()
+			main.Mechanisms = append(main.Mechanisms, m)
+			continue
+		}
+
+		if len(part.Mechanisms) > 0 && len(part.String())+1+len(m.String()) > maxLength {
+			flush()
+		}
+
+		part.Mechanisms = append(part.Mechanisms, m)
+	}
+
+	flush()
+
+	texts[name] = main.String()
+
+	return texts
+}
+
+func (f *Flattener) names() (name string, source string) {
+
+	name = rc0go.NormalizeName(f.Zone)
+	if f.Name != "" {
+		name = rc0go.NormalizeName(f.Name)
+	}
+
+	source = rc0go.NormalizeName(defaultSourcePrefix + f.Zone)
+	if f.Source != "" {
+		source = rc0go.NormalizeName(f.Source)
+	}
+
+	return name, source
+}
+
+func (f *Flattener) logf(format string, args ...interface{}) {
+	if f.Logf != nil {
+		f.Logf(format, args...)
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func isNotFound(err error) bool {
+	dnsErr, ok := err.(*net.DNSError)
+	return ok && dnsErr.IsNotFound
+}
+
+// networkSet collects ip4 and ip6 networks without duplicates
+type networkSet struct {
+	networks map[string]*net.IPNet
+}
+
+// add adds an address or network. cidr holds the prefix lengths of a and mx mechanisms,
+// f.e. "/24" or "/24//64".
+func (s *networkSet) add(value string, cidr string) {
+
+	if s.networks == nil {
+		s.networks = make(map[string]*net.IPNet)
+	}
+
+	var network *net.IPNet
+
+	if _, n, err := net.ParseCIDR(value); err == nil {
+		network = n
+	} else if ip := net.ParseIP(value); ip != nil {
+
+		bits := 128
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+
+		ones := bits
+		v4, v6 := cidr, ""
+		if i := strings.Index(cidr, "//"); i >= 0 {
+			v4, v6 = cidr[:i], cidr[i+1:]
+		}
+		prefix := v4
+		if bits == 128 {
+			prefix = v6
+		}
+		if prefix != "" {
+			_, _ = fmt.Sscanf(prefix, "/%d", &ones)
+		}
+
+		network = &net.IPNet{IP: ip.Mask(net.CIDRMask(ones, bits)), Mask: net.CIDRMask(ones, bits)}
+	} else {
+		return
+	}
+
+	s.networks[network.String()] = network
+}
+
+func (s *networkSet) merge(o *networkSet) {
+	for _, n := range o.networks {
+		s.add(n.String(), "")
+	}
+}
+
+// mechanisms returns ip4 networks before ip6 networks, each sorted by address
+func (s *networkSet) mechanisms() []*Mechanism {
+
+	networks := make([]*net.IPNet, 0, len(s.networks))
+	for _, n := range s.networks {
+		networks = append(networks, n)
+	}
+
+	sort.Slice(networks, func(i, j int) bool {
+		a, b := networks[i], networks[j]
+		if len(a.IP) != len(b.IP) {
+			return len(a.IP) < len(b.IP)
+		}
+		if c := strings.Compare(string(a.IP), string(b.IP)); c != 0 {
+			return c < 0
+		}
+		return a.String() < b.String()
+	})
+
+	mechanisms := make([]*Mechanism, len(networks))
+
+	for i, n := range networks {
+
+		name, value := "ip6", n.String()
+		if len(n.IP) == net.IPv4len {
+			name = "ip4"
+		}
+
+		if ones, bits := n.Mask.Size(); ones == bits {
+			value = n.IP.String()
+		}
+
+		mechanisms[i] = &Mechanism{Name: name, Value: value}
+	}
+
+	return mechanisms
+}
@@ -0,0 +1,335 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/rc0test"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSPFResolver answers from maps, unknown names are not found
+type fakeSPFResolver struct {
+	txt   map[string][]string
+	addrs map[string][]string
+	mx    map[string][]string
+}
+
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeSPFResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if txt, ok := r.txt[strings.TrimSuffix(name, ".")]; ok {
+		return txt, nil
+	}
+	return nil, notFound(name)
+}
+
+func (r *fakeSPFResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	addrs, ok := r.addrs[strings.TrimSuffix(host, ".")]
+	if !ok {
+		return nil, notFound(host)
+	}
+	var result []net.IPAddr
+	for _, a := range addrs {
+		result = append(result, net.IPAddr{IP: net.ParseIP(a)})
+	}
+	return result, nil
+}
+
+func (r *fakeSPFResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	hosts, ok := r.mx[strings.TrimSuffix(name, ".")]
+	if !ok {
+		return nil, notFound(name)
+	}
+	var result []*net.MX
+	for i, h := range hosts {
+		result = append(result, &net.MX{Host: h + ".", Pref: uint16(10 * (i + 1))})
+	}
+	return result, nil
+}
+
+func newFlattenTest(t *testing.T, source string, rrsets ...*rc0go.RRType) (*rc0test.Server, *Flattener, *fakeSPFResolver) {
+
+	server := rc0test.NewServer()
+
+	server.AddZone("example.at", append([]*rc0go.RRType{
+		rc0test.TXTRRSet("_spf-source.example.at.", source),
+	}, rrsets...)...)
+
+	resolver := &fakeSPFResolver{
+		txt: map[string][]string{
+			"_spf.mail.example.net":  {"v=spf1 ip4:198.51.100.0/24 include:_spf2.mail.example.net ~all"},
+			"_spf2.mail.example.net": {"v=spf1 ip6:2001:db8:100::/48 ip4:198.51.100.0/24"},
+			"redirect.example.net":   {"v=spf1 redirect=_spf.mail.example.net"},
+			"mixed.example.net":      {"v=spf1 -ip4:203.0.113.1 ip4:203.0.113.0/24 -all"},
+			"other.example.net":      {"some verification", "v=spf1 a:relay.example.net -all"},
+		},
+		addrs: map[string][]string{
+			"example.at":        {"192.0.2.1", "2001:db8::1"},
+			"mx1.example.at":    {"192.0.2.10"},
+			"mx2.example.at":    {"192.0.2.11", "192.0.2.10"},
+			"relay.example.net": {"203.0.113.50"},
+		},
+		mx: map[string][]string{
+			"example.at": {"mx1.example.at", "mx2.example.at"},
+		},
+	}
+
+	f := &Flattener{RRSet: server.Client().RRSet, Resolver: resolver, Zone: "example.at"}
+
+	return server, f, resolver
+}
+
+func apexSPF(server *rc0test.Server, name string) []string {
+	for _, rrset := range server.RRSets("example.at") {
+		if rrset.Name == name && rrset.Type == "TXT" {
+			texts, _ := rrset.TXT()
+			return texts
+		}
+	}
+	return nil
+}
+
+func TestFlattener_Update(t *testing.T) {
+
+	server, f, resolver := newFlattenTest(t,
+		"v=spf1 a mx include:_spf.mail.example.net include:other.example.net ip4:192.0.2.10 -all",
+		rc0test.TXTRRSet("example.at.", "google-site-verification=abc", "v=spf1 include:_spf.mail.example.net -all"),
+	)
+	defer server.Close()
+
+	changed, err := f.Update(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("Update returned %v, %v", changed, err)
+	}
+
+	want := []string{
+		"google-site-verification=abc",
+		"v=spf1 ip4:192.0.2.1 ip4:192.0.2.10 ip4:192.0.2.11 ip4:198.51.100.0/24 ip4:203.0.113.50 ip6:2001:db8::1 ip6:2001:db8:100::/48 -all",
+	}
+
+	if got := apexSPF(server, "example.at."); !reflect.DeepEqual(got, want) {
+		t.Errorf("example.at. has TXT %q, want %q", got, want)
+	}
+
+	if changed, err := f.Update(context.Background()); err != nil || changed {
+		t.Errorf("second Update returned %v, %v", changed, err)
+	}
+
+	if n := len(server.Patches("example.at")); n != 1 {
+		t.Errorf("got %d PATCH requests, want 1", n)
+	}
+
+	resolver.addrs["mx2.example.at"] = []string{"192.0.2.12"}
+
+	if changed, err := f.Update(context.Background()); err != nil || !changed {
+		t.Errorf("Update after address change returned %v, %v", changed, err)
+	}
+
+	if got := apexSPF(server, "example.at."); !strings.Contains(got[1], "ip4:192.0.2.12") || strings.Contains(got[1], "192.0.2.11") {
+		t.Errorf("example.at. has TXT %q after address change", got)
+	}
+}
+
+func TestFlattener_KeepsUnflattenable(t *testing.T) {
+
+	server, f, _ := newFlattenTest(t, "v=spf1 include:mixed.example.net exists:%{i}.rbl.example.net a:missing.example.at redirect=redirect.example.net")
+	defer server.Close()
+
+	f.Name = "mail.example.at"
+	f.TTL = 600
+
+	plan, err := f.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+
+	want := map[string]string{
+		"mail.example.at.": "v=spf1 include:mixed.example.net exists:%{i}.rbl.example.net ip4:198.51.100.0/24 ip6:2001:db8:100::/48 ~all",
+	}
+
+	if !reflect.DeepEqual(plan.Records, want) {
+		t.Errorf("Plan returned %q, want %q", plan.Records, want)
+	}
+
+	if len(plan.Diff.Changes) != 1 || plan.Diff.Changes[0].ChangeType != rc0go.ChangeTypeADD || plan.Diff.Changes[0].TTL != 600 {
+		t.Errorf("Plan returned changes %+v", plan.Diff.Changes)
+	}
+}
+
+func TestFlattener_Split(t *testing.T) {
+
+	// a hand-maintained record which only looks like a part
+	manual := rc0test.TXTRRSet("_spf1.example.at.", "v=spf1 ip4:203.0.113.0/24 -all")
+
+	server, f, resolver := newFlattenTest(t, "v=spf1 mx include:_spf.mail.example.net -all", manual)
+	defer server.Close()
+
+	f.MaxLength = 60
+
+	if _, err := f.Update(context.Background()); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	want := map[string][]string{
+		"example.at.":            {"v=spf1 include:_spf-flat1.example.at include:_spf-flat2.example.at -all"},
+		"_spf-flat1.example.at.": {"v=spf1 ip4:192.0.2.10 ip4:192.0.2.11 ip4:198.51.100.0/24"},
+		"_spf-flat2.example.at.": {"v=spf1 ip6:2001:db8:100::/48"},
+	}
+
+	for name, texts := range want {
+		if got := apexSPF(server, name); !reflect.DeepEqual(got, texts) {
+			t.Errorf("%s has TXT %q, want %q", name, got, texts)
+		}
+	}
+
+	// a shorter result removes the parts which are not needed anymore
+	resolver.txt["_spf.mail.example.net"] = []string{"v=spf1 ip4:198.51.100.1"}
+	delete(resolver.mx, "example.at")
+
+	if _, err := f.Update(context.Background()); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	if got := apexSPF(server, "example.at."); !reflect.DeepEqual(got, []string{"v=spf1 ip4:198.51.100.1 -all"}) {
+		t.Errorf("example.at. has TXT %q", got)
+	}
+
+	for _, name := range []string{"_spf-flat1.example.at.", "_spf-flat2.example.at."} {
+		if got := apexSPF(server, name); got != nil {
+			t.Errorf("%s was not deleted: %q", name, got)
+		}
+	}
+
+	if got := apexSPF(server, "_spf1.example.at."); !reflect.DeepEqual(got, []string{"v=spf1 ip4:203.0.113.0/24 -all"}) {
+		t.Errorf("_spf1.example.at. has TXT %q, want it unchanged", got)
+	}
+}
+
+func TestFlattener_TooManyLookups(t *testing.T) {
+
+	source := "v=spf1"
+	for i := 0; i <= MaxSPFLookups; i++ {
+		source += fmt.Sprintf(" exists:%%{i}.rbl%d.example.net", i)
+	}
+
+	server, f, _ := newFlattenTest(t, source+" -all")
+	defer server.Close()
+
+	if _, err := f.Plan(context.Background()); err == nil {
+		t.Errorf("Plan returned no error for %d lookups", MaxSPFLookups+1)
+	}
+}
+
+func TestFlattener_Errors(t *testing.T) {
+
+	server, f, resolver := newFlattenTest(t, "v=spf1 include:loop.example.net -all")
+	defer server.Close()
+
+	resolver.txt["loop.example.net"] = []string{"v=spf1 include:loop.example.net"}
+
+	if _, err := f.Update(context.Background()); err == nil || !strings.Contains(err.Error(), "nested") {
+		t.Errorf("Update of an include loop returned %v", err)
+	}
+
+	f.Source = "missing.example.at"
+
+	if _, err := f.Update(context.Background()); err == nil {
+		t.Error("Update without source record did not return an error")
+	}
+
+	if n := len(server.Patches("example.at")); n != 0 {
+		t.Errorf("got %d PATCH requests, want 0", n)
+	}
+}
+
+func TestFlattener_Run(t *testing.T) {
+
+	server, f, _ := newFlattenTest(t, "v=spf1 ip4:192.0.2.1 -all")
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var logged []string
+	f.Logf = func(format string, args ...interface{}) {
+		logged = append(logged, format)
+		cancel()
+	}
+
+	if err := f.Run(ctx, time.Hour); err != context.Canceled {
+		t.Errorf("Run returned %v", err)
+	}
+
+	if len(logged) != 1 || len(server.Patches("example.at")) != 1 {
+		t.Errorf("Run logged %q and sent %d PATCH requests", logged, len(server.Patches("example.at")))
+	}
+
+	if err := f.Run(context.Background(), 0); err == nil {
+		t.Errorf("Run with interval 0 returned no error")
+	}
+}
+
+func TestFlattener_SplitKeepsFailNetworks(t *testing.T) {
+
+	server, f, _ := newFlattenTest(t, "v=spf1 -ip4:192.0.2.10 mx include:_spf.mail.example.net exists:%{i}.rbl.example.net ip4:203.0.113.1 -all")
+	defer server.Close()
+
+	f.MaxLength = 60
+
+	plan, err := f.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+
+	want := map[string]string{
+		"example.at.":            "v=spf1 -ip4:192.0.2.10 include:_spf-flat1.example.at include:_spf-flat2.example.at exists:%{i}.rbl.example.net include:_spf-flat3.example.at -all",
+		"_spf-flat1.example.at.": "v=spf1 ip4:192.0.2.10 ip4:192.0.2.11 ip4:198.51.100.0/24",
+		"_spf-flat2.example.at.": "v=spf1 ip6:2001:db8:100::/48",
+		"_spf-flat3.example.at.": "v=spf1 ip4:203.0.113.1",
+	}
+
+	if !reflect.DeepEqual(plan.Records, want) {
+		t.Errorf("Plan returned\n%q\nwant\n%q", plan.Records, want)
+	}
+}
+
+func TestFlattener_IncludeWithRedirect(t *testing.T) {
+
+	server, f, resolver := newFlattenTest(t, "v=spf1 include:inc.example.net -all")
+	defer server.Close()
+
+	resolver.txt["inc.example.net"] = []string{"v=spf1 ip4:203.0.113.5 redirect=other.example.net"}
+	resolver.txt["other.example.net"] = []string{"v=spf1 ip4:198.51.100.1 redirect=last.example.net"}
+	resolver.txt["last.example.net"] = []string{"v=spf1 ip6:2001:db8::5 -all"}
+
+	plan, err := f.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("Plan returned error: %v", err)
+	}
+
+	want := map[string]string{
+		"example.at.": "v=spf1 ip4:198.51.100.1 ip4:203.0.113.5 ip6:2001:db8::5 -all",
+	}
+
+	if !reflect.DeepEqual(plan.Records, want) {
+		t.Errorf("Plan returned %q, want %q", plan.Records, want)
+	}
+
+	// a redirect loop ends at the depth limit
+	resolver.txt["last.example.net"] = []string{"v=spf1 redirect=inc.example.net"}
+
+	if _, err := f.Plan(context.Background()); err == nil {
+		t.Errorf("Plan returned no error for a redirect loop")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"strings"
+)
+
+const (
+	// MTASTSVersion starts every MTA-STS record
+	MTASTSVersion = "v=STSv1"
+
+	// TLSRPTVersion starts every TLS-RPT record
+	TLSRPTVersion = "v=TLSRPTv1"
+)
+
+// MTASTS is a parsed MTA-STS record (RFC 8461 3.1)
+type MTASTS struct {
+
+	// ID changes whenever the policy is updated, f.e. "20191011T120000"
+	ID string
+
+	// Extra holds unknown tags, which are kept for formatting
+	Extra []Tag
+}
+
+// MTASTSName returns the owner name of the MTA-STS record, f.e. "_mta-sts.example.at."
+func MTASTSName(domain string) string {
+	return fqdn("_mta-sts." + domain)
+}
+
+// ParseMTASTS parses the text of an MTA-STS record
+func ParseMTASTS(text string) (*MTASTS, error) {
+
+	tags, err := parseVersioned(text, "STSv1")
+	if err != nil {
+		return nil, err
+	}
+
+	m := &MTASTS{}
+
+	for _, t := range tags {
+		if t.Name == "id" {
+			m.ID = t.Value
+		} else {
+			m.Extra = append(m.Extra, t)
+		}
+	}
+
+	if m.ID == "" {
+		return nil, fmt.Errorf("record has no \"id\" tag")
+	}
+
+	return m, nil
+}
+
+// Version implements Record
+func (m *MTASTS) Version() string {
+	return MTASTSVersion
+}
+
+// String returns the text of the record
+func (m *MTASTS) String() string {
+	return formatTags(append([]Tag{{Name: "v", Value: "STSv1"}, {Name: "id", Value: m.ID}}, m.Extra...))
+}
+
+func (m *MTASTS) matches(text string) bool {
+	return rc0go.HasTXTVersion(text, MTASTSVersion)
+}
+
+// TLSRPT is a parsed SMTP TLS reporting record (RFC 8460 3)
+type TLSRPT struct {
+
+	// RUA are the report URIs, f.e. "mailto:tlsrpt@example.at"
+	RUA []string
+
+	// Extra holds unknown tags, which are kept for formatting
+	Extra []Tag
+}
+
+// TLSRPTName returns the owner name of the TLS-RPT record, f.e. "_smtp._tls.example.at."
+func TLSRPTName(domain string) string {
+	return fqdn("_smtp._tls." + domain)
+}
+
+// ParseTLSRPT parses the text of a TLS-RPT record
+func ParseTLSRPT(text string) (*TLSRPT, error) {
+
+	tags, err := parseVersioned(text, "TLSRPTv1")
+	if err != nil {
+		return nil, err
+	}
+
+	r := &TLSRPT{}
+
+	for _, t := range tags {
+		if t.Name == "rua" {
+			r.RUA = splitList(t.Value, ",")
+		} else {
+			r.Extra = append(r.Extra, t)
+		}
+	}
+
+	if len(r.RUA) == 0 {
+		return nil, fmt.Errorf("record has no \"rua\" tag")
+	}
+
+	return r, nil
+}
+
+// Version implements Record
+func (r *TLSRPT) Version() string {
+	return TLSRPTVersion
+}
+
+// String returns the text of the record
+func (r *TLSRPT) String() string {
+	return formatTags(append([]Tag{{Name: "v", Value: "TLSRPTv1"}, {Name: "rua", Value: strings.Join(r.RUA, ",")}}, r.Extra...))
+}
+
+func (r *TLSRPT) matches(text string) bool {
+	return rc0go.HasTXTVersion(text, TLSRPTVersion)
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package mailauth builds, parses and audits the TXT records used for email authentication:
+// SPF, DKIM, DMARC, MTA-STS and TLS-RPT
+package mailauth
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"strings"
+)
+
+// Record is one of *SPF, *DKIM, *DMARC, *MTASTS and *TLSRPT
+type Record interface {
+
+	// Version returns the version tag of the record type, f.e. "v=spf1"
+	Version() string
+
+	// String returns the text of the record
+	String() string
+
+	// matches reports whether the TXT text is a record of the same type
+	matches(text string) bool
+}
+
+// Change returns the change which puts the record into the TXT rrset of name, replacing records
+// of the same type and keeping all others (f.e. site verifications next to SPF). existing is
+// the current TXT rrset or nil. A ttl of 0 keeps the TTL of the existing rrset. Long records
+// are split into several character-strings.
+func Change(existing *rc0go.RRType, name string, ttl int, record Record) *rc0go.RRSetChange {
+
+	change := &rc0go.RRSetChange{
+		Name:       rc0go.NormalizeName(name),
+		Type:       "TXT",
+		ChangeType: rc0go.ChangeTypeADD,
+		TTL:        ttl,
+	}
+
+	if existing != nil && len(existing.Records) > 0 {
+
+		change.ChangeType = rc0go.ChangeTypeUPDATE
+
+		if ttl == 0 {
+			change.TTL = existing.TTL
+		}
+
+		for _, r := range existing.Records {
+			if text, err := rc0go.UnquoteTXT(r.Content); err == nil && record.matches(text) {
+				continue
+			}
+			change.Records = append(change.Records, &rc0go.Record{Content: r.Content, Disabled: r.Disabled})
+		}
+	}
+
+	change.AddTXT(record.String())
+
+	return change
+}
+
+// ParseError is a TXT record which looks like an email authentication record but is invalid
+type ParseError struct {
+	Name string
+	Text string
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Name, e.Err)
+}
+
+// ZoneRecords holds the email authentication records of a zone by owner name
+type ZoneRecords struct {
+	Zone string
+
+	SPF    map[string][]*SPF
+	DKIM   map[string][]*DKIM
+	DMARC  map[string][]*DMARC
+	MTASTS map[string][]*MTASTS
+	TLSRPT map[string][]*TLSRPT
+
+	Errors []*ParseError
+}
+
+// Extract parses the enabled TXT records of the zone's rrsets
+func Extract(zone string, rrsets []*rc0go.RRType) *ZoneRecords {
+
+	z := &ZoneRecords{
+		Zone:   zone,
+		SPF:    make(map[string][]*SPF),
+		DKIM:   make(map[string][]*DKIM),
+		DMARC:  make(map[string][]*DMARC),
+		MTASTS: make(map[string][]*MTASTS),
+		TLSRPT: make(map[string][]*TLSRPT),
+	}
+
+	for _, rrset := range rrsets {
+
+		if !strings.EqualFold(rrset.Type, "TXT") {
+			continue
+		}
+
+		name := rc0go.NormalizeName(rrset.Name)
+
+		for _, r := range rrset.Records {
+
+			if r.Disabled {
+				continue
+			}
+
+			text, err := rc0go.UnquoteTXT(r.Content)
+			if err != nil {
+				continue
+			}
+
+			if err := z.add(name, text); err != nil {
+				z.Errors = append(z.Errors, &ParseError{Name: name, Text: text, Err: err})
+			}
+		}
+	}
+
+	return z
+}
+
+func (z *ZoneRecords) add(name string, text string) error {
+
+	switch {
+
+	case rc0go.HasTXTVersion(text, SPFVersion):
+		spf, err := ParseSPF(text)
+		if err == nil {
+			z.SPF[name] = append(z.SPF[name], spf)
+		}
+		return err
+
+	case rc0go.HasTXTVersion(text, DMARCVersion):
+		dmarc, err := ParseDMARC(text)
+		if err == nil {
+			z.DMARC[name] = append(z.DMARC[name], dmarc)
+		}
+		return err
+
+	case rc0go.HasTXTVersion(text, MTASTSVersion):
+		sts, err := ParseMTASTS(text)
+		if err == nil {
+			z.MTASTS[name] = append(z.MTASTS[name], sts)
+		}
+		return err
+
+	case rc0go.HasTXTVersion(text, TLSRPTVersion):
+		rpt, err := ParseTLSRPT(text)
+		if err == nil {
+			z.TLSRPT[name] = append(z.TLSRPT[name], rpt)
+		}
+		return err
+
+	case rc0go.HasTXTVersion(text, DKIMVersion) || strings.Contains(name, "._domainkey."):
+		dkim, err := ParseDKIM(text)
+		if err == nil {
+			z.DKIM[name] = append(z.DKIM[name], dkim)
+		} else if !rc0go.HasTXTVersion(text, DKIMVersion) {
+			// other TXT records below _domainkey, f.e. an ADSP policy
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Load lists the rrsets of the zone and extracts its email authentication records
+func Load(ctx context.Context, rrsetService rc0go.RRSetServiceInterface, zone string) (*ZoneRecords, error) {
+
+	rrsets, err := rrsetService.ListAll(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return Extract(zone, rrsets), nil
+}
+
+func fqdn(name string) string {
+	return rc0go.NormalizeName(name)
+}
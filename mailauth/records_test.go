@@ -0,0 +1,72 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"context"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/rc0test"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestChange(t *testing.T) {
+
+	existing := rc0test.TXTRRSet("example.at.", "google-site-verification=abc", "v=spf1 -all")
+
+	change := Change(existing, "example.at", 0, &SPF{Mechanisms: []*Mechanism{{Name: "mx"}, {Qualifier: "-", Name: "all"}}})
+
+	texts, _ := change.TXT()
+
+	if change.ChangeType != rc0go.ChangeTypeUPDATE || change.TTL != 3600 || !reflect.DeepEqual(texts, []string{"google-site-verification=abc", "v=spf1 mx -all"}) {
+		t.Errorf("Change returned %+v with %v", change, texts)
+	}
+
+	key := rsaKey(t, 2048)
+
+	change = Change(nil, DKIMName("mail", "example.at"), 300, &DKIM{KeyType: "rsa", PublicKey: key})
+
+	if change.ChangeType != rc0go.ChangeTypeADD || change.Name != "mail._domainkey.example.at." || len(change.Records) != 1 {
+		t.Fatalf("Change returned %+v", change)
+	}
+
+	if content := change.Records[0].Content; strings.Count(content, `"`) != 4 {
+		t.Errorf("long DKIM record was not split into character-strings: %s", content)
+	}
+
+	if err := change.Validate(); err != nil {
+		t.Errorf("Change returned an invalid change: %v", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	server.AddZone("example.at",
+		rc0test.TXTRRSet("example.at.", "v=spf1 mx -all", "v=spf1 +all", "other"),
+		rc0test.TXTRRSet("_dmarc.example.at.", "v=DMARC1; p=quarantine"),
+		rc0test.TXTRRSet("mail._domainkey.example.at.", "k=rsa; p="+rsaKey(t, 2048)),
+		rc0test.TXTRRSet("_adsp._domainkey.example.at.", "dkim=all"),
+		rc0test.TXTRRSet("_mta-sts.example.at.", "v=STSv1; id=1"),
+		rc0test.TXTRRSet("_smtp._tls.example.at.", "v=TLSRPTv1"),
+	)
+
+	z, err := Load(context.Background(), server.Client().RRSet, "example.at")
+	if err != nil {
+		t.Fatalf("Load returned error: %v", err)
+	}
+
+	if len(z.SPF["example.at."]) != 2 || len(z.DMARC["_dmarc.example.at."]) != 1 || len(z.DKIM["mail._domainkey.example.at."]) != 1 || len(z.MTASTS["_mta-sts.example.at."]) != 1 {
+		t.Errorf("Load returned %+v", z)
+	}
+
+	if len(z.Errors) != 1 || z.Errors[0].Name != "_smtp._tls.example.at." {
+		t.Errorf("Load returned errors %v", z.Errors)
+	}
+}
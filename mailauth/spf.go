@@ -0,0 +1,226 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"net"
+	"strings"
+)
+
+// SPFVersion starts every SPF record
+const SPFVersion = "v=spf1"
+
+// MaxSPFLookups is the limit of DNS lookups during an SPF evaluation (RFC 7208 4.6.4)
+const MaxSPFLookups = 10
+
+// Mechanism is a single SPF mechanism, f.e. "-all" or "include:_spf.example.at"
+type Mechanism struct {
+
+	// Qualifier is one of "+", "-", "~", "?" or empty for the default "+"
+	Qualifier string
+
+	// Name is one of "all", "include", "a", "mx", "ptr", "ip4", "ip6" and "exists"
+	Name string
+
+	// Value is the domain-spec or, for ip4 and ip6, the address or network
+	Value string
+
+	// CIDR holds the prefix lengths of "a" and "mx", f.e. "/24" or "/24//64"
+	CIDR string
+}
+
+// SPF is a parsed SPF record (RFC 7208)
+type SPF struct {
+	Mechanisms []*Mechanism
+
+	// Redirect and Exp are the values of the modifiers of the same name
+	Redirect string
+	Exp      string
+
+	// Modifiers holds other modifiers, which are kept for formatting
+	Modifiers []Tag
+}
+
+// ParseMechanism parses a single mechanism
+func ParseMechanism(term string) (*Mechanism, error) {
+
+	m := &Mechanism{}
+
+	if term != "" && strings.ContainsAny(term[:1], "+-~?") {
+		m.Qualifier, term = term[:1], term[1:]
+	}
+
+	name := term
+	if i := strings.IndexAny(term, ":/"); i >= 0 {
+		name = term[:i]
+	}
+
+	m.Name = strings.ToLower(name)
+	rest := term[len(name):]
+
+	switch m.Name {
+
+	case "all":
+		if rest != "" {
+			return nil, fmt.Errorf("invalid mechanism %q", term)
+		}
+
+	case "ip4", "ip6":
+		if !strings.HasPrefix(rest, ":") {
+			return nil, fmt.Errorf("mechanism %q requires an address", term)
+		}
+		m.Value = rest[1:]
+
+		ip := net.ParseIP(m.Value)
+		if _, network, err := net.ParseCIDR(m.Value); err == nil {
+			ip = network.IP
+		}
+		if ip == nil || (ip.To4() != nil) != (m.Name == "ip4") {
+			return nil, fmt.Errorf("invalid address in mechanism %q", term)
+		}
+
+	case "include", "exists":
+		if !strings.HasPrefix(rest, ":") || len(rest) == 1 {
+			return nil, fmt.Errorf("mechanism %q requires a domain", term)
+		}
+		m.Value = rest[1:]
+
+	case "a", "mx", "ptr":
+		if strings.HasPrefix(rest, ":") {
+			rest = rest[1:]
+			if i := strings.Index(rest, "/"); i >= 0 && m.Name != "ptr" {
+				m.Value, rest = rest[:i], rest[i:]
+			} else {
+				m.Value, rest = rest, ""
+			}
+		}
+		if rest != "" && m.Name == "ptr" {
+			return nil, fmt.Errorf("invalid mechanism %q", term)
+		}
+		m.CIDR = rest
+
+	default:
+		return nil, fmt.Errorf("unknown mechanism %q", term)
+	}
+
+	return m, nil
+}
+
+// String returns the mechanism as in the record
+func (m *Mechanism) String() string {
+
+	s := m.Qualifier + m.Name
+
+	if m.Value != "" {
+		s += ":" + m.Value
+	}
+
+	return s + m.CIDR
+}
+
+// ParseSPF parses the text of an SPF TXT record
+func ParseSPF(text string) (*SPF, error) {
+
+	if !rc0go.HasTXTVersion(text, SPFVersion) {
+		return nil, fmt.Errorf("record does not start with %q", SPFVersion)
+	}
+
+	spf := &SPF{}
+
+	for _, term := range strings.Fields(text)[1:] {
+
+		if i := strings.Index(term, "="); i > 0 && !strings.ContainsAny(term[:i], ":/") {
+
+			name, value := strings.ToLower(term[:i]), term[i+1:]
+
+			switch name {
+			case "redirect":
+				spf.Redirect = value
+			case "exp":
+				spf.Exp = value
+			default:
+				spf.Modifiers = append(spf.Modifiers, Tag{Name: name, Value: value})
+			}
+			continue
+		}
+
+		m, err := ParseMechanism(term)
+		if err != nil {
+			return nil, err
+		}
+
+		spf.Mechanisms = append(spf.Mechanisms, m)
+	}
+
+	return spf, nil
+}
+
+// Version implements Record
+func (s *SPF) Version() string {
+	return SPFVersion
+}
+
+// String returns the text of the record
+func (s *SPF) String() string {
+
+	terms := []string{SPFVersion}
+
+	for _, m := range s.Mechanisms {
+		terms = append(terms, m.String())
+	}
+
+	if s.Redirect != "" {
+		terms = append(terms, "redirect="+s.Redirect)
+	}
+
+	if s.Exp != "" {
+		terms = append(terms, "exp="+s.Exp)
+	}
+
+	for _, t := range s.Modifiers {
+		terms = append(terms, t.Name+"="+t.Value)
+	}
+
+	return strings.Join(terms, " ")
+}
+
+// All returns the "all" mechanism or nil
+func (s *SPF) All() *Mechanism {
+
+	for _, m := range s.Mechanisms {
+		if m.Name == "all" {
+			return m
+		}
+	}
+
+	return nil
+}
+
+// Lookups returns the number of mechanisms and modifiers of this record which cause DNS
+// lookups. Lookups of included records are not counted.
+func (s *SPF) Lookups() int {
+
+	n := 0
+
+	for _, m := range s.Mechanisms {
+		switch m.Name {
+		case "include", "a", "mx", "ptr", "exists":
+			n++
+		}
+	}
+
+	if s.Redirect != "" {
+		n++
+	}
+
+	return n
+}
+
+func (s *SPF) matches(text string) bool {
+	return rc0go.HasTXTVersion(text, SPFVersion)
+}
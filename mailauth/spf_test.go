@@ -0,0 +1,83 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSPF(t *testing.T) {
+
+	text := "v=spf1 ip4:192.0.2.0/24 ip6:2001:db8::/32 a mx:mail.example.at/24 include:_spf.example.net ~all redirect=_spf.example.at exp=explain.example.at"
+
+	spf, err := ParseSPF(text)
+	if err != nil {
+		t.Fatalf("ParseSPF returned error: %v", err)
+	}
+
+	want := []*Mechanism{
+		{Name: "ip4", Value: "192.0.2.0/24"},
+		{Name: "ip6", Value: "2001:db8::/32"},
+		{Name: "a"},
+		{Name: "mx", Value: "mail.example.at", CIDR: "/24"},
+		{Name: "include", Value: "_spf.example.net"},
+		{Qualifier: "~", Name: "all"},
+	}
+
+	if !reflect.DeepEqual(spf.Mechanisms, want) {
+		t.Errorf("ParseSPF returned mechanisms %+v", spf.Mechanisms)
+	}
+
+	if spf.Redirect != "_spf.example.at" || spf.Exp != "explain.example.at" {
+		t.Errorf("ParseSPF returned modifiers redirect=%q exp=%q", spf.Redirect, spf.Exp)
+	}
+
+	if got := spf.String(); got != text {
+		t.Errorf("String returned %q, want %q", got, text)
+	}
+
+	if n := spf.Lookups(); n != 4 {
+		t.Errorf("Lookups returned %d, want 4", n)
+	}
+
+	if all := spf.All(); all == nil || all.String() != "~all" {
+		t.Errorf("All returned %v", all)
+	}
+}
+
+func TestParseSPFErrors(t *testing.T) {
+
+	for _, text := range []string{
+		"v=spf10 -all",
+		"spf1 -all",
+		"v=spf1 ip4:2001:db8::1",
+		"v=spf1 ip6:192.0.2.1",
+		"v=spf1 ip4",
+		"v=spf1 include:",
+		"v=spf1 all:example.at",
+		"v=spf1 ptr/24",
+		"v=spf1 foo:example.at",
+	} {
+		if _, err := ParseSPF(text); err == nil {
+			t.Errorf("ParseSPF(%q) did not return an error", text)
+		}
+	}
+}
+
+func TestSPFBuilder(t *testing.T) {
+
+	spf := &SPF{Mechanisms: []*Mechanism{
+		{Name: "mx"},
+		{Name: "a", CIDR: "/28"},
+		{Name: "ip4", Value: "192.0.2.1"},
+		{Qualifier: "-", Name: "all"},
+	}}
+
+	if got, want := spf.String(), "v=spf1 mx a/28 ip4:192.0.2.1 -all"; got != want {
+		t.Errorf("String returned %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tag is a tag=value pair of a DKIM, DMARC, MTA-STS or TLS-RPT record
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// parseTags parses a tag-list (RFC 6376 3.2), f.e. "v=DMARC1; p=none"
+func parseTags(text string) ([]Tag, error) {
+
+	var tags []Tag
+
+	seen := make(map[string]bool)
+
+	for _, spec := range strings.Split(text, ";") {
+
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		i := strings.Index(spec, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid tag %q", spec)
+		}
+
+		name := strings.ToLower(strings.TrimSpace(spec[:i]))
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate tag %q", name)
+		}
+		seen[name] = true
+
+		tags = append(tags, Tag{Name: name, Value: strings.TrimSpace(spec[i+1:])})
+	}
+
+	return tags, nil
+}
+
+// parseVersioned parses a tag-list which has to start with the version tag, f.e. "v=STSv1"
+func parseVersioned(text string, version string) ([]Tag, error) {
+
+	tags, err := parseTags(text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tags) == 0 || tags[0].Name != "v" || tags[0].Value != version {
+		return nil, fmt.Errorf("record does not start with \"v=%s\"", version)
+	}
+
+	return tags[1:], nil
+}
+
+// formatTags returns the tags as tag-list
+func formatTags(tags []Tag) string {
+
+	specs := make([]string, len(tags))
+	for i, t := range tags {
+		specs[i] = t.Name + "=" + t.Value
+	}
+
+	return strings.Join(specs, "; ")
+}
+
+// splitList splits a comma or colon separated tag value
+func splitList(value string, sep string) []string {
+
+	var list []string
+
+	for _, v := range strings.Split(value, sep) {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,140 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package mailauth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func rsaKey(t *testing.T, bits int) string {
+
+	n := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	n.Add(n, big.NewInt(1))
+
+	der, err := x509.MarshalPKIXPublicKey(&rsa.PublicKey{N: n, E: 65537})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func TestParseDKIM(t *testing.T) {
+
+	key := rsaKey(t, 2048)
+
+	d, err := ParseDKIM("v=DKIM1; k=rsa; t=y:s; h=sha256; p=" + key[:100] + " " + key[100:])
+	if err != nil {
+		t.Fatalf("ParseDKIM returned error: %v", err)
+	}
+
+	if d.PublicKey != key || !d.Testing() || !reflect.DeepEqual(d.HashAlgorithms, []string{"sha256"}) {
+		t.Errorf("ParseDKIM returned %+v", d)
+	}
+
+	if bits, err := d.KeyBits(); err != nil || bits != 2048 {
+		t.Errorf("KeyBits returned %d, %v", bits, err)
+	}
+
+	if got, want := d.String(), "v=DKIM1; k=rsa; h=sha256; t=y:s; p="+key; got != want {
+		t.Errorf("String returned %q, want %q", got, want)
+	}
+
+	pub, _, _ := ed25519.GenerateKey(nil)
+	ed := &DKIM{KeyType: "ed25519", PublicKey: base64.StdEncoding.EncodeToString(pub)}
+
+	if bits, err := ed.KeyBits(); err != nil || bits != 256 {
+		t.Errorf("KeyBits of ed25519 key returned %d, %v", bits, err)
+	}
+
+	if _, err := ParseDKIM("v=DKIM1; k=rsa"); err == nil {
+		t.Error("ParseDKIM accepted a record without key")
+	}
+
+	if _, err := ParseDKIM("k=rsa; v=DKIM1; p="); err == nil {
+		t.Error("ParseDKIM accepted a version tag which is not the first")
+	}
+}
+
+func TestParseDMARC(t *testing.T) {
+
+	text := "v=DMARC1; p=reject; sp=none; pct=50; rua=mailto:a@example.at,mailto:b@example.at; adkim=s"
+
+	d, err := ParseDMARC(text)
+	if err != nil {
+		t.Fatalf("ParseDMARC returned error: %v", err)
+	}
+
+	if d.Policy != "reject" || d.SubdomainPolicy != "none" || d.Pct() != 50 || len(d.RUA) != 2 || d.ADKIM != "s" {
+		t.Errorf("ParseDMARC returned %+v", d)
+	}
+
+	if got := d.String(); got != text {
+		t.Errorf("String returned %q, want %q", got, text)
+	}
+
+	if got := (&DMARC{Policy: "none"}).String(); got != "v=DMARC1; p=none" {
+		t.Errorf("String returned %q", got)
+	}
+
+	for _, text := range []string{
+		"p=none",
+		"v=DMARC1",
+		"v=DMARC1; p=block",
+		"v=DMARC1; p=none; pct=101",
+		"v=DMARC1; p=none; p=reject",
+		"v=DMARC1; p",
+	} {
+		if _, err := ParseDMARC(text); err == nil {
+			t.Errorf("ParseDMARC(%q) did not return an error", text)
+		}
+	}
+}
+
+func TestParseMTASTSAndTLSRPT(t *testing.T) {
+
+	sts, err := ParseMTASTS("v=STSv1; id=20191011T120000;")
+	if err != nil || sts.ID != "20191011T120000" {
+		t.Fatalf("ParseMTASTS returned %+v, %v", sts, err)
+	}
+
+	if got := sts.String(); got != "v=STSv1; id=20191011T120000" {
+		t.Errorf("String returned %q", got)
+	}
+
+	rpt, err := ParseTLSRPT("v=TLSRPTv1;rua=mailto:tlsrpt@example.at")
+	if err != nil || !reflect.DeepEqual(rpt.RUA, []string{"mailto:tlsrpt@example.at"}) {
+		t.Fatalf("ParseTLSRPT returned %+v, %v", rpt, err)
+	}
+
+	if got := rpt.String(); got != "v=TLSRPTv1; rua=mailto:tlsrpt@example.at" {
+		t.Errorf("String returned %q", got)
+	}
+
+	if _, err := ParseMTASTS("v=STSv1"); err == nil {
+		t.Error("ParseMTASTS accepted a record without id")
+	}
+
+	if _, err := ParseTLSRPT("v=TLSRPTv1; rua="); err == nil {
+		t.Error("ParseTLSRPT accepted a record without rua")
+	}
+}
+
+func TestNames(t *testing.T) {
+
+	got := []string{DKIMName("mail", "Example.at"), DMARCName("example.at"), MTASTSName("example.at."), TLSRPTName("example.at")}
+	want := []string{"mail._domainkey.example.at.", "_dmarc.example.at.", "_mta-sts.example.at.", "_smtp._tls.example.at."}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got names %v, want %v", got, want)
+	}
+}
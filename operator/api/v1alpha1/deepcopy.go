@@ -0,0 +1,154 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto copies the spec into out
+func (in *DNSZoneSpec) DeepCopyInto(out *DNSZoneSpec) {
+	*out = *in
+	if in.Masters != nil {
+		out.Masters = append([]string(nil), in.Masters...)
+	}
+}
+
+// DeepCopyInto copies the status into out
+func (in *DNSZoneStatus) DeepCopyInto(out *DNSZoneStatus) {
+	*out = *in
+	out.Conditions = copyConditions(in.Conditions)
+}
+
+// DeepCopyInto copies the zone into out
+func (in *DNSZone) DeepCopyInto(out *DNSZone) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy returns a copy of the zone
+func (in *DNSZone) DeepCopy() *DNSZone {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSZone)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *DNSZone) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+// DeepCopyInto copies the list into out
+func (in *DNSZoneList) DeepCopyInto(out *DNSZoneList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]DNSZone, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a copy of the list
+func (in *DNSZoneList) DeepCopy() *DNSZoneList {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSZoneList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *DNSZoneList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+// DeepCopyInto copies the spec into out
+func (in *DNSRecordSetSpec) DeepCopyInto(out *DNSRecordSetSpec) {
+	*out = *in
+	if in.Records != nil {
+		out.Records = append([]string(nil), in.Records...)
+	}
+}
+
+// DeepCopyInto copies the status into out
+func (in *DNSRecordSetStatus) DeepCopyInto(out *DNSRecordSetStatus) {
+	*out = *in
+	out.Conditions = copyConditions(in.Conditions)
+}
+
+// DeepCopyInto copies the rrset into out
+func (in *DNSRecordSet) DeepCopyInto(out *DNSRecordSet) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy returns a copy of the rrset
+func (in *DNSRecordSet) DeepCopy() *DNSRecordSet {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSRecordSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *DNSRecordSet) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+// DeepCopyInto copies the list into out
+func (in *DNSRecordSetList) DeepCopyInto(out *DNSRecordSetList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]DNSRecordSet, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a copy of the list
+func (in *DNSRecordSetList) DeepCopy() *DNSRecordSetList {
+	if in == nil {
+		return nil
+	}
+	out := new(DNSRecordSetList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object
+func (in *DNSRecordSetList) DeepCopyObject() runtime.Object {
+	return in.DeepCopy()
+}
+
+func copyConditions(in []metav1.Condition) []metav1.Condition {
+	if in == nil {
+		return nil
+	}
+	out := make([]metav1.Condition, len(in))
+	for i := range in {
+		in[i].DeepCopyInto(&out[i])
+	}
+	return out
+}
@@ -0,0 +1,25 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package v1alpha1 contains the DNSZone and DNSRecordSet resources of the rc0 operator.
+// +kubebuilder:object:generate=true
+// +groupName=rc0.nic.at
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// GroupVersion is the group and version of the resources
+	GroupVersion = schema.GroupVersion{Group: "rc0.nic.at", Version: "v1alpha1"}
+
+	// SchemeBuilder registers the resources with a scheme
+	SchemeBuilder = &scheme.Builder{GroupVersion: GroupVersion}
+
+	// AddToScheme adds the resources to the scheme
+	AddToScheme = SchemeBuilder.AddToScheme
+)
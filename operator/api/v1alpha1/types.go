@@ -0,0 +1,132 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Finalizer is set on resources whose rcode0 counterpart has to be cleaned up
+	Finalizer = "rc0.nic.at/cleanup"
+
+	// ConditionReady reports whether the resource is in sync with rcode0
+	ConditionReady = "Ready"
+
+	// ConditionDNSSEC reports whether the zone is signed
+	ConditionDNSSEC = "DNSSECSigned"
+
+	// DeletionPolicyDelete removes the zone from rcode0 when the resource is deleted
+	DeletionPolicyDelete = "Delete"
+
+	// DeletionPolicyRetain keeps the zone on rcode0 when the resource is deleted
+	DeletionPolicyRetain = "Retain"
+)
+
+// DNSZoneSpec is the desired state of a zone
+type DNSZoneSpec struct {
+
+	// Domain of the zone, f.e. "example.at"
+	Domain string `json:"domain"`
+
+	// Type of the zone, "MASTER" or "SLAVE"
+	// +kubebuilder:validation:Enum=MASTER;SLAVE
+	Type string `json:"type"`
+
+	// Masters are the primary name servers of a SLAVE zone
+	// +optional
+	Masters []string `json:"masters,omitempty"`
+
+	// DeletionPolicy decides whether the zone is removed from rcode0 when the resource
+	// is deleted (defaults to "Delete")
+	// +kubebuilder:validation:Enum=Delete;Retain
+	// +optional
+	DeletionPolicy string `json:"deletionPolicy,omitempty"`
+}
+
+// DNSZoneStatus is the observed state of a zone
+type DNSZoneStatus struct {
+	ObservedGeneration int64              `json:"observedGeneration,omitempty"`
+	Serial             int                `json:"serial,omitempty"`
+	DNSSECStatus       string             `json:"dnssecStatus,omitempty"`
+	Conditions         []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// DNSZone is a zone on rcode0
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+type DNSZone struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DNSZoneSpec   `json:"spec,omitempty"`
+	Status DNSZoneStatus `json:"status,omitempty"`
+}
+
+// DNSZoneList is a list of DNSZone resources
+// +kubebuilder:object:root=true
+type DNSZoneList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DNSZone `json:"items"`
+}
+
+// DNSRecordSetSpec is the desired state of an rrset
+type DNSRecordSetSpec struct {
+
+	// Zone the rrset belongs to, f.e. "example.at"
+	Zone string `json:"zone"`
+
+	// Name of the rrset, relative to the zone ("@" for the apex) or fully qualified
+	Name string `json:"name"`
+
+	// Type of the rrset, f.e. "A"
+	Type string `json:"type"`
+
+	// TTL of the rrset in seconds
+	// +kubebuilder:validation:Minimum=60
+	TTL int `json:"ttl"`
+
+	// Records in presentation format, f.e. "10.0.0.1" or "10 mail.example.at."
+	// +kubebuilder:validation:MinItems=1
+	Records []string `json:"records"`
+}
+
+// DNSRecordSetStatus is the observed state of an rrset
+type DNSRecordSetStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// FQDN and Type of the managed rrset, used to clean up after renames
+	FQDN string `json:"fqdn,omitempty"`
+	Type string `json:"type,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// DNSRecordSet is an rrset of a zone on rcode0
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Namespaced
+type DNSRecordSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DNSRecordSetSpec   `json:"spec,omitempty"`
+	Status DNSRecordSetStatus `json:"status,omitempty"`
+}
+
+// DNSRecordSetList is a list of DNSRecordSet resources
+// +kubebuilder:object:root=true
+type DNSRecordSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DNSRecordSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DNSZone{}, &DNSZoneList{}, &DNSRecordSet{}, &DNSRecordSetList{})
+}
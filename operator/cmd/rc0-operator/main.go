@@ -0,0 +1,86 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// rc0-operator manages rcode0 zones and rrsets as Kubernetes resources:
+//
+//	$ kubectl apply -f operator/config/crd
+//	$ RC0_API_KEY=YOUR_API_KEY rc0-operator
+package main
+
+import (
+	"flag"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/operator/api/v1alpha1"
+	"github.com/nic-at/rc0go/operator/controllers"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+func main() {
+
+	metricsAddr := flag.String("metrics-bind-address", ":8080", "address of the metrics endpoint")
+	probeAddr := flag.String("health-probe-bind-address", ":8081", "address of the health probe endpoint")
+	leaderElection := flag.Bool("leader-elect", false, "enable leader election")
+	driftInterval := flag.Duration("drift-interval", controllers.DefaultDriftInterval, "interval to compare resources with rcode0")
+	flag.Parse()
+
+	rc0client, err := rc0go.NewClient(os.Getenv("RC0_API_KEY"))
+	if err != nil {
+		log.Fatalf("failed to initialize rcodezero client: %v", err)
+	}
+
+	if strings.Contains(os.Getenv("RC0_BASE_URL"), "rcodezero.at/api/") {
+		rc0client.BaseURL, _ = url.Parse(os.Getenv("RC0_BASE_URL"))
+	}
+
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: *metricsAddr},
+		HealthProbeBindAddress: *probeAddr,
+		LeaderElection:         *leaderElection,
+		LeaderElectionID:       "rc0-operator.rc0.nic.at",
+	})
+	if err != nil {
+		log.Fatalf("failed to create manager: %v", err)
+	}
+
+	err = (&controllers.DNSZoneReconciler{
+		Client:        mgr.GetClient(),
+		Zones:         rc0client.Zones,
+		DriftInterval: *driftInterval,
+	}).SetupWithManager(mgr)
+	if err != nil {
+		log.Fatalf("failed to set up DNSZone controller: %v", err)
+	}
+
+	err = (&controllers.DNSRecordSetReconciler{
+		Client:        mgr.GetClient(),
+		RRSet:         rc0client.RRSet,
+		Zones:         rc0client.Zones,
+		DriftInterval: *driftInterval,
+	}).SetupWithManager(mgr)
+	if err != nil {
+		log.Fatalf("failed to set up DNSRecordSet controller: %v", err)
+	}
+
+	_ = mgr.AddHealthzCheck("healthz", healthz.Ping)
+	_ = mgr.AddReadyzCheck("readyz", healthz.Ping)
+
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		log.Fatalf("manager stopped: %v", err)
+	}
+}
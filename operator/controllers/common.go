@@ -0,0 +1,48 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package controllers reconciles DNSZone and DNSRecordSet resources with rcode0.
+package controllers
+
+import (
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"time"
+)
+
+// DefaultDriftInterval is the interval in which resources are compared with rcode0 again,
+// so changes made outside of Kubernetes are reverted
+const DefaultDriftInterval = 10 * time.Minute
+
+const (
+	reasonSynced     = "Synced"
+	reasonSyncFailed = "SyncFailed"
+	reasonInvalid    = "Invalid"
+)
+
+func driftInterval(d time.Duration) time.Duration {
+	if d <= 0 {
+		return DefaultDriftInterval
+	}
+	return d
+}
+
+// checkStatus turns a failed rcode0 status response into an error
+func checkStatus(status *rc0go.StatusResponse, err error) error {
+
+	if err != nil {
+		return err
+	}
+
+	if status == nil {
+		return fmt.Errorf("empty response from rcode0")
+	}
+
+	if status.HasError() {
+		return fmt.Errorf("rcode0: %s", status.Message)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"strings"
+	"time"
+)
+
+// DNSRecordSetReconciler submits rcode0 change sets for DNSRecordSet resources
+type DNSRecordSetReconciler struct {
+	client.Client
+
+	// RRSet service of the rcode0 client
+	RRSet rc0go.RRSetServiceInterface
+
+	// Zones service of the rcode0 client, used to detect zones which were deleted before
+	// their rrsets (optional)
+	Zones rc0go.ZoneManagementServiceInterface
+
+	// DriftInterval defaults to DefaultDriftInterval
+	DriftInterval time.Duration
+}
+
+// SetupWithManager registers the reconciler with the manager
+func (r *DNSRecordSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).For(&v1alpha1.DNSRecordSet{}).Complete(r)
+}
+
+// Reconcile brings the rcode0 rrset in line with the DNSRecordSet resource. If name or type
+// changed, the previously managed rrset is deleted.
+func (r *DNSRecordSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	var rrset v1alpha1.DNSRecordSet
+
+	if err := r.Get(ctx, req.NamespacedName, &rrset); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	zone := strings.TrimSuffix(strings.ToLower(rrset.Spec.Zone), ".")
+
+	if !rrset.DeletionTimestamp.IsZero() {
+
+		if !controllerutil.ContainsFinalizer(&rrset, v1alpha1.Finalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if rrset.Status.FQDN != "" {
+
+			current, err := r.managed(ctx, zone, rrset.Status.FQDN, rrset.Status.Type)
+			if err == nil {
+				err = r.submit(ctx, zone, current, nil)
+			}
+
+			// the rrset went away with its zone
+			if err != nil && r.zoneGone(zone) {
+				err = nil
+			}
+
+			if err != nil {
+				return r.fail(ctx, &rrset, reasonSyncFailed, err)
+			}
+		}
+
+		controllerutil.RemoveFinalizer(&rrset, v1alpha1.Finalizer)
+
+		return ctrl.Result{}, r.Update(ctx, &rrset)
+	}
+
+	if controllerutil.AddFinalizer(&rrset, v1alpha1.Finalizer) {
+		if err := r.Update(ctx, &rrset); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	name, err := fqdn(rrset.Spec.Name, zone)
+	if err != nil {
+		// retrying does not help, wait for the resource to change
+		_, _ = r.fail(ctx, &rrset, reasonInvalid, err)
+		return ctrl.Result{}, nil
+	}
+
+	desired := &rc0go.RRType{
+		Name: name,
+		Type: strings.ToUpper(rrset.Spec.Type),
+		TTL:  rrset.Spec.TTL,
+	}
+
+	for _, content := range rrset.Spec.Records {
+
+		if err := rc0go.ValidateContent(desired.Type, content); err != nil {
+			// retrying does not help, wait for the resource to change
+			_, _ = r.fail(ctx, &rrset, reasonInvalid, err)
+			return ctrl.Result{}, nil
+		}
+
+		desired.Records = append(desired.Records, &rc0go.Record{Content: content})
+	}
+
+	current, err := r.managed(ctx, zone, desired.Name, desired.Type)
+
+	if err == nil && rrset.Status.FQDN != "" && (rrset.Status.FQDN != desired.Name || rrset.Status.Type != desired.Type) {
+		var previous []*rc0go.RRType
+		previous, err = r.managed(ctx, zone, rrset.Status.FQDN, rrset.Status.Type)
+		current = append(current, previous...)
+	}
+
+	if err == nil {
+		err = r.submit(ctx, zone, current, []*rc0go.RRType{desired})
+	}
+
+	if err != nil {
+		return r.fail(ctx, &rrset, reasonSyncFailed, err)
+	}
+
+	rrset.Status.ObservedGeneration = rrset.Generation
+	rrset.Status.FQDN = desired.Name
+	rrset.Status.Type = desired.Type
+
+	meta.SetStatusCondition(&rrset.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonSynced,
+		Message:            fmt.Sprintf("rrset %s %s is in sync with rcode0", desired.Name, desired.Type),
+		ObservedGeneration: rrset.Generation,
+	})
+
+	if err := r.Status().Update(ctx, &rrset); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: driftInterval(r.DriftInterval)}, nil
+}
+
+// managed returns the rrset with the name and type if it exists on rcode0
+func (r *DNSRecordSetReconciler) managed(ctx context.Context, zone string, name string, rrType string) ([]*rc0go.RRType, error) {
+
+	rrset, err := r.RRSet.Get(ctx, zone, name, rrType)
+	if rc0go.IsRRSetNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return []*rc0go.RRType{rrset}, nil
+}
+
+// zoneGone reports whether the zone no longer exists on rcode0
+func (r *DNSRecordSetReconciler) zoneGone(zone string) bool {
+
+	if r.Zones == nil {
+		return false
+	}
+
+	current, err := r.Zones.Get(zone)
+
+	return err == nil && (current == nil || current.Domain == "")
+}
+
+// submit applies the difference between current and desired rrsets
+func (r *DNSRecordSetReconciler) submit(ctx context.Context, zone string, current []*rc0go.RRType, desired []*rc0go.RRType) error {
+
+	diff := rc0go.DiffRRSets(current, desired)
+	if diff.IsEmpty() {
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return checkStatus(r.RRSet.SubmitChangeSet(zone, diff.Changes))
+}
+
+// fail records the error in the Ready condition and returns it for a retry
+func (r *DNSRecordSetReconciler) fail(ctx context.Context, rrset *v1alpha1.DNSRecordSet, reason string, err error) (ctrl.Result, error) {
+
+	meta.SetStatusCondition(&rrset.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: rrset.Generation,
+	})
+
+	_ = r.Status().Update(ctx, rrset)
+
+	return ctrl.Result{}, err
+}
+
+// fqdn returns the fully qualified name of the rrset, "@" is the apex. Fully qualified
+// names outside of the zone are rejected.
+func fqdn(name string, zone string) (string, error) {
+
+	apex := rc0go.NormalizeName(zone)
+
+	switch {
+	case name == "@" || name == "":
+		return apex, nil
+	case !strings.HasSuffix(name, "."):
+		return rc0go.NormalizeName(name + "." + zone), nil
+	}
+
+	name = rc0go.NormalizeName(name)
+
+	if name != apex && !strings.HasSuffix(name, "."+apex) {
+		return "", fmt.Errorf("name %s is outside of zone %s", name, apex)
+	}
+
+	return name, nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/operator/api/v1alpha1"
+	"github.com/nic-at/rc0go/rc0test"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"testing"
+)
+
+func TestDNSRecordSetReconciler(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	server.AddZone("testzone1.at")
+
+	rrset := &v1alpha1.DNSRecordSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "www"},
+		Spec:       v1alpha1.DNSRecordSetSpec{Zone: "testzone1.at", Name: "www", Type: "A", TTL: 300, Records: []string{"10.10.0.1"}},
+	}
+
+	k8s := newFakeClient(rrset)
+	key := types.NamespacedName{Namespace: "default", Name: "www"}
+
+	reconciler := &DNSRecordSetReconciler{Client: k8s, RRSet: server.Client().RRSet}
+
+	reconcile(t, reconciler, "www")
+
+	want := []*rc0go.RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.1"}}},
+	}
+
+	if got := server.RRSets("testzone1.at"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile left rrsets %+v, want %+v", got, want)
+	}
+
+	// in sync: nothing is submitted
+	reconcile(t, reconciler, "www")
+
+	if got := len(server.Patches("testzone1.at")); got != 1 {
+		t.Errorf("Reconcile submitted %d change sets, want 1", got)
+	}
+
+	// rename: the previous rrset is deleted
+	var got v1alpha1.DNSRecordSet
+	_ = k8s.Get(context.Background(), key, &got)
+
+	got.Spec.Name = "web"
+	_ = k8s.Update(context.Background(), &got)
+
+	reconcile(t, reconciler, "www")
+
+	want[0].Name = "web.testzone1.at."
+
+	if got := server.RRSets("testzone1.at"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile left rrsets %+v after rename, want %+v", got, want)
+	}
+
+	// delete
+	_ = k8s.Get(context.Background(), key, &got)
+	_ = k8s.Delete(context.Background(), &got)
+
+	reconcile(t, reconciler, "www")
+
+	if got := server.RRSets("testzone1.at"); len(got) != 0 {
+		t.Errorf("Reconcile did not delete the rrset: %+v", got)
+	}
+}
+
+func TestDNSRecordSetReconciler_Invalid(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	server.AddZone("testzone1.at")
+
+	rrset := &v1alpha1.DNSRecordSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "www"},
+		Spec:       v1alpha1.DNSRecordSetSpec{Zone: "testzone1.at", Name: "www", Type: "A", TTL: 300, Records: []string{"10.10.0.256"}},
+	}
+
+	k8s := newFakeClient(rrset)
+
+	reconcile(t, &DNSRecordSetReconciler{Client: k8s, RRSet: server.Client().RRSet}, "www")
+
+	var got v1alpha1.DNSRecordSet
+	_ = k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "www"}, &got)
+
+	condition := meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionReady)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != reasonInvalid {
+		t.Errorf("Reconcile set condition %+v, want Ready=False with reason %s", condition, reasonInvalid)
+	}
+
+	if got := len(server.Patches("testzone1.at")); got != 0 {
+		t.Errorf("Reconcile submitted %d change sets for an invalid record", got)
+	}
+}
+
+// failingRRSet fails every lookup, like the API does for a deleted zone
+type failingRRSet struct {
+	rc0go.RRSetServiceInterface
+}
+
+func (f *failingRRSet) Get(ctx context.Context, zone string, name string, rrType string) (*rc0go.RRType, error) {
+	return nil, fmt.Errorf("zone %s not found", zone)
+}
+
+func TestDNSRecordSetReconciler_ZoneGone(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	server.AddZone("testzone1.at")
+
+	rrset := &v1alpha1.DNSRecordSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "www"},
+		Spec:       v1alpha1.DNSRecordSetSpec{Zone: "testzone1.at", Name: "www", Type: "A", TTL: 300, Records: []string{"10.10.0.1"}},
+	}
+
+	k8s := newFakeClient(rrset)
+	key := types.NamespacedName{Namespace: "default", Name: "www"}
+
+	reconciler := &DNSRecordSetReconciler{Client: k8s, RRSet: server.Client().RRSet, Zones: server.Client().Zones}
+
+	reconcile(t, reconciler, "www")
+
+	if _, err := server.Client().Zones.Delete("testzone1.at"); err != nil {
+		t.Fatalf("Zones.Delete returned error: %v", err)
+	}
+
+	var got v1alpha1.DNSRecordSet
+	_ = k8s.Get(context.Background(), key, &got)
+	_ = k8s.Delete(context.Background(), &got)
+
+	reconciler.RRSet = &failingRRSet{}
+
+	reconcile(t, reconciler, "www")
+
+	if err := k8s.Get(context.Background(), key, &got); !apierrors.IsNotFound(err) {
+		t.Errorf("Reconcile kept the finalizer of a DNSRecordSet in a deleted zone: %v", err)
+	}
+}
+
+func TestDNSRecordSetReconciler_OutOfZone(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	server.AddZone("testzone1.at")
+
+	rrset := &v1alpha1.DNSRecordSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "www"},
+		Spec:       v1alpha1.DNSRecordSetSpec{Zone: "testzone1.at", Name: "www.testzone2.at.", Type: "A", TTL: 300, Records: []string{"10.10.0.1"}},
+	}
+
+	k8s := newFakeClient(rrset)
+
+	reconcile(t, &DNSRecordSetReconciler{Client: k8s, RRSet: server.Client().RRSet}, "www")
+
+	var got v1alpha1.DNSRecordSet
+	_ = k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "www"}, &got)
+
+	condition := meta.FindStatusCondition(got.Status.Conditions, v1alpha1.ConditionReady)
+	if condition == nil || condition.Status != metav1.ConditionFalse || condition.Reason != reasonInvalid {
+		t.Errorf("Reconcile set condition %+v, want Ready=False with reason %s", condition, reasonInvalid)
+	}
+
+	if got := len(server.Patches("testzone1.at")); got != 0 {
+		t.Errorf("Reconcile submitted %d change sets for a name outside of the zone", got)
+	}
+}
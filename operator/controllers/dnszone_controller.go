@@ -0,0 +1,205 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DNSZoneReconciler creates, edits and deletes rcode0 zones for DNSZone resources
+type DNSZoneReconciler struct {
+	client.Client
+
+	// Zones service of the rcode0 client
+	Zones rc0go.ZoneManagementServiceInterface
+
+	// DriftInterval defaults to DefaultDriftInterval
+	DriftInterval time.Duration
+}
+
+// SetupWithManager registers the reconciler with the manager
+func (r *DNSZoneReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).For(&v1alpha1.DNSZone{}).Complete(r)
+}
+
+// Reconcile brings the rcode0 zone in line with the DNSZone resource
+func (r *DNSZoneReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+
+	var zone v1alpha1.DNSZone
+
+	if err := r.Get(ctx, req.NamespacedName, &zone); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	domain := strings.TrimSuffix(strings.ToLower(zone.Spec.Domain), ".")
+
+	if !zone.DeletionTimestamp.IsZero() {
+
+		if !controllerutil.ContainsFinalizer(&zone, v1alpha1.Finalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if zone.Spec.DeletionPolicy != v1alpha1.DeletionPolicyRetain {
+			if err := r.deleteZone(domain); err != nil {
+				return r.fail(ctx, &zone, err)
+			}
+		}
+
+		controllerutil.RemoveFinalizer(&zone, v1alpha1.Finalizer)
+
+		return ctrl.Result{}, r.Update(ctx, &zone)
+	}
+
+	if controllerutil.AddFinalizer(&zone, v1alpha1.Finalizer) {
+		if err := r.Update(ctx, &zone); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	current, err := r.Zones.Get(domain)
+	if err != nil {
+		return r.fail(ctx, &zone, err)
+	}
+
+	switch {
+
+	case current == nil || current.Domain == "":
+		err = checkStatus(r.Zones.Create(&rc0go.ZoneCreate{
+			Domain:  domain,
+			Type:    strings.ToUpper(zone.Spec.Type),
+			Masters: zone.Spec.Masters,
+		}))
+
+	case zoneDrifted(current, &zone.Spec):
+		err = checkStatus(r.Zones.Edit(domain, &rc0go.ZoneEdit{
+			Type:    strings.ToUpper(zone.Spec.Type),
+			Masters: zone.Spec.Masters,
+		}))
+	}
+
+	if err != nil {
+		return r.fail(ctx, &zone, err)
+	}
+
+	if current, err = r.Zones.Get(domain); err != nil {
+		return r.fail(ctx, &zone, err)
+	}
+
+	zone.Status.ObservedGeneration = zone.Generation
+	zone.Status.Serial = current.Serial
+	zone.Status.DNSSECStatus = current.DNSSECStatus
+
+	meta.SetStatusCondition(&zone.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             reasonSynced,
+		Message:            fmt.Sprintf("zone %s is in sync with rcode0", domain),
+		ObservedGeneration: zone.Generation,
+	})
+
+	meta.SetStatusCondition(&zone.Status.Conditions, dnssecCondition(current, zone.Generation))
+
+	if err := r.Status().Update(ctx, &zone); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: driftInterval(r.DriftInterval)}, nil
+}
+
+// deleteZone removes the zone from rcode0 unless it is already gone
+func (r *DNSZoneReconciler) deleteZone(domain string) error {
+
+	current, err := r.Zones.Get(domain)
+	if err != nil {
+		return err
+	}
+
+	if current == nil || current.Domain == "" {
+		return nil
+	}
+
+	return checkStatus(r.Zones.Delete(domain))
+}
+
+// fail records the error in the Ready condition and returns it for a retry
+func (r *DNSZoneReconciler) fail(ctx context.Context, zone *v1alpha1.DNSZone, err error) (ctrl.Result, error) {
+
+	meta.SetStatusCondition(&zone.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionReady,
+		Status:             metav1.ConditionFalse,
+		Reason:             reasonSyncFailed,
+		Message:            err.Error(),
+		ObservedGeneration: zone.Generation,
+	})
+
+	_ = r.Status().Update(ctx, zone)
+
+	return ctrl.Result{}, err
+}
+
+// zoneDrifted reports whether type or masters of the rcode0 zone differ from the spec
+func zoneDrifted(current *rc0go.Zone, spec *v1alpha1.DNSZoneSpec) bool {
+
+	if !strings.EqualFold(current.Type, spec.Type) {
+		return true
+	}
+
+	a := append([]string(nil), current.Masters...)
+	b := append([]string(nil), spec.Masters...)
+
+	if len(a) != len(b) {
+		return true
+	}
+
+	sort.Strings(a)
+	sort.Strings(b)
+
+	for i := range a {
+		if a[i] != b[i] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dnssecCondition reflects the DNSSEC status of the rcode0 zone
+func dnssecCondition(zone *rc0go.Zone, generation int64) metav1.Condition {
+
+	condition := metav1.Condition{
+		Type:               v1alpha1.ConditionDNSSEC,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Unsigned",
+		Message:            strings.TrimSpace(zone.DNSSECStatus + " " + zone.DNSSECStatusDetail),
+		ObservedGeneration: generation,
+	}
+
+	switch strings.ToLower(zone.DNSSECStatus) {
+	case "yes", "signed":
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Signed"
+	case "":
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "Unknown"
+	}
+
+	if condition.Message == "" {
+		condition.Message = "no DNSSEC status reported"
+	}
+
+	return condition
+}
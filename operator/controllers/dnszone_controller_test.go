@@ -0,0 +1,131 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package controllers
+
+import (
+	"context"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/operator/api/v1alpha1"
+	"github.com/nic-at/rc0go/rc0test"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func newFakeClient(objects ...client.Object) client.Client {
+
+	scheme := runtime.NewScheme()
+	_ = v1alpha1.AddToScheme(scheme)
+
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&v1alpha1.DNSZone{}, &v1alpha1.DNSRecordSet{}).
+		Build()
+}
+
+func reconcile(t *testing.T, r interface {
+	Reconcile(context.Context, ctrl.Request) (ctrl.Result, error)
+}, name string) ctrl.Result {
+
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: name}})
+	if err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+
+	return result
+}
+
+func TestDNSZoneReconciler(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	zone := &v1alpha1.DNSZone{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "testzone1"},
+		Spec:       v1alpha1.DNSZoneSpec{Domain: "testzone1.at", Type: "MASTER"},
+	}
+
+	k8s := newFakeClient(zone)
+
+	reconciler := &DNSZoneReconciler{Client: k8s, Zones: server.Client().Zones}
+
+	// create
+	if result := reconcile(t, reconciler, "testzone1"); result.RequeueAfter != DefaultDriftInterval {
+		t.Errorf("Reconcile requeued after %v, want %v", result.RequeueAfter, DefaultDriftInterval)
+	}
+
+	if z := server.Zone("testzone1.at"); z == nil || z.Type != "MASTER" {
+		t.Fatalf("Reconcile did not create the zone: %+v", z)
+	}
+
+	var got v1alpha1.DNSZone
+	_ = k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testzone1"}, &got)
+
+	if len(got.Finalizers) != 1 || got.Finalizers[0] != v1alpha1.Finalizer {
+		t.Errorf("Reconcile set finalizers %v", got.Finalizers)
+	}
+
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionReady) || got.Status.Serial != 1 {
+		t.Errorf("Reconcile set status %+v", got.Status)
+	}
+
+	// drift: the zone was changed to a slave outside of Kubernetes
+	_, _ = server.Client().Zones.Edit("testzone1.at", &rc0go.ZoneEdit{Type: "SLAVE", Masters: []string{"193.0.2.2"}})
+	server.SetDNSSECStatus("testzone1.at", "yes")
+
+	reconcile(t, reconciler, "testzone1")
+
+	if z := server.Zone("testzone1.at"); z.Type != "MASTER" {
+		t.Errorf("Reconcile did not revert the drift: %+v", z)
+	}
+
+	_ = k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testzone1"}, &got)
+
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, v1alpha1.ConditionDNSSEC) {
+		t.Errorf("Reconcile did not reflect the DNSSEC status: %+v", got.Status.Conditions)
+	}
+
+	// delete
+	_ = k8s.Delete(context.Background(), &got)
+
+	reconcile(t, reconciler, "testzone1")
+
+	if z := server.Zone("testzone1.at"); z != nil {
+		t.Errorf("Reconcile did not delete the zone: %+v", z)
+	}
+
+	if err := k8s.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testzone1"}, &got); err == nil {
+		t.Errorf("Reconcile did not remove the finalizer")
+	}
+}
+
+func TestDNSZoneReconciler_Retain(t *testing.T) {
+
+	server := rc0test.NewServer()
+	defer server.Close()
+
+	server.AddZone("testzone1.at")
+
+	zone := &v1alpha1.DNSZone{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "testzone1", Finalizers: []string{v1alpha1.Finalizer}},
+		Spec:       v1alpha1.DNSZoneSpec{Domain: "testzone1.at", Type: "MASTER", DeletionPolicy: v1alpha1.DeletionPolicyRetain},
+	}
+
+	k8s := newFakeClient(zone)
+	_ = k8s.Delete(context.Background(), zone)
+
+	reconcile(t, &DNSZoneReconciler{Client: k8s, Zones: server.Client().Zones}, "testzone1")
+
+	if server.Zone("testzone1.at") == nil {
+		t.Errorf("Reconcile deleted a zone with deletion policy Retain")
+	}
+}
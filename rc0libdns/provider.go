@@ -0,0 +1,520 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package rc0libdns implements the libdns interfaces (https://github.com/libdns/libdns)
+// for rcode0, f.e. to be used by Caddy.
+package rc0libdns
+
+import (
+	"context"
+	"fmt"
+	"github.com/libdns/libdns"
+	"github.com/nic-at/rc0go"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTTL = 3600
+
+// Provider manages the records of rcode0 zones through the libdns interfaces.
+// rcode0 replaces whole rrsets on every change, so all operations read the
+// current rrsets first and merge the records to keep the rest of the set intact.
+type Provider struct {
+
+	// APIToken is the rcode0 API token (only used if no client is given)
+	APIToken string `json:"api_token,omitempty"`
+
+	// BaseURL overrides the rcode0 API base URL, f.e. for the test system
+	BaseURL string `json:"base_url,omitempty"`
+
+	client *rc0go.Client
+
+	// Serializes the read-modify-write of the rrsets
+	mu sync.Mutex
+}
+
+// NewProvider returns a provider using the given client
+func NewProvider(client *rc0go.Client) *Provider {
+	return &Provider{client: client}
+}
+
+var (
+	_ libdns.RecordGetter   = (*Provider)(nil)
+	_ libdns.RecordAppender = (*Provider)(nil)
+	_ libdns.RecordSetter   = (*Provider)(nil)
+	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
+)
+
+// GetRecords returns all enabled records of the zone
+func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	rrsets, err := client.RRSet.ListAll(ctx, rc0Zone(zone))
+	if err != nil {
+		return nil, err
+	}
+
+	var records []libdns.Record
+
+	for _, rrset := range rrsets {
+		for _, r := range rrset.Records {
+			if r.Disabled {
+				continue
+			}
+
+			record, err := toLibdnsRecord(zone, rrset, r.Content)
+			if err != nil {
+				return nil, err
+			}
+
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// AppendRecords adds the records to their rrsets. Existing records of the rrsets are kept;
+// a disabled record with the same content is enabled.
+func (p *Provider) AppendRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+
+	var appended []libdns.Record
+
+	err := p.modify(ctx, zone, recs, false, func(current []*rc0go.Record, group []libdns.Record, contents []string) []*rc0go.Record {
+		for i, content := range contents {
+			rrType := group[i].Type
+			switch j := indexOf(current, rrType, content); {
+			case j < 0:
+				current = append(current, &rc0go.Record{Content: content})
+			case current[j].Disabled:
+				current[j].Disabled = false
+			default:
+				continue
+			}
+			appended = append(appended, group[i])
+		}
+		return current
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return appended, nil
+}
+
+// SetRecords replaces the enabled records of the rrsets of the given records with exactly
+// these records. Disabled records and other rrsets are not touched.
+func (p *Provider) SetRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+
+	err := p.modify(ctx, zone, recs, false, func(current []*rc0go.Record, group []libdns.Record, contents []string) []*rc0go.Record {
+		var set []*rc0go.Record
+		for i, content := range contents {
+			if indexOf(set, group[i].Type, content) < 0 {
+				set = append(set, &rc0go.Record{Content: content})
+			}
+		}
+		for _, r := range current {
+			if r.Disabled && indexOf(set, group[0].Type, r.Content) < 0 {
+				set = append(set, r)
+			}
+		}
+		return set
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return recs, nil
+}
+
+// DeleteRecords removes the records from their rrsets. A record with an empty value removes
+// all enabled records of its name and type. Disabled records are kept and rrsets which end up
+// empty are deleted. The TTLs of the given records are ignored, remaining records keep the
+// TTL of their rrset.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, recs []libdns.Record) ([]libdns.Record, error) {
+
+	var deleted []libdns.Record
+
+	err := p.modify(ctx, zone, recs, true, func(current []*rc0go.Record, group []libdns.Record, contents []string) []*rc0go.Record {
+		var kept []*rc0go.Record
+		for _, r := range current {
+			match := -1
+			for i, g := range group {
+				if !r.Disabled && (g.Value == "" || sameContent(g.Type, contents[i], r.Content)) {
+					match = i
+					break
+				}
+			}
+			if match < 0 {
+				kept = append(kept, r)
+				continue
+			}
+			record := group[match]
+			record.Value = r.Content
+			deleted = append(deleted, record)
+		}
+		return kept
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// report the values as libdns records
+	for i, record := range deleted {
+		rrset := &rc0go.RRType{Name: absoluteName(record.Name, zone), Type: record.Type}
+		if deleted[i], err = toLibdnsRecord(zone, rrset, record.Value); err != nil {
+			return nil, err
+		}
+		deleted[i].TTL = record.TTL
+	}
+
+	return deleted, nil
+}
+
+// ListZones returns all zones of the account
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+
+	client, err := p.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := rc0go.ZoneNames(ctx, client.Zones)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []libdns.Zone
+
+	for _, name := range names {
+		zones = append(zones, libdns.Zone{Name: fqdn(name)})
+	}
+
+	return zones, nil
+}
+
+type rrsetKey struct {
+	name   string
+	rrType string
+}
+
+// modify groups the records by rrset, computes the new contents of each rrset with update
+// and submits the resulting change set at once. With keepTTL the TTLs of the given records
+// are ignored for existing rrsets, f.e. when records are deleted.
+func (p *Provider) modify(ctx context.Context, zone string, recs []libdns.Record, keepTTL bool,
+	update func(current []*rc0go.Record, group []libdns.Record, contents []string) []*rc0go.Record) error {
+
+	client, err := p.getClient()
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[rrsetKey][]libdns.Record)
+	var keys []rrsetKey
+
+	for _, r := range recs {
+		key := rrsetKey{
+			name:   absoluteName(r.Name, zone),
+			rrType: strings.ToUpper(r.Type),
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], r)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].rrType < keys[j].rrType
+	})
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	rrsets, err := client.RRSet.ListAll(ctx, rc0Zone(zone))
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[rrsetKey]*rc0go.RRType)
+	for _, rrset := range rrsets {
+		existing[rrsetKey{rc0go.NormalizeName(rrset.Name), strings.ToUpper(rrset.Type)}] = rrset
+	}
+
+	var changeSet []*rc0go.RRSetChange
+
+	for _, key := range keys {
+
+		group := groups[key]
+
+		contents := make([]string, len(group))
+		for i, r := range group {
+			if r.Value == "" {
+				continue
+			}
+			if contents[i], err = toContent(r); err != nil {
+				return err
+			}
+		}
+
+		var current []*rc0go.Record
+		ttl := 0
+
+		rrset := existing[key]
+		if rrset != nil {
+			ttl = rrset.TTL
+			current = rrset.Records
+		}
+
+		for _, r := range group {
+			if r.TTL > 0 && !(keepTTL && rrset != nil) {
+				ttl = int(r.TTL / time.Second)
+				break
+			}
+		}
+
+		if ttl <= 0 {
+			ttl = defaultTTL
+		}
+
+		updated := update(copyRecords(current), group, contents)
+
+		change := &rc0go.RRSetChange{Name: key.name, Type: key.rrType, TTL: ttl}
+
+		switch {
+		case len(updated) == 0 && rrset == nil:
+			continue
+		case len(updated) == 0:
+			change.ChangeType = rc0go.ChangeTypeDELETE
+		case rrset == nil:
+			change.ChangeType = rc0go.ChangeTypeADD
+		case equal(key.rrType, current, updated) && ttl == rrset.TTL:
+			continue
+		default:
+			change.ChangeType = rc0go.ChangeTypeUPDATE
+		}
+
+		if change.ChangeType != rc0go.ChangeTypeDELETE {
+			change.Records = updated
+		}
+
+		changeSet = append(changeSet, change)
+	}
+
+	if len(changeSet) == 0 {
+		return nil
+	}
+
+	status, err := client.RRSet.SubmitChangeSet(rc0Zone(zone), changeSet)
+	if err != nil {
+		return err
+	}
+
+	if status.HasError() {
+		return fmt.Errorf("zone %s: %s", rc0Zone(zone), status.Message)
+	}
+
+	return nil
+}
+
+func (p *Provider) getClient() (*rc0go.Client, error) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client != nil {
+		return p.client, nil
+	}
+
+	if p.APIToken == "" {
+		return nil, fmt.Errorf("rcode0 API token missing")
+	}
+
+	client, err := rc0go.NewClient(p.APIToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.BaseURL != "" {
+		if client.BaseURL, err = url.Parse(p.BaseURL); err != nil {
+			return nil, err
+		}
+	}
+
+	p.client = client
+
+	return client, nil
+}
+
+// toLibdnsRecord converts the rcode0 record content to a libdns record. The priority and
+// weight of MX, SRV, URI and HTTPS records are moved to their own fields.
+func toLibdnsRecord(zone string, rrset *rc0go.RRType, content string) (libdns.Record, error) {
+
+	record := libdns.Record{
+		Type:  rrset.Type,
+		Name:  relativeName(rrset.Name, zone),
+		Value: content,
+		TTL:   time.Duration(rrset.TTL) * time.Second,
+	}
+
+	var err error
+
+	switch strings.ToUpper(rrset.Type) {
+
+	case "TXT":
+		record.Value, err = rc0go.UnquoteTXT(content)
+
+	case "MX", "HTTPS":
+		record.Priority, record.Value, err = splitUint(content)
+
+	case "SRV", "URI":
+		if record.Priority, record.Value, err = splitUint(content); err == nil {
+			record.Weight, record.Value, err = splitUint(record.Value)
+		}
+	}
+
+	if err != nil {
+		return libdns.Record{}, fmt.Errorf("rrset %s %s: %v", rrset.Name, rrset.Type, err)
+	}
+
+	return record, nil
+}
+
+// toContent converts the libdns record to rcode0 record content
+func toContent(record libdns.Record) (string, error) {
+
+	value := record.Value
+
+	switch strings.ToUpper(record.Type) {
+
+	case "TXT":
+		return rc0go.QuoteTXT(value), nil
+
+	case "MX", "HTTPS":
+		value = fmt.Sprintf("%d %s", record.Priority, value)
+
+	case "SRV", "URI":
+		value = fmt.Sprintf("%d %d %s", record.Priority, record.Weight, value)
+	}
+
+	return value, rc0go.ValidateContent(strings.ToUpper(record.Type), value)
+}
+
+// splitUint splits the leading unsigned integer field from the content
+func splitUint(content string) (uint, string, error) {
+
+	fields := strings.SplitN(strings.TrimSpace(content), " ", 2)
+	if len(fields) != 2 {
+		return 0, "", fmt.Errorf("invalid content %q", content)
+	}
+
+	n, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid content %q", content)
+	}
+
+	return uint(n), strings.TrimSpace(fields[1]), nil
+}
+
+// relativeName returns the name relative to the zone, "@" for the apex
+func relativeName(name string, zone string) string {
+
+	name = rc0go.NormalizeName(name)
+	origin := rc0go.NormalizeName(zone)
+
+	if name == origin {
+		return "@"
+	}
+
+	if strings.HasSuffix(name, "."+origin) {
+		return strings.TrimSuffix(name, "."+origin)
+	}
+
+	return name
+}
+
+// absoluteName returns the fully qualified name. Names with trailing dot are already absolute.
+func absoluteName(name string, zone string) string {
+
+	if strings.HasSuffix(name, ".") {
+		return rc0go.NormalizeName(name)
+	}
+
+	return rc0go.NormalizeName(libdns.AbsoluteName(name, fqdn(zone)))
+}
+
+// rc0Zone returns the zone name as used by the rcode0 API (without trailing dot)
+func rc0Zone(zone string) string {
+	return strings.TrimSuffix(zone, ".")
+}
+
+func fqdn(zone string) string {
+	return rc0go.NormalizeName(zone)
+}
+
+// sameContent compares record contents after normalization (see rc0go.NormalizeContent).
+// TXT contents are compared by their text, independent of quoting and chunking.
+func sameContent(rrType string, a string, b string) bool {
+
+	if t := strings.ToUpper(rrType); t == "TXT" || t == "SPF" {
+		textA, errA := rc0go.UnquoteTXT(a)
+		textB, errB := rc0go.UnquoteTXT(b)
+		if errA == nil && errB == nil {
+			return textA == textB
+		}
+	}
+
+	return rc0go.NormalizeContent(rrType, a) == rc0go.NormalizeContent(rrType, b)
+}
+
+// indexOf returns the index of the record with the content or -1
+func indexOf(records []*rc0go.Record, rrType string, content string) int {
+	for i, r := range records {
+		if sameContent(rrType, r.Content, content) {
+			return i
+		}
+	}
+	return -1
+}
+
+func copyRecords(records []*rc0go.Record) []*rc0go.Record {
+
+	copies := make([]*rc0go.Record, len(records))
+	for i, r := range records {
+		copies[i] = &rc0go.Record{Content: r.Content, Disabled: r.Disabled}
+	}
+
+	return copies
+}
+
+// equal reports whether both lists hold the same records in the same order
+func equal(rrType string, a []*rc0go.Record, b []*rc0go.Record) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Disabled != b[i].Disabled || !sameContent(rrType, a[i].Content, b[i].Content) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,322 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0libdns
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/libdns/libdns"
+	"github.com/nic-at/rc0go"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAPI serves the rrsets of the zone testzone1.at like the rcode0 API
+type fakeAPI struct {
+	mu      sync.Mutex
+	rrsets  []*rc0go.RRType
+	patches [][]*rc0go.RRSetChange
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	page := map[string]interface{}{"current_page": 1, "last_page": 1}
+
+	switch {
+
+	case r.URL.Path == "/api/v1/zones":
+		page["data"] = []interface{}{map[string]interface{}{"domain": "testzone1.at", "type": "MASTER"}}
+
+	case r.URL.Path == "/api/v1/zones/testzone1.at/rrsets" && r.Method == "GET":
+		page["data"] = f.rrsets
+
+	case r.URL.Path == "/api/v1/zones/testzone1.at/rrsets" && r.Method == "PATCH":
+		var changeSet []*rc0go.RRSetChange
+		body, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &changeSet)
+		f.patches = append(f.patches, changeSet)
+		f.apply(changeSet)
+		_, _ = fmt.Fprint(w, `{"status": "ok", "message": "RRsets updated"}`)
+		return
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	dat, _ := json.Marshal(page)
+	_, _ = w.Write(dat)
+}
+
+func (f *fakeAPI) apply(changeSet []*rc0go.RRSetChange) {
+
+	for _, change := range changeSet {
+
+		var kept []*rc0go.RRType
+		for _, rrset := range f.rrsets {
+			if !strings.EqualFold(rrset.Name, change.Name) || rrset.Type != change.Type {
+				kept = append(kept, rrset)
+			}
+		}
+
+		if change.ChangeType != rc0go.ChangeTypeDELETE {
+			kept = append(kept, &rc0go.RRType{Name: change.Name, Type: change.Type, TTL: change.TTL, Records: change.Records})
+		}
+
+		f.rrsets = kept
+	}
+}
+
+func setup(rrsets ...*rc0go.RRType) (*Provider, *fakeAPI, func()) {
+
+	api := &fakeAPI{rrsets: rrsets}
+	server := httptest.NewServer(api)
+
+	client, _ := rc0go.NewClient("test123")
+	client.BaseURL, _ = url.Parse(server.URL + "/api/")
+
+	return NewProvider(client), api, server.Close
+}
+
+func TestProvider_GetRecords(t *testing.T) {
+
+	provider, _, teardown := setup(
+		&rc0go.RRType{Name: "testzone1.at.", Type: "MX", TTL: 3600, Records: []*rc0go.Record{{Content: "10 mail.testzone1.at."}}},
+		&rc0go.RRType{Name: "www.testzone1.at.", Type: "TXT", TTL: 300, Records: []*rc0go.Record{{Content: `"hello world"`}, {Content: `"off"`, Disabled: true}}},
+		&rc0go.RRType{Name: "_sip._tcp.testzone1.at.", Type: "SRV", TTL: 60, Records: []*rc0go.Record{{Content: "10 20 5060 sip.testzone1.at."}}},
+	)
+	defer teardown()
+
+	records, err := provider.GetRecords(context.Background(), "testzone1.at.")
+	if err != nil {
+		t.Fatalf("Provider.GetRecords returned error: %v", err)
+	}
+
+	want := []libdns.Record{
+		{Type: "MX", Name: "@", Value: "mail.testzone1.at.", TTL: time.Hour, Priority: 10},
+		{Type: "TXT", Name: "www", Value: "hello world", TTL: 5 * time.Minute},
+		{Type: "SRV", Name: "_sip._tcp", Value: "5060 sip.testzone1.at.", TTL: time.Minute, Priority: 10, Weight: 20},
+	}
+
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("Provider.GetRecords returned %+v, want %+v", records, want)
+	}
+}
+
+func TestProvider_AppendRecords(t *testing.T) {
+
+	provider, api, teardown := setup(
+		&rc0go.RRType{Name: "_acme-challenge.testzone1.at.", Type: "TXT", TTL: 60, Records: []*rc0go.Record{{Content: `"first"`}}},
+	)
+	defer teardown()
+
+	appended, err := provider.AppendRecords(context.Background(), "testzone1.at.", []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "first"},
+		{Type: "TXT", Name: "_acme-challenge", Value: "second"},
+		{Type: "A", Name: "www", Value: "10.10.0.1", TTL: 5 * time.Minute},
+	})
+
+	if err != nil {
+		t.Fatalf("Provider.AppendRecords returned error: %v", err)
+	}
+
+	if len(appended) != 2 {
+		t.Errorf("Provider.AppendRecords appended %d records, want 2", len(appended))
+	}
+
+	want := []*rc0go.RRSetChange{
+		{Name: "_acme-challenge.testzone1.at.", Type: "TXT", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 60, Records: []*rc0go.Record{{Content: `"first"`}, {Content: `"second"`}}},
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeADD, TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.1"}}},
+	}
+
+	if len(api.patches) != 1 || !reflect.DeepEqual(api.patches[0], want) {
+		t.Errorf("Provider.AppendRecords submitted %+v, want %+v", api.patches, want)
+	}
+}
+
+func TestProvider_SetRecords(t *testing.T) {
+
+	provider, api, teardown := setup(
+		&rc0go.RRType{Name: "www.testzone1.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.1"}, {Content: "10.10.0.2"}}},
+		&rc0go.RRType{Name: "www.testzone1.at.", Type: "AAAA", TTL: 300, Records: []*rc0go.Record{{Content: "2001:db8::1"}}},
+	)
+	defer teardown()
+
+	_, err := provider.SetRecords(context.Background(), "testzone1.at.", []libdns.Record{
+		{Type: "A", Name: "www.testzone1.at.", Value: "10.10.0.3"},
+	})
+
+	if err != nil {
+		t.Fatalf("Provider.SetRecords returned error: %v", err)
+	}
+
+	want := []*rc0go.RRSetChange{
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.3"}}},
+	}
+
+	if len(api.patches) != 1 || !reflect.DeepEqual(api.patches[0], want) {
+		t.Errorf("Provider.SetRecords submitted %+v, want %+v", api.patches, want)
+	}
+
+	if len(api.rrsets) != 2 {
+		t.Errorf("Provider.SetRecords touched other rrsets: %+v", api.rrsets)
+	}
+}
+
+func TestProvider_DeleteRecords(t *testing.T) {
+
+	provider, api, teardown := setup(
+		&rc0go.RRType{Name: "testzone1.at.", Type: "MX", TTL: 3600, Records: []*rc0go.Record{{Content: "10 mx1.testzone1.at."}, {Content: "20 mx2.testzone1.at."}}},
+		&rc0go.RRType{Name: "_acme-challenge.testzone1.at.", Type: "TXT", TTL: 60, Records: []*rc0go.Record{{Content: `"token"`}}},
+	)
+	defer teardown()
+
+	deleted, err := provider.DeleteRecords(context.Background(), "testzone1.at.", []libdns.Record{
+		{Type: "MX", Name: "@", Value: "mx1.testzone1.at.", Priority: 10, TTL: time.Minute},
+		{Type: "TXT", Name: "_acme-challenge"},
+		{Type: "A", Name: "missing", Value: "10.10.0.1"},
+	})
+
+	if err != nil {
+		t.Fatalf("Provider.DeleteRecords returned error: %v", err)
+	}
+
+	wantDeleted := []libdns.Record{
+		{Type: "TXT", Name: "_acme-challenge", Value: "token"},
+		{Type: "MX", Name: "@", Value: "mx1.testzone1.at.", Priority: 10, TTL: time.Minute},
+	}
+
+	if !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("Provider.DeleteRecords returned %+v, want %+v", deleted, wantDeleted)
+	}
+
+	want := []*rc0go.RRSetChange{
+		{Name: "_acme-challenge.testzone1.at.", Type: "TXT", ChangeType: rc0go.ChangeTypeDELETE, TTL: 60},
+		{Name: "testzone1.at.", Type: "MX", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 3600, Records: []*rc0go.Record{{Content: "20 mx2.testzone1.at."}}},
+	}
+
+	if len(api.patches) != 1 || !reflect.DeepEqual(api.patches[0], want) {
+		t.Errorf("Provider.DeleteRecords submitted %+v, want %+v", api.patches, want)
+	}
+}
+
+func TestProvider_ListZones(t *testing.T) {
+
+	provider, _, teardown := setup()
+	defer teardown()
+
+	zones, err := provider.ListZones(context.Background())
+	if err != nil {
+		t.Fatalf("Provider.ListZones returned error: %v", err)
+	}
+
+	if want := []libdns.Zone{{Name: "testzone1.at."}}; !reflect.DeepEqual(zones, want) {
+		t.Errorf("Provider.ListZones returned %+v, want %+v", zones, want)
+	}
+}
+
+func TestProvider_KeepsDisabledRecords(t *testing.T) {
+
+	rrsets := func() []*rc0go.RRType {
+		return []*rc0go.RRType{
+			{Name: "www.testzone1.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.1"}, {Content: "10.10.0.9", Disabled: true}}},
+			{Name: "testzone1.at.", Type: "MX", TTL: 3600, Records: []*rc0go.Record{{Content: "10 MX1.testzone1.at."}, {Content: "20 mx2.testzone1.at.", Disabled: true}}},
+			{Name: "testzone1.at.", Type: "TXT", TTL: 3600, Records: []*rc0go.Record{{Content: `"v=spf1 " "-all"`}}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(p *Provider) error
+		want   []*rc0go.RRSetChange
+	}{
+		{
+			name: "append",
+			modify: func(p *Provider) error {
+				_, err := p.AppendRecords(context.Background(), "testzone1.at.", []libdns.Record{
+					{Type: "A", Name: "www", Value: "10.10.0.2"},
+					{Type: "MX", Name: "@", Value: "mx1.testzone1.at.", Priority: 10},
+					{Type: "TXT", Name: "@", Value: "v=spf1 -all"},
+				})
+				return err
+			},
+			want: []*rc0go.RRSetChange{
+				{Name: "www.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.1"}, {Content: "10.10.0.9", Disabled: true}, {Content: "10.10.0.2"}}},
+			},
+		},
+		{
+			name: "append enables",
+			modify: func(p *Provider) error {
+				_, err := p.AppendRecords(context.Background(), "testzone1.at.", []libdns.Record{
+					{Type: "A", Name: "www", Value: "10.10.0.9"},
+				})
+				return err
+			},
+			want: []*rc0go.RRSetChange{
+				{Name: "www.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.1"}, {Content: "10.10.0.9"}}},
+			},
+		},
+		{
+			name: "set",
+			modify: func(p *Provider) error {
+				_, err := p.SetRecords(context.Background(), "testzone1.at.", []libdns.Record{
+					{Type: "A", Name: "www", Value: "10.10.0.3"},
+				})
+				return err
+			},
+			want: []*rc0go.RRSetChange{
+				{Name: "www.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.3"}, {Content: "10.10.0.9", Disabled: true}}},
+			},
+		},
+		{
+			name: "delete",
+			modify: func(p *Provider) error {
+				_, err := p.DeleteRecords(context.Background(), "testzone1.at.", []libdns.Record{
+					{Type: "MX", Name: "@", Value: "mx1.TESTZONE1.at.", Priority: 10},
+					{Type: "TXT", Name: "@", Value: "v=spf1 -all"},
+				})
+				return err
+			},
+			want: []*rc0go.RRSetChange{
+				{Name: "testzone1.at.", Type: "MX", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 3600, Records: []*rc0go.Record{{Content: "20 mx2.testzone1.at.", Disabled: true}}},
+				{Name: "testzone1.at.", Type: "TXT", ChangeType: rc0go.ChangeTypeDELETE, TTL: 3600},
+			},
+		},
+	}
+
+	for _, test := range tests {
+
+		provider, api, teardown := setup(rrsets()...)
+
+		if err := test.modify(provider); err != nil {
+			t.Errorf("%s returned error: %v", test.name, err)
+		}
+
+		if len(api.patches) != 1 || !reflect.DeepEqual(api.patches[0], test.want) {
+			var got []*rc0go.RRSetChange
+			if len(api.patches) > 0 {
+				got = api.patches[0]
+			}
+			t.Errorf("%s submitted %d change sets:", test.name, len(api.patches))
+			for _, c := range got {
+				t.Errorf("  %+v %+v", c, c.Records)
+			}
+		}
+
+		teardown()
+	}
+}
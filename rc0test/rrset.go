@@ -0,0 +1,32 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0test
+
+import (
+	"github.com/nic-at/rc0go"
+)
+
+// RRSet returns an rrset with a TTL of 3600 and one record per content,
+// f.e. RRSet("www.example.at.", "A", "192.0.2.1")
+func RRSet(name string, rrType string, contents ...string) *rc0go.RRType {
+
+	rrset := &rc0go.RRType{Name: name, Type: rrType, TTL: 3600}
+
+	for _, content := range contents {
+		rrset.Records = append(rrset.Records, &rc0go.Record{Content: content})
+	}
+
+	return rrset
+}
+
+// TXTRRSet returns a TXT rrset with a TTL of 3600 and one quoted record per text
+func TXTRRSet(name string, texts ...string) *rc0go.RRType {
+
+	rrset := &rc0go.RRType{Name: name, Type: "TXT", TTL: 3600}
+	rrset.AddTXT(texts...)
+
+	return rrset
+}
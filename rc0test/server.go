@@ -0,0 +1,369 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package rc0test provides an in-memory stand-in for the rcode0 API to test code using
+// rc0go offline.
+package rc0test
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/nic-at/rc0go"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server is a httptest server answering the zone and rrset endpoints of the rcode0 API
+type Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	zones   map[string]*zone
+	patches map[string][][]*rc0go.RRSetChange
+}
+
+type zone struct {
+	info   rc0go.Zone
+	rrsets []*rc0go.RRType
+}
+
+// NewServer starts a server without zones. Call Close when done.
+func NewServer() *Server {
+
+	s := &Server{
+		zones:   make(map[string]*zone),
+		patches: make(map[string][][]*rc0go.RRSetChange),
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1"+rc0go.RC0Zones, s.listZones).Methods("GET")
+	router.HandleFunc("/api/v1"+rc0go.RC0Zones, s.createZone).Methods("POST")
+	router.HandleFunc("/api/v1"+rc0go.RC0Zone, s.getZone).Methods("GET")
+	router.HandleFunc("/api/v1"+rc0go.RC0Zone, s.editZone).Methods("PUT")
+	router.HandleFunc("/api/v1"+rc0go.RC0Zone, s.deleteZone).Methods("DELETE")
+	router.HandleFunc("/api/v1"+rc0go.RC0ZoneRRSets, s.listRRSets).Methods("GET")
+	router.HandleFunc("/api/v1"+rc0go.RC0ZoneRRSets, s.patchRRSets).Methods("PATCH")
+
+	s.Server = httptest.NewServer(router)
+
+	return s
+}
+
+// Client returns a rc0go client talking to the server
+func (s *Server) Client() *rc0go.Client {
+
+	client, _ := rc0go.NewClient("rc0test")
+	client.BaseURL, _ = url.Parse(s.URL + "/api/")
+
+	return client
+}
+
+// AddZone adds a master zone (f.e. "example.at") with the given rrsets
+func (s *Server) AddZone(name string, rrsets ...*rc0go.RRType) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.zones[zoneName(name)] = &zone{
+		info:   rc0go.Zone{Domain: zoneName(name), Type: "MASTER", Serial: 1},
+		rrsets: rrsets,
+	}
+}
+
+// Zone returns the zone details, nil if the zone does not exist
+func (s *Server) Zone(name string) *rc0go.Zone {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z, ok := s.zones[zoneName(name)]
+	if !ok {
+		return nil
+	}
+
+	info := z.info
+	return &info
+}
+
+// SetDNSSECStatus sets the DNSSEC status of the zone, f.e. "yes"
+func (s *Server) SetDNSSECStatus(name string, status string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if z, ok := s.zones[zoneName(name)]; ok {
+		z.info.DNSSECStatus = status
+	}
+}
+
+// RRSets returns the current rrsets of the zone sorted by name and type
+func (s *Server) RRSets(zone string) []*rc0go.RRType {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var rrsets []*rc0go.RRType
+	if z, ok := s.zones[zoneName(zone)]; ok {
+		rrsets = append(rrsets, z.rrsets...)
+	}
+
+	sort.Slice(rrsets, func(i, j int) bool {
+		if rrsets[i].Name != rrsets[j].Name {
+			return rrsets[i].Name < rrsets[j].Name
+		}
+		return rrsets[i].Type < rrsets[j].Type
+	})
+
+	return rrsets
+}
+
+// Patches returns the change sets submitted for the zone
+func (s *Server) Patches(zone string) [][]*rc0go.RRSetChange {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.patches[zoneName(zone)]
+}
+
+func (s *Server) listZones(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for zone := range s.zones {
+		names = append(names, zone)
+	}
+	sort.Strings(names)
+
+	data := make([]interface{}, len(names))
+	for i, name := range names {
+		info := s.zones[name].info
+		data[i] = &info
+	}
+
+	writePage(w, r, data)
+}
+
+func (s *Server) getZone(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z, ok := s.zones[zoneName(mux.Vars(r)["zone"])]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	writeJSON(w, &z.info)
+}
+
+func (s *Server) createZone(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var create rc0go.ZoneCreate
+	if err := json.NewDecoder(r.Body).Decode(&create); err != nil || create.Domain == "" {
+		writeStatus(w, http.StatusBadRequest, "invalid zone")
+		return
+	}
+
+	name := zoneName(create.Domain)
+
+	if _, ok := s.zones[name]; ok {
+		writeStatus(w, http.StatusConflict, "Zone already exists")
+		return
+	}
+
+	s.zones[name] = &zone{info: rc0go.Zone{Domain: name, Type: strings.ToUpper(create.Type), Masters: create.Masters, Serial: 1}}
+
+	writeStatus(w, http.StatusCreated, "")
+}
+
+func (s *Server) editZone(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z, ok := s.zones[zoneName(mux.Vars(r)["zone"])]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	var edit rc0go.ZoneEdit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	z.info.Type = strings.ToUpper(edit.Type)
+	z.info.Masters = edit.Masters
+
+	writeStatus(w, http.StatusOK, "")
+}
+
+func (s *Server) deleteZone(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := zoneName(mux.Vars(r)["zone"])
+
+	if _, ok := s.zones[name]; !ok {
+		writeNotFound(w)
+		return
+	}
+
+	delete(s.zones, name)
+
+	writeStatus(w, http.StatusOK, "")
+}
+
+func (s *Server) listRRSets(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	z, ok := s.zones[zoneName(mux.Vars(r)["zone"])]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	data := make([]interface{}, len(z.rrsets))
+	for i, rrset := range z.rrsets {
+		data[i] = rrset
+	}
+
+	writePage(w, r, data)
+}
+
+// patchRRSets applies the change set. Added and updated rrsets replace existing ones.
+func (s *Server) patchRRSets(w http.ResponseWriter, r *http.Request) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name := zoneName(mux.Vars(r)["zone"])
+
+	z, ok := s.zones[name]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+
+	var changeSet []*rc0go.RRSetChange
+	if err := json.Unmarshal(body, &changeSet); err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.patches[name] = append(s.patches[name], changeSet)
+
+	rrsets := z.rrsets
+
+	for _, change := range changeSet {
+
+		var kept []*rc0go.RRType
+		for _, rrset := range rrsets {
+			if !strings.EqualFold(rrset.Name, change.Name) || !strings.EqualFold(rrset.Type, change.Type) {
+				kept = append(kept, rrset)
+			}
+		}
+
+		if change.ChangeType != rc0go.ChangeTypeDELETE {
+			kept = append(kept, &rc0go.RRType{Name: change.Name, Type: change.Type, TTL: change.TTL, Records: change.Records})
+		}
+
+		rrsets = kept
+	}
+
+	z.rrsets = rrsets
+	z.info.Serial++
+
+	writeStatus(w, http.StatusOK, "RRsets updated")
+}
+
+// writePage writes the requested page of the data like the paginated rcode0 endpoints
+func writePage(w http.ResponseWriter, r *http.Request, data []interface{}) {
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	current, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if current <= 0 {
+		current = 1
+	}
+
+	last := (len(data) + pageSize - 1) / pageSize
+	if last == 0 {
+		last = 1
+	}
+
+	from := (current - 1) * pageSize
+	to := from + pageSize
+
+	if from > len(data) {
+		from = len(data)
+	}
+	if to > len(data) {
+		to = len(data)
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"data":         data[from:to],
+		"current_page": current,
+		"last_page":    last,
+		"per_page":     pageSize,
+		"from":         from + 1,
+		"to":           to,
+		"total":        len(data),
+	})
+}
+
+// writeStatus writes a status response, failed for status codes of 400 and above
+func writeStatus(w http.ResponseWriter, code int, message string) {
+
+	status := "ok"
+	if code >= http.StatusBadRequest {
+		status = "failed"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	dat, _ := json.Marshal(&rc0go.StatusResponse{Status: status, Message: message})
+	_, _ = w.Write(dat)
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeStatus(w, http.StatusNotFound, "Zone not found")
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+
+	dat, _ := json.Marshal(v)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(dat)
+}
+
+func zoneName(zone string) string {
+	return strings.ToLower(strings.TrimSuffix(zone, "."))
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0test
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+
+	server := NewServer()
+	defer server.Close()
+
+	var rrsets []*rc0go.RRType
+	for i := 0; i < 250; i++ {
+		rrsets = append(rrsets, &rc0go.RRType{
+			Name:    fmt.Sprintf("host%d.testzone1.at.", i),
+			Type:    "A",
+			TTL:     300,
+			Records: []*rc0go.Record{{Content: "10.10.0.1"}},
+		})
+	}
+
+	server.AddZone("testzone1.at", rrsets...)
+
+	client := server.Client()
+
+	zone, err := client.Zones.Get("testzone1.at")
+	if err != nil || zone.Domain != "testzone1.at" {
+		t.Errorf("Zones.Get returned %+v, %v", zone, err)
+	}
+
+	all, err := client.RRSet.ListAll(context.Background(), "testzone1.at")
+	if err != nil {
+		t.Fatalf("RRSet.ListAll returned error: %v", err)
+	}
+
+	if len(all) != 250 {
+		t.Errorf("RRSet.ListAll returned %d rrsets, want 250", len(all))
+	}
+
+	for i := 2; i <= 150; i++ {
+		server.AddZone(fmt.Sprintf("testzone%d.at", i))
+	}
+
+	zones, err := client.Zones.ListAll(context.Background())
+	if err != nil || len(zones) != 150 {
+		t.Errorf("Zones.ListAll returned %d zones, %v, want 150", len(zones), err)
+	}
+
+	status, err := client.RRSet.SubmitChangeSet("testzone1.at", []*rc0go.RRSetChange{
+		{Name: "host0.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeDELETE},
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeADD, TTL: 60, Records: []*rc0go.Record{{Content: "10.10.0.80"}}},
+	})
+
+	if err != nil || status.HasError() {
+		t.Fatalf("RRSet.SubmitChangeSet returned %+v, %v", status, err)
+	}
+
+	if got := len(server.RRSets("testzone1.at")); got != 250 {
+		t.Errorf("Server has %d rrsets, want 250", got)
+	}
+
+	if got := len(server.Patches("testzone1.at")); got != 1 {
+		t.Errorf("Server recorded %d patches, want 1", got)
+	}
+
+	if _, err := client.RRSet.Get(context.Background(), "testzone1.at", "host0.testzone1.at.", "A"); !rc0go.IsRRSetNotFound(err) {
+		t.Errorf("RRSet.Get returned %v for a deleted rrset", err)
+	}
+}
@@ -0,0 +1,650 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxTXTStringLength is the maximum length of a single TXT character-string (RFC 1035, section 3.3)
+const maxTXTStringLength = 255
+
+// NewARecord returns an A record for the given IPv4 address
+func NewARecord(ip string) (*Record, error) {
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.To4() == nil || strings.Contains(ip, ":") {
+		return nil, fmt.Errorf("invalid IPv4 address %q", ip)
+	}
+
+	return &Record{Content: parsed.String()}, nil
+}
+
+// NewAAAARecord returns an AAAA record for the given IPv6 address. IPv4-mapped addresses
+// (f.e. "::ffff:192.0.2.1") are rejected, use an A record instead.
+func NewAAAARecord(ip string) (*Record, error) {
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil || !strings.Contains(ip, ":") {
+		return nil, fmt.Errorf("invalid IPv6 address %q", ip)
+	}
+
+	if parsed.To4() != nil {
+		return nil, fmt.Errorf("invalid IPv6 address %q: IPv4-mapped addresses belong in A records", ip)
+	}
+
+	return &Record{Content: parsed.String()}, nil
+}
+
+// NewCNAMERecord returns a CNAME record pointing to the given target
+func NewCNAMERecord(target string) (*Record, error) {
+	return newNameRecord(target)
+}
+
+// NewNSRecord returns an NS record for the given name server
+func NewNSRecord(nameserver string) (*Record, error) {
+	return newNameRecord(nameserver)
+}
+
+// NewPTRRecord returns a PTR record pointing to the given name
+func NewPTRRecord(target string) (*Record, error) {
+	return newNameRecord(target)
+}
+
+// NewMXRecord returns an MX record for the given preference and mail exchange
+func NewMXRecord(preference uint16, exchange string) (*Record, error) {
+
+	exchange, err := fqdn(exchange)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Record{Content: fmt.Sprintf("%d %s", preference, exchange)}, nil
+}
+
+// NewTXTRecord returns a TXT record with the given text, quoted and escaped as character-strings.
+// Text longer than 255 bytes is split into several character-strings (see QuoteTXT).
+func NewTXTRecord(text string) (*Record, error) {
+	return &Record{Content: QuoteTXT(text)}, nil
+}
+
+// NewSRVRecord returns an SRV record. Use "." as target to indicate that the service is not available.
+func NewSRVRecord(priority uint16, weight uint16, port uint16, target string) (*Record, error) {
+
+	target, err := fqdn(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Record{Content: fmt.Sprintf("%d %d %d %s", priority, weight, port, target)}, nil
+}
+
+// NewCAARecord returns a CAA record (f.e. flags 0, tag "issue" and value "letsencrypt.org")
+func NewCAARecord(flags uint8, tag string, value string) (*Record, error) {
+
+	if !isCAATag(tag) {
+		return nil, fmt.Errorf("invalid CAA tag %q", tag)
+	}
+
+	return &Record{Content: fmt.Sprintf("%d %s %s", flags, strings.ToLower(tag), quoteTXT(value))}, nil
+}
+
+// NewTLSARecord returns a TLSA record with the hex encoded certificate association data
+func NewTLSARecord(usage uint8, selector uint8, matchingType uint8, data string) (*Record, error) {
+
+	if !isHex(data) {
+		return nil, fmt.Errorf("invalid TLSA certificate association data %q", data)
+	}
+
+	return &Record{Content: fmt.Sprintf("%d %d %d %s", usage, selector, matchingType, strings.ToLower(data))}, nil
+}
+
+// NewSSHFPRecord returns an SSHFP record with the hex encoded fingerprint
+func NewSSHFPRecord(algorithm uint8, fingerprintType uint8, fingerprint string) (*Record, error) {
+
+	if !isHex(fingerprint) {
+		return nil, fmt.Errorf("invalid SSHFP fingerprint %q", fingerprint)
+	}
+
+	return &Record{Content: fmt.Sprintf("%d %d %s", algorithm, fingerprintType, strings.ToLower(fingerprint))}, nil
+}
+
+// NewDSRecord returns a DS record with the hex encoded digest
+func NewDSRecord(keyTag uint16, algorithm uint8, digestType uint8, digest string) (*Record, error) {
+
+	if !isHex(digest) {
+		return nil, fmt.Errorf("invalid DS digest %q", digest)
+	}
+
+	return &Record{Content: fmt.Sprintf("%d %d %d %s", keyTag, algorithm, digestType, strings.ToLower(digest))}, nil
+}
+
+// NewSVCBRecord returns an SVCB record. Use "." as target to refer to the owner name.
+// Service parameters are given as key/value pairs (f.e. "alpn": "h2,h3", "port": "8443"); keys
+// without value (like "no-default-alpn") map to an empty string.
+func NewSVCBRecord(priority uint16, target string, params map[string]string) (*Record, error) {
+
+	target, err := fqdn(target)
+	if err != nil {
+		return nil, err
+	}
+
+	if priority == 0 && len(params) > 0 {
+		return nil, fmt.Errorf("service parameters are not allowed in AliasMode (priority 0)")
+	}
+
+	// keys are case insensitive
+	normalized := make(map[string]string, len(params))
+	keys := make([]string, 0, len(params))
+
+	for k, v := range params {
+		if _, err := svcParamKeyNumber(k); err != nil {
+			return nil, err
+		}
+		k = strings.ToLower(k)
+		if _, ok := normalized[k]; ok {
+			return nil, fmt.Errorf("duplicate service parameter %q", k)
+		}
+		normalized[k] = v
+		keys = append(keys, k)
+	}
+
+	// SvcParams are written in increasing key order (RFC 9460, section 2.2)
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := svcParamKeyNumber(keys[i])
+		b, _ := svcParamKeyNumber(keys[j])
+		return a < b
+	})
+
+	fields := []string{strconv.Itoa(int(priority)), target}
+
+	for _, k := range keys {
+		v := normalized[k]
+		if v == "" {
+			fields = append(fields, k)
+		} else if strings.ContainsAny(v, " \t\"") {
+			fields = append(fields, k+"="+quoteTXT(v))
+		} else {
+			fields = append(fields, k+"="+v)
+		}
+	}
+
+	return &Record{Content: strings.Join(fields, " ")}, nil
+}
+
+// NewHTTPSRecord returns an HTTPS record (see NewSVCBRecord)
+func NewHTTPSRecord(priority uint16, target string, params map[string]string) (*Record, error) {
+	return NewSVCBRecord(priority, target, params)
+}
+
+// Validate checks the name, type and the content of all records of the change.
+// The records of a delete are not checked.
+func (c *RRSetChange) Validate() error {
+
+	if _, err := fqdn(c.Name); err != nil {
+		return fmt.Errorf("invalid rrset name %q: %v", c.Name, err)
+	}
+
+	if c.Type == "" {
+		return fmt.Errorf("rrset %s without type", c.Name)
+	}
+
+	switch c.ChangeType {
+	case ChangeTypeADD, ChangeTypeUPDATE:
+	case ChangeTypeDELETE:
+		return nil
+	default:
+		return fmt.Errorf("invalid change type %q for rrset %s %s", c.ChangeType, c.Name, c.Type)
+	}
+
+	if len(c.Records) == 0 {
+		return fmt.Errorf("rrset %s %s without records", c.Name, c.Type)
+	}
+
+	for _, r := range c.Records {
+		if err := ValidateContent(c.Type, r.Content); err != nil {
+			return fmt.Errorf("rrset %s %s: %v", c.Name, c.Type, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateContent parses the record content according to its type. Content of types
+// which are not known to the validator is accepted.
+func ValidateContent(rrType string, content string) error {
+
+	rrType = strings.ToUpper(rrType)
+
+	var err error
+
+	switch rrType {
+
+	case "A":
+		_, err = NewARecord(content)
+
+	case "AAAA":
+		_, err = NewAAAARecord(content)
+
+	case "CNAME", "NS", "PTR", "DNAME":
+		err = validateFQDN(content)
+
+	case "TXT", "SPF":
+		_, err = parseTXTStrings(content)
+
+	default:
+		err = validateFields(rrType, content)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %s record %q: %v", rrType, content, err)
+	}
+
+	return nil
+}
+
+// validateFields checks types with a fixed field layout
+func validateFields(rrType string, content string) error {
+
+	fields := strings.Fields(content)
+
+	expect := func(n int) error {
+		if len(fields) != n {
+			return fmt.Errorf("expected %d fields, got %d", n, len(fields))
+		}
+		return nil
+	}
+
+	switch rrType {
+
+	case "MX":
+		if err := expect(2); err != nil {
+			return err
+		}
+		return firstError(checkUint(fields[0], 16), validateFQDN(fields[1]))
+
+	case "SRV":
+		if err := expect(4); err != nil {
+			return err
+		}
+		return firstError(checkUint(fields[0], 16), checkUint(fields[1], 16), checkUint(fields[2], 16), validateFQDN(fields[3]))
+
+	case "CAA":
+		if len(fields) < 3 {
+			return fmt.Errorf("expected 3 fields, got %d", len(fields))
+		}
+		if !isCAATag(fields[1]) {
+			return fmt.Errorf("invalid tag %q", fields[1])
+		}
+		if _, err := parseTXTStrings(skipFields(content, 2)); err != nil {
+			return err
+		}
+		return checkUint(fields[0], 8)
+
+	case "TLSA":
+		if err := expect(4); err != nil {
+			return err
+		}
+		return firstError(checkUint(fields[0], 8), checkUint(fields[1], 8), checkUint(fields[2], 8), checkHex(fields[3]))
+
+	case "SSHFP":
+		if err := expect(3); err != nil {
+			return err
+		}
+		return firstError(checkUint(fields[0], 8), checkUint(fields[1], 8), checkHex(fields[2]))
+
+	case "DS":
+		if len(fields) < 4 {
+			return fmt.Errorf("expected 4 fields, got %d", len(fields))
+		}
+		// The digest may be split into several fields
+		return firstError(checkUint(fields[0], 16), checkUint(fields[1], 8), checkUint(fields[2], 8), checkHex(strings.Join(fields[3:], "")))
+
+	case "SVCB", "HTTPS":
+		if len(fields) < 2 {
+			return fmt.Errorf("expected at least 2 fields, got %d", len(fields))
+		}
+		if err := firstError(checkUint(fields[0], 16), validateFQDN(fields[1])); err != nil {
+			return err
+		}
+		params, err := svcParamFields(skipFields(content, 2))
+		if err != nil {
+			return err
+		}
+		for _, param := range params {
+			key := strings.SplitN(param, "=", 2)[0]
+			if _, err := svcParamKeyNumber(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+func newNameRecord(name string) (*Record, error) {
+
+	name, err := fqdn(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Record{Content: name}, nil
+}
+
+// fqdn validates the domain name and returns it with a trailing dot
+func fqdn(name string) (string, error) {
+
+	if name != "." && !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+
+	if err := validateFQDN(name); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
+
+// validateFQDN checks the syntax of an absolute domain name. Underscores and a leading wildcard
+// label are allowed, as they are common in service and wildcard names.
+func validateFQDN(name string) error {
+
+	if name == "." {
+		return nil
+	}
+
+	if !strings.HasSuffix(name, ".") {
+		return fmt.Errorf("domain name %q is not fully qualified", name)
+	}
+
+	if len(name) > 254 {
+		return fmt.Errorf("domain name %q is too long", name)
+	}
+
+	labels := strings.Split(strings.TrimSuffix(name, "."), ".")
+
+	for i, label := range labels {
+
+		if len(label) == 0 || len(label) > 63 {
+			return fmt.Errorf("domain name %q has an invalid label length", name)
+		}
+
+		if label == "*" && i == 0 {
+			continue
+		}
+
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return fmt.Errorf("domain name %q contains invalid character %q", name, c)
+			}
+		}
+	}
+
+	return nil
+}
+
+// quoteTXT returns the text as a quoted character-string with quotes, backslashes and
+// non-printable bytes escaped
+func quoteTXT(text string) string {
+
+	var b strings.Builder
+
+	b.WriteByte('"')
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// parseTXTStrings returns the unescaped character-strings of TXT content.
+// Unquoted content is accepted as a sequence of space separated strings.
+func parseTXTStrings(content string) ([]string, error) {
+
+	var strs []string
+
+	content = strings.TrimSpace(content)
+
+	for i := 0; i < len(content); {
+
+		switch content[i] {
+		case ' ', '\t':
+			i++
+			continue
+		}
+
+		quoted := content[i] == '"'
+		if quoted {
+			i++
+		}
+
+		var b []byte
+		closed := !quoted
+
+		for i < len(content) {
+			c := content[i]
+
+			if quoted && c == '"' {
+				closed = true
+				i++
+				break
+			}
+
+			if !quoted && (c == ' ' || c == '\t') {
+				break
+			}
+
+			if c == '\\' {
+				if i+3 < len(content) && isDigits(content[i+1:i+4]) {
+					v, _ := strconv.Atoi(content[i+1 : i+4])
+					if v > 255 {
+						return nil, fmt.Errorf("invalid escape sequence \\%s", content[i+1:i+4])
+					}
+					b = append(b, byte(v))
+					i += 4
+					continue
+				}
+				if i+1 >= len(content) {
+					return nil, fmt.Errorf("dangling escape character")
+				}
+				b = append(b, content[i+1])
+				i += 2
+				continue
+			}
+
+			b = append(b, c)
+			i++
+		}
+
+		if !closed {
+			return nil, fmt.Errorf("unterminated character-string")
+		}
+
+		if len(b) > maxTXTStringLength {
+			return nil, fmt.Errorf("character-string exceeds %d bytes", maxTXTStringLength)
+		}
+
+		strs = append(strs, string(b))
+	}
+
+	if len(strs) == 0 {
+		return nil, fmt.Errorf("no character-string")
+	}
+
+	return strs, nil
+}
+
+// svcParamKeys maps the registered SvcParamKeys to their numbers (RFC 9460, section 14.3.2)
+var svcParamKeys = map[string]int{
+	"mandatory":       0,
+	"alpn":            1,
+	"no-default-alpn": 2,
+	"port":            3,
+	"ipv4hint":        4,
+	"ech":             5,
+	"ipv6hint":        6,
+}
+
+func svcParamKeyNumber(key string) (int, error) {
+
+	key = strings.ToLower(key)
+
+	if n, ok := svcParamKeys[key]; ok {
+		return n, nil
+	}
+
+	if strings.HasPrefix(key, "key") {
+		if n, err := strconv.Atoi(key[3:]); err == nil && n >= 0 && n <= 65535 {
+			return n, nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid service parameter key %q", key)
+}
+
+// skipFields returns the content after the first n whitespace separated fields
+func skipFields(content string, n int) string {
+
+	content = strings.TrimSpace(content)
+
+	for i := 0; i < n; i++ {
+		end := strings.IndexAny(content, " \t")
+		if end < 0 {
+			return ""
+		}
+		content = strings.TrimSpace(content[end:])
+	}
+
+	return content
+}
+
+// svcParamFields splits SVCB service parameters at spaces outside of quoted values,
+// f.e. `alpn=h2 ech="a b"` returns "alpn=h2" and `ech="a b"`
+func svcParamFields(content string) ([]string, error) {
+
+	var fields []string
+
+	for i := 0; i < len(content); {
+
+		switch content[i] {
+		case ' ', '\t':
+			i++
+			continue
+		}
+
+		start := i
+		quoted := false
+
+		for i < len(content) {
+			c := content[i]
+
+			if !quoted && (c == ' ' || c == '\t') {
+				break
+			}
+
+			if c == '\\' {
+				if i+1 >= len(content) {
+					return nil, fmt.Errorf("dangling escape character")
+				}
+				i += 2
+				continue
+			}
+
+			if c == '"' {
+				quoted = !quoted
+			}
+
+			i++
+		}
+
+		if quoted {
+			return nil, fmt.Errorf("unterminated quoted service parameter value")
+		}
+
+		fields = append(fields, content[start:i])
+	}
+
+	return fields, nil
+}
+
+func isCAATag(tag string) bool {
+
+	if tag == "" || len(tag) > 15 {
+		return false
+	}
+
+	for _, c := range tag {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isHex(s string) bool {
+	_, err := hex.DecodeString(s)
+	return s != "" && err == nil
+}
+
+func isDigits(s string) bool {
+
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+func checkHex(s string) error {
+
+	if !isHex(s) {
+		return fmt.Errorf("invalid hex value %q", s)
+	}
+
+	return nil
+}
+
+func checkUint(s string, bits int) error {
+
+	if _, err := strconv.ParseUint(s, 10, bits); err != nil {
+		return fmt.Errorf("invalid %d bit number %q", bits, s)
+	}
+
+	return nil
+}
+
+func firstError(errs ...error) error {
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRecordBuilders(t *testing.T) {
+
+	must := func(r *Record, err error) string {
+		if err != nil {
+			t.Fatalf("record builder returned error: %v", err)
+		}
+		return r.Content
+	}
+
+	tests := []struct {
+		got  string
+		want string
+	}{
+		{must(NewARecord("10.10.0.1")), "10.10.0.1"},
+		{must(NewAAAARecord("2001:DB8:0::1")), "2001:db8::1"},
+		{must(NewCNAMERecord("www.testzone1.at")), "www.testzone1.at."},
+		{must(NewNSRecord("sec1.rcode0.net.")), "sec1.rcode0.net."},
+		{must(NewPTRRecord("host.testzone1.at")), "host.testzone1.at."},
+		{must(NewMXRecord(10, "mail.testzone1.at")), "10 mail.testzone1.at."},
+		{must(NewTXTRecord(`say "hi"`)), `"say \"hi\""`},
+		{must(NewSRVRecord(10, 60, 5060, "sip.testzone1.at")), "10 60 5060 sip.testzone1.at."},
+		{must(NewCAARecord(0, "issue", "letsencrypt.org")), `0 issue "letsencrypt.org"`},
+		{must(NewTLSARecord(3, 1, 1, "ABCDEF01")), "3 1 1 abcdef01"},
+		{must(NewSSHFPRecord(4, 2, "abcdef01")), "4 2 abcdef01"},
+		{must(NewDSRecord(12345, 13, 2, "ABCDEF01")), "12345 13 2 abcdef01"},
+		{must(NewHTTPSRecord(1, ".", map[string]string{"port": "8443", "alpn": "h2,h3"})), "1 . alpn=h2,h3 port=8443"},
+		{must(NewSVCBRecord(0, "svc.testzone1.at", nil)), "0 svc.testzone1.at."},
+		{must(NewSVCBRecord(1, ".", map[string]string{"ALPN": "h2", "Port": "443"})), "1 . alpn=h2 port=443"},
+	}
+
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("record builder returned %q, want %q", test.got, test.want)
+		}
+	}
+}
+
+func TestRecordBuilders_Invalid(t *testing.T) {
+
+	if _, err := NewARecord("2001:db8::1"); err == nil {
+		t.Errorf("NewARecord accepted an IPv6 address")
+	}
+
+	if _, err := NewAAAARecord("10.10.0.1"); err == nil {
+		t.Errorf("NewAAAARecord accepted an IPv4 address")
+	}
+
+	if _, err := NewAAAARecord("::ffff:192.0.2.1"); err == nil {
+		t.Errorf("NewAAAARecord accepted an IPv4-mapped address")
+	}
+
+	if _, err := NewCNAMERecord("www..testzone1.at"); err == nil {
+		t.Errorf("NewCNAMERecord accepted an empty label")
+	}
+
+	if _, err := NewCAARecord(0, "is sue", "letsencrypt.org"); err == nil {
+		t.Errorf("NewCAARecord accepted an invalid tag")
+	}
+
+	if _, err := NewDSRecord(1, 13, 2, "xyz"); err == nil {
+		t.Errorf("NewDSRecord accepted an invalid digest")
+	}
+
+	if _, err := NewHTTPSRecord(0, ".", map[string]string{"alpn": "h2"}); err == nil {
+		t.Errorf("NewHTTPSRecord accepted service parameters in AliasMode")
+	}
+
+	if _, err := NewHTTPSRecord(1, ".", map[string]string{"foo": "bar"}); err == nil {
+		t.Errorf("NewHTTPSRecord accepted an unknown service parameter key")
+	}
+
+	if _, err := NewSVCBRecord(1, ".", map[string]string{"alpn": "h2", "ALPN": "h3"}); err == nil {
+		t.Errorf("NewSVCBRecord accepted a duplicate service parameter key")
+	}
+}
+
+func TestValidateContent(t *testing.T) {
+
+	valid := map[string][]string{
+		"A":     {"10.10.0.1"},
+		"AAAA":  {"2001:db8::1"},
+		"CNAME": {"www.testzone1.at."},
+		"MX":    {"10 mail.testzone1.at."},
+		"TXT":   {`"v=spf1 -all"`, `"part one" "part two"`, `"escaped \" quote \059"`},
+		"SRV":   {"0 0 443 ."},
+		"CAA":   {`0 issue "letsencrypt.org"`, `128 iodef "mailto:hostmaster@testzone1.at"`},
+		"TLSA":  {"3 1 1 abcdef01"},
+		"SSHFP": {"4 2 abcdef01"},
+		"DS":    {"12345 13 2 abcdef01 23456789"},
+		"HTTPS": {"1 . alpn=h2,h3 ipv4hint=10.10.0.1", `1 . alpn=h2 ech="a b" port=443`},
+		"LOC":   {"anything goes for unknown types"},
+	}
+
+	for rrType, contents := range valid {
+		for _, content := range contents {
+			if err := ValidateContent(rrType, content); err != nil {
+				t.Errorf("ValidateContent(%s, %q) returned error: %v", rrType, content, err)
+			}
+		}
+	}
+
+	invalid := map[string][]string{
+		"A":     {"10.10.0.256", "www"},
+		"AAAA":  {"10.10.0.1"},
+		"CNAME": {"www.testzone1.at"},
+		"MX":    {"mail.testzone1.at.", "70000 mail.testzone1.at."},
+		"TXT":   {`"unterminated`, `"` + strings.Repeat("a", 256) + `"`},
+		"SRV":   {"0 0 443"},
+		"CAA":   {`0 is-sue "letsencrypt.org"`},
+		"TLSA":  {"3 1 1 xyz"},
+		"SSHFP": {"4 256 abcdef01"},
+		"DS":    {"12345 13 2"},
+		"HTTPS": {"1 . foo=bar", `1 . ech="unterminated`},
+	}
+
+	for rrType, contents := range invalid {
+		for _, content := range contents {
+			if err := ValidateContent(rrType, content); err == nil {
+				t.Errorf("ValidateContent(%s, %q) returned no error", rrType, content)
+			}
+		}
+	}
+}
+
+func TestRRSetService_SubmitChangeSet_Validate(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("RRSet.SubmitChangeSet sent an invalid change set")
+	})
+
+	client.ValidateChangeSets = true
+
+	changeSet := []*RRSetChange{{
+		Name:       "testzone1.at.",
+		Type:       "MX",
+		ChangeType: ChangeTypeADD,
+		Records:    []*Record{{Content: "mail.testzone1.at."}},
+	}}
+
+	if _, err := client.RRSet.SubmitChangeSet("testzone1.at", changeSet); err == nil {
+		t.Errorf("RRSet.SubmitChangeSet returned no error for an invalid record")
+	}
+}
+
+func TestNewSVCBRecord_ValidateContent(t *testing.T) {
+
+	params := map[string]string{"alpn": "h2,h3", "ech": "a b", "key65000": `a "quoted" value`, "no-default-alpn": ""}
+
+	record, err := NewSVCBRecord(1, "svc.testzone1.at", params)
+	if err != nil {
+		t.Fatalf("NewSVCBRecord returned error: %v", err)
+	}
+
+	if err := ValidateContent("SVCB", record.Content); err != nil {
+		t.Errorf("ValidateContent(SVCB, %q) returned error: %v", record.Content, err)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package rfc2136 accepts RFC 2136 dynamic UPDATE messages (f.e. from nsupdate or DHCP servers)
+// and applies them to rcode0 zones through the REST API.
+package rfc2136
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/nic-at/rc0go"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Gateway is a DNS server which translates TSIG-signed UPDATE messages into rcode0 change sets
+type Gateway struct {
+
+	// RRSet service of the rcode0 client
+	RRSet rc0go.RRSetServiceInterface
+
+	// Zones accepted by the gateway, f.e. "example.at"
+	Zones []string
+
+	// TSIG keys by key name, f.e. "dhcp." => base64 encoded secret
+	Keys map[string]string
+
+	// Policies define the names and types each TSIG key may change. Keys without
+	// policy are refused.
+	Policies map[string]*rc0go.SyncScope
+
+	// Logf receives a message per handled update (optional)
+	Logf func(format string, args ...interface{})
+
+	// Serializes the read-modify-write of the zones
+	mu sync.Mutex
+}
+
+// NewServer returns a DNS server for the network ("udp" or "tcp") and address which passes
+// UPDATE messages to the gateway
+func (g *Gateway) NewServer(network string, addr string) *dns.Server {
+
+	secrets := make(map[string]string)
+	for name, secret := range g.Keys {
+		secrets[dns.Fqdn(name)] = secret
+	}
+
+	return &dns.Server{
+		Addr:          addr,
+		Net:           network,
+		Handler:       g,
+		TsigSecret:    secrets,
+		MsgAcceptFunc: acceptUpdate,
+	}
+}
+
+// ListenAndServe serves UPDATE messages on UDP and TCP at the address (f.e. "127.0.0.1:53")
+// until the context is done
+func (g *Gateway) ListenAndServe(ctx context.Context, addr string) error {
+
+	servers := []*dns.Server{
+		g.NewServer("udp", addr),
+		g.NewServer("tcp", addr),
+	}
+
+	errs := make(chan error, len(servers))
+
+	for _, server := range servers {
+		go func(server *dns.Server) {
+			errs <- server.ListenAndServe()
+		}(server)
+	}
+
+	var err error
+
+	select {
+	case <-ctx.Done():
+	case err = <-errs:
+	}
+
+	for _, server := range servers {
+		_ = server.Shutdown()
+	}
+
+	return err
+}
+
+// ServeDNS handles a single UPDATE message
+func (g *Gateway) ServeDNS(w dns.ResponseWriter, r *dns.Msg) {
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	rcode, keyName := g.handle(w, r)
+	m.Rcode = rcode
+
+	// the server signs the reply with the key of the request
+	if tsig := r.IsTsig(); tsig != nil && w.TsigStatus() == nil {
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+	}
+
+	if g.Logf != nil && r.Opcode == dns.OpcodeUpdate && len(r.Question) == 1 {
+		g.Logf("update of %s by key %q from %s: %s", r.Question[0].Name, keyName, w.RemoteAddr(), dns.RcodeToString[rcode])
+	}
+
+	_ = w.WriteMsg(m)
+}
+
+// handle checks and applies the update and returns the response code and the TSIG key name
+func (g *Gateway) handle(w dns.ResponseWriter, r *dns.Msg) (int, string) {
+
+	if r.Opcode != dns.OpcodeUpdate {
+		return dns.RcodeNotImplemented, ""
+	}
+
+	// zone section (RFC 2136, section 3.1)
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		return dns.RcodeFormatError, ""
+	}
+
+	zone := rc0go.NormalizeName(r.Question[0].Name)
+	if !g.servesZone(zone) {
+		return dns.RcodeNotAuth, ""
+	}
+
+	tsig := r.IsTsig()
+	if tsig == nil {
+		return dns.RcodeRefused, ""
+	}
+
+	keyName := rc0go.NormalizeName(tsig.Hdr.Name)
+
+	if w.TsigStatus() != nil {
+		return dns.RcodeNotAuth, keyName
+	}
+
+	policy, ok := g.policy(keyName)
+	if !ok {
+		return dns.RcodeRefused, keyName
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	rrsets, err := g.RRSet.ListAll(context.Background(), strings.TrimSuffix(zone, "."))
+	if err != nil {
+		return dns.RcodeServerFailure, keyName
+	}
+
+	u := newZoneUpdate(zone, rrsets)
+
+	if rcode := u.checkPrerequisites(r.Answer); rcode != dns.RcodeSuccess {
+		return rcode, keyName
+	}
+
+	if rcode := u.prescan(r.Ns, policy); rcode != dns.RcodeSuccess {
+		return rcode, keyName
+	}
+
+	changes := u.apply(r.Ns)
+	if len(changes) == 0 {
+		return dns.RcodeSuccess, keyName
+	}
+
+	status, err := g.RRSet.SubmitChangeSet(strings.TrimSuffix(zone, "."), changes)
+	if err != nil || status.HasError() {
+		return dns.RcodeServerFailure, keyName
+	}
+
+	return dns.RcodeSuccess, keyName
+}
+
+func (g *Gateway) servesZone(zone string) bool {
+	for _, z := range g.Zones {
+		if rc0go.NormalizeName(z) == zone {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gateway) policy(keyName string) (*rc0go.SyncScope, bool) {
+	for name, policy := range g.Policies {
+		if rc0go.NormalizeName(name) == keyName {
+			return policy, policy != nil
+		}
+	}
+	return nil, false
+}
+
+// acceptUpdate accepts UPDATE messages in addition to the ones accepted by default
+func acceptUpdate(dh dns.Header) dns.MsgAcceptAction {
+
+	if int(dh.Bits>>11)&0xF == dns.OpcodeUpdate {
+		return dns.MsgAccept
+	}
+
+	return dns.DefaultMsgAcceptFunc(dh)
+}
+
+// rdata returns the presentation format of the record data
+func rdata(rr dns.RR) string {
+	return strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+}
+
+// inZone reports whether the name is the zone or below it
+func inZone(name string, zone string) bool {
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
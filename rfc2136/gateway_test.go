@@ -0,0 +1,219 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rfc2136
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/nic-at/rc0go"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testKey    = "dhcp."
+	testSecret = "c2VjcmV0LXNlY3JldC1zZWNyZXQtc2VjcmV0IQ=="
+)
+
+// fakeRRSet serves the rrsets of a zone from memory
+type fakeRRSet struct {
+	rc0go.RRSetServiceInterface
+
+	mu      sync.Mutex
+	rrsets  []*rc0go.RRType
+	patches [][]*rc0go.RRSetChange
+}
+
+func (f *fakeRRSet) ListAll(ctx context.Context, zone string) ([]*rc0go.RRType, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rrsets, nil
+}
+
+func (f *fakeRRSet) SubmitChangeSet(zone string, changeSet []*rc0go.RRSetChange) (*rc0go.StatusResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.patches = append(f.patches, changeSet)
+	return &rc0go.StatusResponse{Status: "ok"}, nil
+}
+
+// submitted returns the change sets the gateway submitted so far
+func (f *fakeRRSet) submitted() [][]*rc0go.RRSetChange {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]*rc0go.RRSetChange(nil), f.patches...)
+}
+
+func setup(t *testing.T) (*fakeRRSet, string, func()) {
+
+	rrset := &fakeRRSet{rrsets: []*rc0go.RRType{
+		{Name: "testzone1.at.", Type: "NS", TTL: 3600, Records: []*rc0go.Record{{Content: "sec1.rcode0.net."}}},
+		{Name: "host1.dyn.testzone1.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.1"}}},
+		{Name: "www.testzone1.at.", Type: "A", TTL: 300, Records: []*rc0go.Record{{Content: "10.10.0.80"}}},
+	}}
+
+	gateway := &Gateway{
+		RRSet:    rrset,
+		Zones:    []string{"testzone1.at"},
+		Keys:     map[string]string{testKey: testSecret},
+		Policies: map[string]*rc0go.SyncScope{testKey: {Names: []string{"*.dyn"}, Types: []string{"A", "AAAA", "TXT"}}},
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+
+	server := gateway.NewServer("udp", "")
+	server.PacketConn = conn
+	server.NotifyStartedFunc = func() { close(started) }
+
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+
+	return rrset, conn.LocalAddr().String(), func() { _ = server.Shutdown() }
+}
+
+func exchange(t *testing.T, addr string, m *dns.Msg, secret string) *dns.Msg {
+
+	m.SetTsig(testKey, dns.HmacSHA256, 300, time.Now().Unix())
+
+	client := &dns.Client{TsigSecret: map[string]string{testKey: secret}}
+
+	r, _, err := client.Exchange(m, addr)
+	if err != nil && r == nil {
+		t.Fatalf("exchange failed: %v", err)
+	}
+
+	return r
+}
+
+func newRR(t *testing.T, s string) dns.RR {
+	rr, err := dns.NewRR(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rr
+}
+
+func TestGateway_Update(t *testing.T) {
+
+	rrset, addr, teardown := setup(t)
+	defer teardown()
+
+	m := new(dns.Msg)
+	m.SetUpdate("testzone1.at.")
+	m.RRsetUsed([]dns.RR{newRR(t, "host1.dyn.testzone1.at. 0 IN A 0.0.0.0")})
+	m.RemoveRRset([]dns.RR{newRR(t, "host1.dyn.testzone1.at. 0 IN A 0.0.0.0")})
+	m.Insert([]dns.RR{
+		newRR(t, "host1.dyn.testzone1.at. 60 IN A 10.10.0.2"),
+		newRR(t, `host2.dyn.testzone1.at. 60 IN TXT "dhcp lease"`),
+	})
+
+	r := exchange(t, addr, m, testSecret)
+
+	if r.Rcode != dns.RcodeSuccess {
+		t.Fatalf("Gateway returned %s, want NOERROR", dns.RcodeToString[r.Rcode])
+	}
+
+	if r.IsTsig() == nil {
+		t.Errorf("Gateway did not sign the response")
+	}
+
+	want := [][]*rc0go.RRSetChange{{
+		{Name: "host1.dyn.testzone1.at.", Type: "A", ChangeType: rc0go.ChangeTypeUPDATE, TTL: 60, Records: []*rc0go.Record{{Content: "10.10.0.2"}}},
+		{Name: "host2.dyn.testzone1.at.", Type: "TXT", ChangeType: rc0go.ChangeTypeADD, TTL: 60, Records: []*rc0go.Record{{Content: `"dhcp lease"`}}},
+	}}
+
+	if got := rrset.submitted(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Gateway submitted %+v, want %+v", got, want)
+	}
+}
+
+func TestGateway_Prerequisites(t *testing.T) {
+
+	rrset, addr, teardown := setup(t)
+	defer teardown()
+
+	tests := []struct {
+		prereq func(m *dns.Msg)
+		rcode  int
+	}{
+		{func(m *dns.Msg) { m.RRsetUsed([]dns.RR{newRR(t, "host9.dyn.testzone1.at. 0 IN A 0.0.0.0")}) }, dns.RcodeNXRrset},
+		{func(m *dns.Msg) { m.NameUsed([]dns.RR{newRR(t, "host9.dyn.testzone1.at. 0 IN A 0.0.0.0")}) }, dns.RcodeNameError},
+		{func(m *dns.Msg) { m.NameNotUsed([]dns.RR{newRR(t, "host1.dyn.testzone1.at. 0 IN A 0.0.0.0")}) }, dns.RcodeYXDomain},
+		{func(m *dns.Msg) { m.RRsetNotUsed([]dns.RR{newRR(t, "host1.dyn.testzone1.at. 0 IN A 0.0.0.0")}) }, dns.RcodeYXRrset},
+		{func(m *dns.Msg) { m.Used([]dns.RR{newRR(t, "host1.dyn.testzone1.at. 0 IN A 10.10.0.9")}) }, dns.RcodeNXRrset},
+		{func(m *dns.Msg) { m.Used([]dns.RR{newRR(t, "host1.other.at. 0 IN A 10.10.0.1")}) }, dns.RcodeNotZone},
+	}
+
+	for _, test := range tests {
+
+		m := new(dns.Msg)
+		m.SetUpdate("testzone1.at.")
+		test.prereq(m)
+		m.Insert([]dns.RR{newRR(t, "host1.dyn.testzone1.at. 60 IN A 10.10.0.2")})
+
+		if r := exchange(t, addr, m, testSecret); r.Rcode != test.rcode {
+			t.Errorf("Gateway returned %s, want %s", dns.RcodeToString[r.Rcode], dns.RcodeToString[test.rcode])
+		}
+	}
+
+	if got := rrset.submitted(); len(got) != 0 {
+		t.Errorf("Gateway submitted %+v despite failed prerequisites", got)
+	}
+}
+
+func TestGateway_Refused(t *testing.T) {
+
+	rrset, addr, teardown := setup(t)
+	defer teardown()
+
+	// outside of the key's policy
+	m := new(dns.Msg)
+	m.SetUpdate("testzone1.at.")
+	m.Insert([]dns.RR{newRR(t, "www.testzone1.at. 60 IN A 10.10.0.2")})
+
+	if r := exchange(t, addr, m, testSecret); r.Rcode != dns.RcodeRefused {
+		t.Errorf("Gateway returned %s for a name outside of the policy, want REFUSED", dns.RcodeToString[r.Rcode])
+	}
+
+	// unknown zone
+	m = new(dns.Msg)
+	m.SetUpdate("testzone2.at.")
+	m.Insert([]dns.RR{newRR(t, "host1.testzone2.at. 60 IN A 10.10.0.2")})
+
+	if r := exchange(t, addr, m, testSecret); r.Rcode != dns.RcodeNotAuth {
+		t.Errorf("Gateway returned %s for an unknown zone, want NOTAUTH", dns.RcodeToString[r.Rcode])
+	}
+
+	// unsigned
+	m = new(dns.Msg)
+	m.SetUpdate("testzone1.at.")
+	m.Insert([]dns.RR{newRR(t, "host1.dyn.testzone1.at. 60 IN A 10.10.0.2")})
+
+	if r, err := dns.Exchange(m, addr); err != nil || r.Rcode != dns.RcodeRefused {
+		t.Errorf("Gateway returned %v, %v for an unsigned update, want REFUSED", r, err)
+	}
+
+	// wrong secret
+	m = new(dns.Msg)
+	m.SetUpdate("testzone1.at.")
+	m.Insert([]dns.RR{newRR(t, "host1.dyn.testzone1.at. 60 IN A 10.10.0.2")})
+
+	if r := exchange(t, addr, m, "d3Jvbmctc2VjcmV0"); r.Rcode != dns.RcodeNotAuth {
+		t.Errorf("Gateway returned %s for a bad signature, want NOTAUTH", dns.RcodeToString[r.Rcode])
+	}
+
+	if got := rrset.submitted(); len(got) != 0 {
+		t.Errorf("Gateway submitted %+v for refused updates", got)
+	}
+}
@@ -0,0 +1,278 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rfc2136
+
+import (
+	"github.com/miekg/dns"
+	"github.com/nic-at/rc0go"
+	"strings"
+)
+
+type rrsetKey struct {
+	name   string
+	rrType string
+}
+
+// zoneUpdate checks and applies an UPDATE message to the rrsets of a zone
+type zoneUpdate struct {
+	zone    string
+	current map[rrsetKey]*rc0go.RRType
+	desired map[rrsetKey]*rc0go.RRType
+}
+
+func newZoneUpdate(zone string, rrsets []*rc0go.RRType) *zoneUpdate {
+
+	u := &zoneUpdate{
+		zone:    zone,
+		current: make(map[rrsetKey]*rc0go.RRType),
+		desired: make(map[rrsetKey]*rc0go.RRType),
+	}
+
+	for _, rrset := range rrsets {
+
+		key := rrsetKey{rc0go.NormalizeName(rrset.Name), strings.ToUpper(rrset.Type)}
+		u.current[key] = rrset
+
+		records := make([]*rc0go.Record, len(rrset.Records))
+		for i, r := range rrset.Records {
+			records[i] = &rc0go.Record{Content: r.Content, Disabled: r.Disabled}
+		}
+
+		u.desired[key] = &rc0go.RRType{Name: rrset.Name, Type: rrset.Type, TTL: rrset.TTL, Records: records}
+	}
+
+	return u
+}
+
+// checkPrerequisites checks the prerequisite section (RFC 2136, section 3.2)
+func (u *zoneUpdate) checkPrerequisites(prereqs []dns.RR) int {
+
+	// value dependent prerequisites are compared per rrset
+	expected := make(map[rrsetKey][]*rc0go.Record)
+
+	for _, rr := range prereqs {
+
+		h := rr.Header()
+		key := rrsetKey{rc0go.NormalizeName(h.Name), dns.TypeToString[h.Rrtype]}
+
+		if h.Ttl != 0 {
+			return dns.RcodeFormatError
+		}
+
+		if !inZone(key.name, u.zone) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+
+		case dns.ClassANY:
+			if h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeANY && !u.nameInUse(key.name) {
+				return dns.RcodeNameError
+			}
+			if h.Rrtype != dns.TypeANY && u.current[key] == nil {
+				return dns.RcodeNXRrset
+			}
+
+		case dns.ClassNONE:
+			if h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+			if h.Rrtype == dns.TypeANY && u.nameInUse(key.name) {
+				return dns.RcodeYXDomain
+			}
+			if h.Rrtype != dns.TypeANY && u.current[key] != nil {
+				return dns.RcodeYXRrset
+			}
+
+		case dns.ClassINET:
+			expected[key] = append(expected[key], &rc0go.Record{Content: rdata(rr)})
+
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	for key, records := range expected {
+		rrset := u.current[key]
+		if rrset == nil || !rc0go.EqualRecords(key.rrType, rrset.Records, records) {
+			return dns.RcodeNXRrset
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// prescan checks the update section (RFC 2136, section 3.4.1) and the policy of the key
+func (u *zoneUpdate) prescan(updates []dns.RR, policy *rc0go.SyncScope) int {
+
+	for _, rr := range updates {
+
+		h := rr.Header()
+		name := rc0go.NormalizeName(h.Name)
+
+		if !inZone(name, u.zone) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Rrtype {
+		case dns.TypeAXFR, dns.TypeIXFR, dns.TypeMAILA, dns.TypeMAILB, dns.TypeOPT, dns.TypeTSIG:
+			return dns.RcodeFormatError
+		}
+
+		switch h.Class {
+
+		case dns.ClassINET:
+			if h.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+
+		case dns.ClassANY:
+			if h.Ttl != 0 || h.Rdlength != 0 {
+				return dns.RcodeFormatError
+			}
+
+		case dns.ClassNONE:
+			if h.Ttl != 0 || h.Rrtype == dns.TypeANY {
+				return dns.RcodeFormatError
+			}
+
+		default:
+			return dns.RcodeFormatError
+		}
+
+		if h.Rrtype != dns.TypeANY {
+			if !policy.Contains(u.zone, name, dns.TypeToString[h.Rrtype]) {
+				return dns.RcodeRefused
+			}
+			continue
+		}
+
+		// deleting all rrsets of a name needs permission for each of them
+		for key := range u.current {
+			if key.name == name && !u.protected(key) && !policy.Contains(u.zone, key.name, key.rrType) {
+				return dns.RcodeRefused
+			}
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// apply processes the update section (RFC 2136, section 3.4.2) and returns the resulting changes.
+// The SOA is managed by rcode0 and not updated, the apex NS rrset is never deleted completely.
+func (u *zoneUpdate) apply(updates []dns.RR) []*rc0go.RRSetChange {
+
+	for _, rr := range updates {
+
+		h := rr.Header()
+		key := rrsetKey{rc0go.NormalizeName(h.Name), dns.TypeToString[h.Rrtype]}
+
+		if h.Rrtype == dns.TypeSOA {
+			continue
+		}
+
+		switch h.Class {
+
+		case dns.ClassINET:
+			u.add(key, int(h.Ttl), rdata(rr))
+
+		case dns.ClassANY:
+			for k := range u.desired {
+				if k.name == key.name && (h.Rrtype == dns.TypeANY || k.rrType == key.rrType) && !u.protected(k) {
+					delete(u.desired, k)
+				}
+			}
+
+		case dns.ClassNONE:
+			u.remove(key, rdata(rr))
+		}
+	}
+
+	var current, desired []*rc0go.RRType
+
+	for _, rrset := range u.current {
+		current = append(current, rrset)
+	}
+
+	for _, rrset := range u.desired {
+		desired = append(desired, rrset)
+	}
+
+	return rc0go.DiffRRSets(current, desired).Changes
+}
+
+// add adds the record to the rrset and sets the TTL of the rrset. CNAME rrsets and other
+// data for the same name exclude each other, conflicting records are ignored.
+func (u *zoneUpdate) add(key rrsetKey, ttl int, content string) {
+
+	for k := range u.desired {
+		if k.name == key.name && k.rrType != key.rrType && (k.rrType == "CNAME" || key.rrType == "CNAME") {
+			return
+		}
+	}
+
+	rrset := u.desired[key]
+	if rrset == nil {
+		rrset = &rc0go.RRType{Name: key.name, Type: key.rrType}
+		u.desired[key] = rrset
+	}
+
+	rrset.TTL = ttl
+
+	normalized := rc0go.NormalizeContent(key.rrType, content)
+	for _, r := range rrset.Records {
+		if rc0go.NormalizeContent(key.rrType, r.Content) == normalized {
+			return
+		}
+	}
+
+	rrset.Records = append(rrset.Records, &rc0go.Record{Content: content})
+}
+
+// remove removes the record from the rrset and deletes the rrset once it is empty
+func (u *zoneUpdate) remove(key rrsetKey, content string) {
+
+	rrset := u.desired[key]
+	if rrset == nil {
+		return
+	}
+
+	normalized := rc0go.NormalizeContent(key.rrType, content)
+
+	var kept []*rc0go.Record
+	for _, r := range rrset.Records {
+		if rc0go.NormalizeContent(key.rrType, r.Content) != normalized {
+			kept = append(kept, r)
+		}
+	}
+
+	if len(kept) == 0 && u.protected(key) {
+		return
+	}
+
+	rrset.Records = kept
+
+	if len(kept) == 0 {
+		delete(u.desired, key)
+	}
+}
+
+func (u *zoneUpdate) nameInUse(name string) bool {
+	for key := range u.current {
+		if key.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// protected reports whether the rrset is the SOA or NS rrset of the apex
+func (u *zoneUpdate) protected(key rrsetKey) bool {
+	return key.name == u.zone && (key.rrType == "SOA" || key.rrType == "NS")
+}
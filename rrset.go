@@ -6,27 +6,33 @@
 package rc0go
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
-	"encoding/base64"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/mitchellh/mapstructure"
 	"io"
-	"strings"
 )
 
 type RRSetService service
 
 type RRSetServiceInterface interface {
 	List(zone string, options *ListOptions) ([]*RRType, *Page, error)
+	ListAll(ctx context.Context, zone string) ([]*RRType, error)
+	Get(ctx context.Context, zone string, name string, rrType string) (*RRType, error)
 	Create(zone string, rrsetCreate []*RRSetChange) (*StatusResponse, error)
 	Edit(zone string, rrsetEdit []*RRSetChange) (*StatusResponse, error)
 	Delete(zone string, rrsetDelete []*RRSetChange) (*StatusResponse, error)
 	SubmitChangeSet(zone string, changeSet []*RRSetChange) (*StatusResponse, error)
-	EncryptTXT(key []byte, rrType *RRSetChange)
-	DecryptTXT(key []byte, rrType *RRType)
+	ValidateChangeSet(ctx context.Context, zone string, changeSet []*RRSetChange) (ChangeSetFindings, error)
+	SubmitChangeSetInChunks(zone string, changeSet []*RRSetChange, options *ChangeSetChunkOptions) (*ChangeSetSummary, error)
+	ImportZoneFile(zone string, r io.Reader, options *ZoneFileOptions) (*StatusResponse, error)
+	ExportZoneFile(ctx context.Context, zone string, w io.Writer, options *ZoneFileExportOptions) error
+	EncryptTXT(key []byte, rrType *RRSetChange) error
+	DecryptTXT(key []byte, rrType *RRType) error
+	MigrateEncryptedTXT(ctx context.Context, zone string, key []byte, verify func(plaintext string) bool) ([]*RRSetChange, error)
+	EncryptTXTWithProvider(provider KeyProvider, rrType *RRSetChange) error
+	DecryptTXTWithProvider(provider KeyProvider, rrType *RRType) error
+	RotateEncryptedTXT(ctx context.Context, zone string, provider KeyProvider) ([]*RRSetChange, error)
 }
 
 type RRType struct {
@@ -95,6 +101,36 @@ func (s *RRSetService) List(zone string, options *ListOptions) ([]*RRType, *Page
 	return rrset, page, nil
 }
 
+// ListAll walks all pages of the zone's rrsets and returns them at once.
+// The context is checked between the page requests.
+func (s *RRSetService) ListAll(ctx context.Context, zone string) ([]*RRType, error) {
+
+	var all []*RRType
+
+	options := NewListOptions()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		rrsets, page, err := s.List(zone, options)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, rrsets...)
+
+		if page.IsLastPage() || len(rrsets) == 0 {
+			break
+		}
+
+		options.SetPageNumber(page.CurrentPage + 1)
+	}
+
+	return all, nil
+}
+
 func (s *RRSetService) Create(zone string, rrsetCreate []*RRSetChange) (*StatusResponse, error) {
 
 	return s.SubmitChangeSet(zone, rrsetCreate)
@@ -111,8 +147,42 @@ func (s *RRSetService) Delete(zone string, rrsetDelete []*RRSetChange) (*StatusR
 		return s.SubmitChangeSet(zone, rrsetDelete)
 }
 
+// SubmitChangeSet adds/updates or deletes rrsets. If Client.ChangeSetChunkSize is set, large
+// change sets are submitted in chunks (see SubmitChangeSetInChunks) and the status of the
+// last chunk is returned.
+//
+// rcode0 API docs: https://my.rcodezero.at/api-doc/#api-zone-management-rrsets-patch
 func (s *RRSetService) SubmitChangeSet(zone string, changeSet []*RRSetChange) (*StatusResponse, error) {
 
+	if s.client.ValidateChangeSets {
+		for _, change := range changeSet {
+			if err := change.Validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if size := s.client.ChangeSetChunkSize; size > 0 && len(changeSet) > size {
+
+		summary, err := s.SubmitChangeSetInChunks(zone, changeSet, &ChangeSetChunkOptions{ChunkSize: size})
+		if err != nil {
+			return nil, err
+		}
+
+		return summary.Chunks[len(summary.Chunks)-1].Status, nil
+	}
+
+	return s.patchChangeSet(zone, changeSet)
+}
+
+// patchChangeSet submits the change set with a single PATCH request
+func (s *RRSetService) patchChangeSet(zone string, changeSet []*RRSetChange) (*StatusResponse, error) {
+
+	// the listing is dropped again once the response arrived, f.e. a Get running
+	// concurrently to the PATCH may have cached the old state in the meantime
+	s.client.rrsetCache.invalidate(zone)
+	defer s.client.rrsetCache.invalidate(zone)
+
 	resp, err := s.client.NewRequest().
 		SetPathParams(
 			map[string]string{
@@ -132,71 +202,40 @@ func (s *RRSetService) SubmitChangeSet(zone string, changeSet []*RRSetChange) (*
 	return s.client.ResponseToRC0StatusResponse(resp)
 }
 
-func (s *RRSetService) EncryptTXT(key []byte, rrType *RRSetChange) {
+// EncryptTXT encrypts the content of all records of the change with AES-GCM.
+// The ciphertext is stored in the versioned "ENC2:" format (see EncryptTXTValue).
+func (s *RRSetService) EncryptTXT(key []byte, rrType *RRSetChange) error {
 
 	for _, c := range rrType.Records {
-		c.Content = "\"ENC:"+encrypt(key, c.Content) +"\""
-	}
-
-}
 
-func (s *RRSetService) DecryptTXT(key []byte, rrType *RRType) {
-
-	for _, c := range rrType.Records {
-		if strings.HasPrefix(c.Content, "\"ENC:") {
-			c.Content = decrypt(key, strings.TrimSuffix(strings.TrimPrefix(c.Content, "\"ENC:"), "\""))
+		content, err := EncryptTXTValue(key, c.Content)
+		if err != nil {
+			return err
 		}
-	}
-
-}
-
-// https://gist.github.com/manishtpatel/8222606
-func encrypt(key []byte, text string) string {
-	// key := []byte(keyText)
-	plaintext := []byte(text)
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		panic(err)
-	}
-
-	// The IV needs to be unique, but not secure. Therefore it's common to
-	// include it at the beginning of the ciphertext.
-	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		panic(err)
+		c.Content = content
 	}
 
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], plaintext)
-
-	// convert to base64
-	return base64.URLEncoding.EncodeToString(ciphertext)
+	return nil
 }
 
-// https://gist.github.com/manishtpatel/8222606
-func decrypt(key []byte, cryptoText string) string {
-	ciphertext, _ := base64.URLEncoding.DecodeString(cryptoText)
+// DecryptTXT decrypts all encrypted records of the rrset. Records in the legacy "ENC:" format
+// are decrypted as well. Records which are not encrypted are left unchanged.
+func (s *RRSetService) DecryptTXT(key []byte, rrType *RRType) error {
 
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		panic(err)
-	}
+	for _, c := range rrType.Records {
 
-	// The IV needs to be unique, but not secure. Therefore it's common to
-	// include it at the beginning of the ciphertext.
-	if len(ciphertext) < aes.BlockSize {
-		panic("ciphertext too short")
-	}
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
+		if !IsEncryptedTXT(c.Content) {
+			continue
+		}
 
-	stream := cipher.NewCFBDecrypter(block, iv)
+		text, err := DecryptTXTValue(key, c.Content)
+		if err != nil {
+			return fmt.Errorf("rrset %s %s: %v", rrType.Name, rrType.Type, err)
+		}
 
-	// XORKeyStream can work in-place if the two arguments are the same.
-	stream.XORKeyStream(ciphertext, ciphertext)
+		c.Content = text
+	}
 
-	return fmt.Sprintf("%s", ciphertext)
+	return nil
 }
-
@@ -0,0 +1,146 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RRSetNotFoundError is returned by RRSetService.Get if the zone has no rrset with the given name and type
+type RRSetNotFoundError struct {
+	Zone string
+	Name string
+	Type string
+}
+
+func (e *RRSetNotFoundError) Error() string {
+	return fmt.Sprintf("rrset %s %s not found in zone %s", e.Name, e.Type, e.Zone)
+}
+
+// IsRRSetNotFound reports whether the error is a *RRSetNotFoundError
+func IsRRSetNotFound(err error) bool {
+	_, ok := err.(*RRSetNotFoundError)
+	return ok
+}
+
+// Get returns a single rrset by name and type.
+//
+// The rcode0 API does not offer filtering of rrsets, so the pages are scanned until the
+// rrset is found. If Client.RRSetCacheTTL is set, the complete listing of the zone is
+// cached for that duration and reused by subsequent calls; it is dropped when a change
+// set is submitted to the zone. The returned rrset is a copy and may be changed.
+func (s *RRSetService) Get(ctx context.Context, zone string, name string, rrType string) (*RRType, error) {
+
+	key := rrsetKey{name: NormalizeName(name), rrType: strings.ToUpper(rrType)}
+
+	notFound := &RRSetNotFoundError{Zone: zone, Name: key.name, Type: key.rrType}
+
+	if ttl := s.client.RRSetCacheTTL; ttl > 0 {
+
+		rrsets, ok := s.client.rrsetCache.get(zone)
+		if !ok {
+			var err error
+			if rrsets, err = s.ListAll(ctx, zone); err != nil {
+				return nil, err
+			}
+			s.client.rrsetCache.put(zone, rrsets, ttl)
+		}
+
+		if rrset := findRRSet(rrsets, key); rrset != nil {
+			return copyRRSet(rrset), nil
+		}
+
+		return nil, notFound
+	}
+
+	options := NewListOptions()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		rrsets, page, err := s.List(zone, options)
+		if err != nil {
+			return nil, err
+		}
+
+		if rrset := findRRSet(rrsets, key); rrset != nil {
+			return rrset, nil
+		}
+
+		if page.IsLastPage() || len(rrsets) == 0 {
+			return nil, notFound
+		}
+
+		options.SetPageNumber(page.CurrentPage + 1)
+	}
+}
+
+func findRRSet(rrsets []*RRType, key rrsetKey) *RRType {
+
+	for _, rrset := range rrsets {
+		if NormalizeName(rrset.Name) == key.name && strings.EqualFold(rrset.Type, key.rrType) {
+			return rrset
+		}
+	}
+
+	return nil
+}
+
+// copyRRSet returns a deep copy, so that callers cannot change the cached listing
+func copyRRSet(rrset *RRType) *RRType {
+	return &RRType{Name: rrset.Name, Type: rrset.Type, TTL: rrset.TTL, Records: copyRecords(rrset.Records)}
+}
+
+// rrsetCache holds the rrset listings of zones for a short time.
+// The cached listings are never handed out, Get returns copies of single rrsets.
+type rrsetCache struct {
+	mu      sync.Mutex
+	entries map[string]*rrsetCacheEntry
+}
+
+type rrsetCacheEntry struct {
+	rrsets  []*RRType
+	expires time.Time
+}
+
+func (c *rrsetCache) get(zone string) ([]*RRType, bool) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[NormalizeName(zone)]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+
+	return entry.rrsets, true
+}
+
+func (c *rrsetCache) put(zone string, rrsets []*RRType, ttl time.Duration) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*rrsetCacheEntry)
+	}
+
+	c.entries[NormalizeName(zone)] = &rrsetCacheEntry{rrsets: rrsets, expires: time.Now().Add(ttl)}
+}
+
+func (c *rrsetCache) invalidate(zone string) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, NormalizeName(zone))
+}
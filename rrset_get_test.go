@@ -0,0 +1,199 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRRSetService_Get(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var pages []string
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+
+		page := r.URL.Query().Get("page")
+		pages = append(pages, page)
+
+		data := getTestDataPaginated(reflect.TypeOf(RRType{}))
+		data["last_page"] = 3
+
+		if page == "2" {
+			data["current_page"] = 2
+			data["data"] = []interface{}{
+				map[string]interface{}{
+					"name":    "mail.testzone1.at.",
+					"type":    "MX",
+					"ttl":     3600,
+					"records": []interface{}{map[string]interface{}{"content": "10 mail.testzone1.at."}},
+				},
+			}
+		}
+
+		dat, _ := json.Marshal(data)
+		_, _ = fmt.Fprint(w, string(dat))
+	})
+
+	rrset, err := client.RRSet.Get(context.Background(), "testzone1.at", "Mail.testzone1.at", "mx")
+	if err != nil {
+		t.Fatalf("RRSet.Get returned error: %v", err)
+	}
+
+	if rrset.Name != "mail.testzone1.at." || rrset.Type != "MX" {
+		t.Errorf("RRSet.Get returned %+v", rrset)
+	}
+
+	// The scan stops at the page containing the rrset
+	if !reflect.DeepEqual(pages, []string{"1", "2"}) {
+		t.Errorf("RRSet.Get requested pages %v, want [1 2]", pages)
+	}
+}
+
+func TestRRSetService_Get_NotFound(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{}
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	_, err := client.RRSet.Get(context.Background(), "testzone1.at", "www.testzone1.at.", "A")
+
+	if !IsRRSetNotFound(err) {
+		t.Errorf("RRSet.Get returned %v, want *RRSetNotFoundError", err)
+	}
+}
+
+func TestRRSetService_Get_Cache(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	requests := 0
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			requests++
+		}
+		zone.handle(w, r)
+	})
+
+	client.RRSetCacheTTL = time.Minute
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.RRSet.Get(context.Background(), "testzone1.at", "www.testzone1.at.", "A"); err != nil {
+			t.Fatalf("RRSet.Get returned error: %v", err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("RRSet.Get sent %d requests, want 1", requests)
+	}
+
+	// Submitting a change set drops the cached listing
+	_, _ = client.RRSet.SubmitChangeSet("testzone1.at", []*RRSetChange{
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeUPDATE, TTL: 3600, Records: []*Record{{Content: "10.10.0.2"}}},
+	})
+
+	rrset, err := client.RRSet.Get(context.Background(), "testzone1.at", "www.testzone1.at.", "A")
+	if err != nil {
+		t.Fatalf("RRSet.Get returned error: %v", err)
+	}
+
+	if rrset.Records[0].Content != "10.10.0.2" || requests != 2 {
+		t.Errorf("RRSet.Get returned %s after %d requests", rrset.Records[0].Content, requests)
+	}
+}
+
+func TestRRSetService_Get_CacheReturnsCopies(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{rrsets: []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+	}}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	client.RRSetCacheTTL = time.Minute
+
+	rrset, err := client.RRSet.Get(context.Background(), "testzone1.at", "www.testzone1.at.", "A")
+	if err != nil {
+		t.Fatalf("RRSet.Get returned error: %v", err)
+	}
+
+	rrset.TTL = 60
+	rrset.Records[0].Content = "192.0.2.1"
+	rrset.Records = append(rrset.Records, &Record{Content: "192.0.2.2"})
+
+	rrset, err = client.RRSet.Get(context.Background(), "testzone1.at", "www.testzone1.at.", "A")
+	if err != nil {
+		t.Fatalf("RRSet.Get returned error: %v", err)
+	}
+
+	if rrset.TTL != 3600 || len(rrset.Records) != 1 || rrset.Records[0].Content != "10.10.0.1" {
+		t.Errorf("RRSet.Get returned %+v with %+v after the previous result was changed", rrset, rrset.Records)
+	}
+
+	if len(zone.patches) != 0 {
+		t.Errorf("RRSet.Get sent %d PATCH requests", len(zone.patches))
+	}
+}
+
+func TestRRSetService_Get_CacheInvalidatedAfterPatch(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{rrsets: []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+	}}
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			// a Get while the PATCH is in flight caches the old state
+			if _, err := client.RRSet.Get(context.Background(), "testzone1.at", "www.testzone1.at.", "A"); err != nil {
+				t.Errorf("RRSet.Get returned error: %v", err)
+			}
+		}
+		zone.handle(w, r)
+	})
+
+	client.RRSetCacheTTL = time.Minute
+
+	_, err := client.RRSet.SubmitChangeSet("testzone1.at", []*RRSetChange{
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeUPDATE, TTL: 3600, Records: []*Record{{Content: "10.10.0.2"}}},
+	})
+	if err != nil {
+		t.Fatalf("RRSet.SubmitChangeSet returned error: %v", err)
+	}
+
+	rrset, err := client.RRSet.Get(context.Background(), "testzone1.at", "www.testzone1.at.", "A")
+	if err != nil {
+		t.Fatalf("RRSet.Get returned error: %v", err)
+	}
+
+	if rrset.Records[0].Content != "10.10.0.2" {
+		t.Errorf("RRSet.Get returned %s after the change set was submitted, want 10.10.0.2", rrset.Records[0].Content)
+	}
+}
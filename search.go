@@ -0,0 +1,372 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const defaultSearchConcurrency = 4
+
+// errNotSubmitted marks replace results of plans which were not started
+var errNotSubmitted = errors.New("not submitted")
+
+// Matcher matches record content of the given type or, if rrType is empty, an owner name
+type Matcher func(rrType string, value string) bool
+
+// MatchExact matches values equal to value after normalization (see NormalizeContent and NormalizeName)
+func MatchExact(value string) Matcher {
+	return func(rrType string, v string) bool {
+		if rrType == "" {
+			return NormalizeName(v) == NormalizeName(value)
+		}
+		return NormalizeContent(rrType, v) == NormalizeContent(rrType, value)
+	}
+}
+
+// MatchCIDR matches IP addresses within the network, f.e. "10.10.0.0/24"
+func MatchCIDR(cidr string) (Matcher, error) {
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(rrType string, v string) bool {
+		ip := net.ParseIP(strings.TrimSpace(v))
+		return ip != nil && network.Contains(ip)
+	}, nil
+}
+
+// MatchRegexp matches values containing a match of the regular expression
+func MatchRegexp(expr string) (Matcher, error) {
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(rrType string, v string) bool {
+		return re.MatchString(v)
+	}, nil
+}
+
+// SearchQuery selects records across zones. All given criteria have to match.
+type SearchQuery struct {
+
+	// Zones to search, all zones of the account if empty
+	Zones []string
+
+	// Types to search (f.e. "A", "CNAME"), all types if empty
+	Types []string
+
+	// Name matches the owner name of the rrset
+	Name Matcher
+
+	// Content matches the record content
+	Content Matcher
+}
+
+// SearchResult holds the matching records of an rrset
+type SearchResult struct {
+	Zone    string
+	RRSet   *RRType
+	Records []*Record
+}
+
+// ZoneReplacePlan holds the changes of a replace for a single zone
+type ZoneReplacePlan struct {
+	Zone string
+	Diff *RRSetDiff
+}
+
+// ZoneReplaceResult is the result of applying a ZoneReplacePlan
+type ZoneReplaceResult struct {
+	Zone    string
+	Changes int
+	Status  *StatusResponse
+	Err     error
+}
+
+// AccountSearch searches and replaces records across all zones of an account
+type AccountSearch struct {
+	client *Client
+
+	// Concurrency is the maximum number of zones processed at once (defaults to 4)
+	Concurrency int
+}
+
+// NewAccountSearch returns an AccountSearch using the given client
+func NewAccountSearch(client *Client) *AccountSearch {
+	return &AccountSearch{client: client, Concurrency: defaultSearchConcurrency}
+}
+
+// Search returns the matching records of all zones, ordered by zone, name and type
+func (s *AccountSearch) Search(ctx context.Context, query *SearchQuery) ([]*SearchResult, error) {
+
+	zones := query.Zones
+
+	if len(zones) == 0 {
+		var err error
+		if zones, err = ZoneNames(ctx, s.client.Zones); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([][]*SearchResult, len(zones))
+
+	err := s.forEach(ctx, len(zones), func(i int) error {
+
+		rrsets, err := s.client.RRSet.ListAll(ctx, zones[i])
+		if err != nil {
+			return fmt.Errorf("zone %s: %v", zones[i], err)
+		}
+
+		results[i] = query.match(zones[i], rrsets)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	var all []*SearchResult
+	for _, r := range results {
+		all = append(all, r...)
+	}
+
+	return all, nil
+}
+
+// PlanReplace searches the records and replaces the content of each matching record with
+// the result of replace. The returned plans hold one diff per affected zone, f.e. for a dry run.
+func (s *AccountSearch) PlanReplace(ctx context.Context, query *SearchQuery, replace func(rrType string, content string) string) ([]*ZoneReplacePlan, error) {
+
+	results, err := s.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var plans []*ZoneReplacePlan
+
+	var current, desired []*RRType
+
+	flush := func(zone string) {
+		if diff := DiffRRSets(current, desired); !diff.IsEmpty() {
+			plans = append(plans, &ZoneReplacePlan{Zone: zone, Diff: diff})
+		}
+		current, desired = nil, nil
+	}
+
+	for i, result := range results {
+
+		matched := make(map[*Record]bool)
+		for _, r := range result.Records {
+			matched[r] = true
+		}
+
+		rrset := &RRType{Name: result.RRSet.Name, Type: result.RRSet.Type, TTL: result.RRSet.TTL}
+		seen := make(map[string]bool)
+
+		for _, r := range result.RRSet.Records {
+
+			content := r.Content
+			if matched[r] {
+				content = replace(result.RRSet.Type, content)
+			}
+
+			key := NormalizeContent(rrset.Type, content)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			rrset.Records = append(rrset.Records, &Record{Content: content, Disabled: r.Disabled})
+		}
+
+		current = append(current, result.RRSet)
+		desired = append(desired, rrset)
+
+		if i == len(results)-1 || results[i+1].Zone != result.Zone {
+			flush(result.Zone)
+		}
+	}
+
+	return plans, nil
+}
+
+// ApplyReplace submits the plans with at most Concurrency zones at once and returns one
+// result per plan. Failing zones do not stop the others. Once the context is done, the
+// remaining plans are not submitted and fail with the context's error.
+func (s *AccountSearch) ApplyReplace(ctx context.Context, plans []*ZoneReplacePlan) []*ZoneReplaceResult {
+
+	results := make([]*ZoneReplaceResult, len(plans))
+
+	for i, plan := range plans {
+		results[i] = &ZoneReplaceResult{Zone: plan.Zone, Changes: len(plan.Diff.Changes), Err: errNotSubmitted}
+	}
+
+	_ = s.forEach(ctx, len(plans), func(i int) error {
+
+		plan := plans[i]
+		result := results[i]
+
+		if err := ctx.Err(); err != nil {
+			result.Err = err
+			return nil
+		}
+
+		result.Status, result.Err = s.client.RRSet.SubmitChangeSet(plan.Zone, plan.Diff.Changes)
+
+		if result.Err == nil && result.Status.HasError() {
+			result.Err = fmt.Errorf("zone %s: %s", plan.Zone, result.Status.Message)
+		}
+
+		return nil
+	})
+
+	for _, result := range results {
+		if result.Err == errNotSubmitted && ctx.Err() != nil {
+			result.Err = ctx.Err()
+		}
+	}
+
+	return results
+}
+
+// ReplaceSummary returns one line per zone with the number of changes and the outcome
+func ReplaceSummary(results []*ZoneReplaceResult) string {
+
+	var b strings.Builder
+
+	failed := 0
+
+	for _, r := range results {
+
+		outcome := "ok"
+		if r.Err != nil {
+			outcome = "failed: " + r.Err.Error()
+			failed++
+		}
+
+		fmt.Fprintf(&b, "%s: %d change(s), %s\n", r.Zone, r.Changes, outcome)
+	}
+
+	fmt.Fprintf(&b, "%d zone(s), %d failed\n", len(results), failed)
+
+	return b.String()
+}
+
+// match returns the matching records of the rrsets
+func (q *SearchQuery) match(zone string, rrsets []*RRType) []*SearchResult {
+
+	var results []*SearchResult
+
+	for _, rrset := range rrsets {
+
+		if len(q.Types) > 0 && !containsFold(q.Types, rrset.Type) {
+			continue
+		}
+
+		if q.Name != nil && !q.Name("", rrset.Name) {
+			continue
+		}
+
+		result := &SearchResult{Zone: zone, RRSet: rrset}
+
+		for _, r := range rrset.Records {
+			if q.Content == nil || q.Content(strings.ToUpper(rrset.Type), r.Content) {
+				result.Records = append(result.Records, r)
+			}
+		}
+
+		if len(result.Records) > 0 {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		a := rrsetKey{NormalizeName(results[i].RRSet.Name), strings.ToUpper(results[i].RRSet.Type)}
+		b := rrsetKey{NormalizeName(results[j].RRSet.Name), strings.ToUpper(results[j].RRSet.Type)}
+		return a.less(b)
+	})
+
+	return results
+}
+
+// forEach calls fn for 0..n-1 with at most Concurrency calls at once and returns the
+// first error. After an error no further calls are started.
+func (s *AccountSearch) forEach(ctx context.Context, n int, fn func(i int) error) error {
+
+	concurrency := s.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultSearchConcurrency
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+
+	sem := make(chan struct{}, concurrency)
+
+	for i := 0; i < n; i++ {
+
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+
+		if failed {
+			break
+		}
+
+		if err := ctx.Err(); err != nil {
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+			break
+		}
+
+		sem <- struct{}{}
+		wg.Add(1)
+
+		go func(i int) {
+			defer func() { <-sem; wg.Done() }()
+
+			if err := fn(i); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
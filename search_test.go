@@ -0,0 +1,274 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func setupSearch(t *testing.T, zones map[string]*fakeZone) (*Client, func()) {
+
+	client, router, _, teardown := setup()
+
+	router.HandleFunc(RC0Zones, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+
+		var list []map[string]interface{}
+		for _, name := range []string{"example.at", "example.com"} {
+			if _, ok := zones[name]; ok {
+				list = append(list, map[string]interface{}{"domain": name, "type": "MASTER"})
+			}
+		}
+
+		data := getTestDataPaginated(reflect.TypeOf(Zone{}))
+		data["data"] = list
+		dat, _ := json.Marshal(data)
+		_, _ = fmt.Fprint(w, string(dat))
+	})
+
+	router.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		zones[mux.Vars(r)["zone"]].handle(w, r)
+	})
+
+	return client, teardown
+}
+
+func searchTestZones() map[string]*fakeZone {
+	return map[string]*fakeZone{
+		"example.at": {rrsets: []*RRType{
+			{Name: "www.example.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.2"}, {Content: "192.0.2.1"}}},
+			{Name: "mail.example.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.3"}}},
+			{Name: "example.at.", Type: "MX", TTL: 3600, Records: []*Record{{Content: "10 mail.example.at."}}},
+		}},
+		"example.com": {rrsets: []*RRType{
+			{Name: "www.example.com.", Type: "A", TTL: 600, Records: []*Record{{Content: "10.10.0.2"}}},
+			{Name: "old.example.com.", Type: "CNAME", TTL: 600, Records: []*Record{{Content: "legacy.example.net."}}},
+		}},
+	}
+}
+
+func TestMatchers(t *testing.T) {
+
+	cidr, err := MatchCIDR("10.10.0.0/24")
+	if err != nil {
+		t.Fatalf("MatchCIDR returned error: %v", err)
+	}
+
+	re, err := MatchRegexp(`^legacy\.`)
+	if err != nil {
+		t.Fatalf("MatchRegexp returned error: %v", err)
+	}
+
+	tests := []struct {
+		matcher Matcher
+		rrType  string
+		value   string
+		want    bool
+	}{
+		{MatchExact("WWW.example.at"), "", "www.example.at.", true},
+		{MatchExact("www.example.at."), "", "mail.example.at.", false},
+		{MatchExact("Mail.Example.at"), "CNAME", "mail.example.at.", true},
+		{MatchExact("2001:DB8::1"), "AAAA", "2001:db8::1", true},
+		{cidr, "A", "10.10.0.200", true},
+		{cidr, "A", "10.10.1.1", false},
+		{cidr, "CNAME", "www.example.at.", false},
+		{re, "CNAME", "legacy.example.net.", true},
+		{re, "CNAME", "new.legacy.example.net.", false},
+	}
+
+	for i, test := range tests {
+		if got := test.matcher(test.rrType, test.value); got != test.want {
+			t.Errorf("case %d: matching %q returned %v, want %v", i, test.value, got, test.want)
+		}
+	}
+
+	if _, err := MatchCIDR("10.10.0.0"); err == nil {
+		t.Error("MatchCIDR accepted an address without prefix length")
+	}
+
+	if _, err := MatchRegexp("("); err == nil {
+		t.Error("MatchRegexp accepted an invalid expression")
+	}
+}
+
+func TestAccountSearch_Search(t *testing.T) {
+
+	client, teardown := setupSearch(t, searchTestZones())
+	defer teardown()
+
+	cidr, _ := MatchCIDR("10.10.0.0/24")
+
+	results, err := NewAccountSearch(client).Search(context.Background(), &SearchQuery{Content: cidr})
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+
+	var got []string
+	for _, r := range results {
+		for _, record := range r.Records {
+			got = append(got, r.Zone+" "+r.RRSet.Name+" "+record.Content)
+		}
+	}
+
+	want := []string{
+		"example.at mail.example.at. 10.10.0.3",
+		"example.at www.example.at. 10.10.0.2",
+		"example.com www.example.com. 10.10.0.2",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Search returned %v, want %v", got, want)
+	}
+
+	results, err = NewAccountSearch(client).Search(context.Background(), &SearchQuery{
+		Zones: []string{"example.at"},
+		Types: []string{"mx"},
+		Name:  MatchExact("example.at"),
+	})
+	if err != nil {
+		t.Fatalf("Search returned error: %v", err)
+	}
+
+	if len(results) != 1 || results[0].RRSet.Type != "MX" {
+		t.Errorf("Search by name and type returned %+v", results)
+	}
+}
+
+func TestAccountSearch_Replace(t *testing.T) {
+
+	zones := searchTestZones()
+
+	client, teardown := setupSearch(t, zones)
+	defer teardown()
+
+	search := NewAccountSearch(client)
+	search.Concurrency = 1
+
+	plans, err := search.PlanReplace(context.Background(), &SearchQuery{Content: MatchExact("10.10.0.2")}, func(rrType string, content string) string {
+		return "10.20.0.2"
+	})
+	if err != nil {
+		t.Fatalf("PlanReplace returned error: %v", err)
+	}
+
+	if len(plans) != 2 || plans[0].Zone != "example.at" || plans[1].Zone != "example.com" {
+		t.Fatalf("PlanReplace returned %+v", plans)
+	}
+
+	for _, zone := range zones {
+		if len(zone.patches) > 0 {
+			t.Fatal("PlanReplace submitted changes")
+		}
+	}
+
+	if s := plans[0].Diff.String(); !strings.Contains(s, "10.20.0.2") || !strings.Contains(s, "www.example.at.") {
+		t.Errorf("dry-run diff does not show the replacement:\n%s", s)
+	}
+
+	results := search.ApplyReplace(context.Background(), plans)
+
+	for _, r := range results {
+		if r.Err != nil || r.Changes != 1 {
+			t.Errorf("ApplyReplace for %s returned %+v", r.Zone, r)
+		}
+	}
+
+	var contents []string
+	for _, rrset := range zones["example.at"].rrsets {
+		if rrset.Name == "www.example.at." {
+			for _, record := range rrset.Records {
+				contents = append(contents, record.Content)
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(contents, []string{"10.20.0.2", "192.0.2.1"}) {
+		t.Errorf("www.example.at. has records %v after replace", contents)
+	}
+
+	want := "example.at: 1 change(s), ok\nexample.com: 1 change(s), ok\n2 zone(s), 0 failed\n"
+	if got := ReplaceSummary(results); got != want {
+		t.Errorf("ReplaceSummary returned %q, want %q", got, want)
+	}
+}
+
+func TestAccountSearch_ApplyReplacePartialFailure(t *testing.T) {
+
+	zones := searchTestZones()
+	zones["example.com"].failPatch = func(n int, changeSet []*RRSetChange) bool { return true }
+
+	client, teardown := setupSearch(t, zones)
+	defer teardown()
+
+	search := NewAccountSearch(client)
+
+	plans, err := search.PlanReplace(context.Background(), &SearchQuery{Types: []string{"A"}}, func(rrType string, content string) string {
+		return strings.Replace(content, "10.10.", "10.30.", 1)
+	})
+	if err != nil {
+		t.Fatalf("PlanReplace returned error: %v", err)
+	}
+
+	results := search.ApplyReplace(context.Background(), plans)
+
+	if len(results) != 2 || results[0].Err != nil || results[1].Err == nil {
+		t.Fatalf("ApplyReplace returned %+v", results)
+	}
+
+	if results[0].Changes != 2 {
+		t.Errorf("example.at had %d changes, want 2", results[0].Changes)
+	}
+
+	if summary := ReplaceSummary(results); !strings.HasSuffix(summary, "2 zone(s), 1 failed\n") {
+		t.Errorf("ReplaceSummary returned %q", summary)
+	}
+}
+
+func TestAccountSearch_ApplyReplaceCancelled(t *testing.T) {
+
+	zones := searchTestZones()
+
+	client, teardown := setupSearch(t, zones)
+	defer teardown()
+
+	search := NewAccountSearch(client)
+
+	plans, err := search.PlanReplace(context.Background(), &SearchQuery{Content: MatchExact("10.10.0.2")}, func(rrType string, content string) string {
+		return "10.20.0.2"
+	})
+	if err != nil || len(plans) != 2 {
+		t.Fatalf("PlanReplace returned %d plans, %v", len(plans), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := search.ApplyReplace(ctx, plans)
+
+	for i, r := range results {
+		if r == nil || r.Zone != plans[i].Zone || r.Err != context.Canceled {
+			t.Errorf("ApplyReplace with cancelled context returned %+v for %s", r, plans[i].Zone)
+		}
+	}
+
+	for name, zone := range zones {
+		if len(zone.patches) > 0 {
+			t.Errorf("ApplyReplace with cancelled context submitted changes to %s", name)
+		}
+	}
+
+	want := "example.at: 1 change(s), failed: context canceled\nexample.com: 1 change(s), failed: context canceled\n2 zone(s), 2 failed\n"
+	if got := ReplaceSummary(results); got != want {
+		t.Errorf("ReplaceSummary returned %q, want %q", got, want)
+	}
+}
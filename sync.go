@@ -0,0 +1,164 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// SyncScope defines the part of a zone which is managed by a RecordSync.
+// Records outside of the scope are neither changed nor deleted.
+type SyncScope struct {
+
+	// Names are glob patterns (see path.Match) for the owner names in scope, f.e. "_acme-challenge.*"
+	// or "*.svc.example.at.". Patterns without trailing dot are relative to the zone.
+	// An empty list matches all names.
+	Names []string
+
+	// Types in scope (f.e. "A", "TXT"). An empty list matches all types.
+	Types []string
+}
+
+// Contains reports whether the rrset with the given name and type in the zone is in scope
+func (s *SyncScope) Contains(zone string, name string, rrType string) bool {
+
+	if s == nil {
+		return true
+	}
+
+	if len(s.Types) > 0 {
+		found := false
+		for _, t := range s.Types {
+			if strings.EqualFold(t, rrType) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(s.Names) == 0 {
+		return true
+	}
+
+	name = NormalizeName(name)
+	origin := NormalizeName(zone)
+
+	for _, pattern := range s.Names {
+
+		pattern = strings.ToLower(pattern)
+		if !strings.HasSuffix(pattern, ".") {
+			pattern = absoluteName(pattern, origin)
+		}
+
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SyncPlan holds the changes needed to bring the scoped part of a zone into the desired state
+type SyncPlan struct {
+	Zone string
+	Diff *RRSetDiff
+}
+
+// RecordSync manages a subset of a zone's rrsets declaratively
+type RecordSync struct {
+	rrset RRSetServiceInterface
+	scope *SyncScope
+}
+
+// NewRecordSync returns a RecordSync which only touches rrsets within the given scope.
+// The SOA and NS rrsets of the zone apex are never touched, even with a nil scope.
+func NewRecordSync(rrset RRSetServiceInterface, scope *SyncScope) *RecordSync {
+	return &RecordSync{rrset: rrset, scope: scope}
+}
+
+// manages reports whether the rrset is in scope and not the apex SOA or NS rrset
+func (s *RecordSync) manages(zone string, name string, rrType string) bool {
+
+	if NormalizeName(name) == NormalizeName(zone) && (strings.EqualFold(rrType, "SOA") || strings.EqualFold(rrType, "NS")) {
+		return false
+	}
+
+	return s.scope.Contains(zone, name, rrType)
+}
+
+// Plan computes the changes needed to make the scoped rrsets of the zone equal to the desired ones.
+// Desired rrsets outside of the scope are rejected. Scoped rrsets which are not desired are deleted.
+func (s *RecordSync) Plan(ctx context.Context, zone string, desired []*RRType) (*SyncPlan, error) {
+
+	for _, rrset := range desired {
+		if !s.manages(zone, rrset.Name, rrset.Type) {
+			return nil, fmt.Errorf("desired rrset %s %s is outside of the sync scope", rrset.Name, rrset.Type)
+		}
+	}
+
+	all, err := s.rrset.ListAll(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var current []*RRType
+	for _, rrset := range all {
+		if s.manages(zone, rrset.Name, rrset.Type) {
+			current = append(current, rrset)
+		}
+	}
+
+	return &SyncPlan{Zone: zone, Diff: DiffRRSets(current, desired)}, nil
+}
+
+// Apply submits the changes of the plan. Plans containing changes outside of the scope are refused.
+// Nothing is submitted for an empty plan and the returned status is nil.
+func (s *RecordSync) Apply(ctx context.Context, plan *SyncPlan) (*StatusResponse, error) {
+
+	for _, change := range plan.Diff.Changes {
+		if !s.manages(plan.Zone, change.Name, change.Type) {
+			return nil, fmt.Errorf("refusing to %s rrset %s %s outside of the sync scope", change.ChangeType, change.Name, change.Type)
+		}
+	}
+
+	if plan.Diff.IsEmpty() {
+		return nil, nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	status, err := s.rrset.SubmitChangeSet(plan.Zone, plan.Diff.Changes)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.HasError() {
+		return status, fmt.Errorf("sync of zone %s failed: %s", plan.Zone, status.Message)
+	}
+
+	return status, nil
+}
+
+// Sync plans and applies the desired state of the scoped rrsets in one step
+func (s *RecordSync) Sync(ctx context.Context, zone string, desired []*RRType) (*SyncPlan, *StatusResponse, error) {
+
+	plan, err := s.Plan(ctx, zone, desired)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, err := s.Apply(ctx, plan)
+
+	return plan, status, err
+}
@@ -0,0 +1,176 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestSyncScope_Contains(t *testing.T) {
+
+	scope := &SyncScope{
+		Names: []string{"_acme-challenge", "_acme-challenge.*", "*.svc.testzone1.at."},
+		Types: []string{"TXT", "A"},
+	}
+
+	tests := []struct {
+		name   string
+		rrType string
+		want   bool
+	}{
+		{"_acme-challenge.testzone1.at.", "TXT", true},
+		{"_acme-challenge.www.testzone1.at.", "TXT", true},
+		{"api.svc.testzone1.at.", "A", true},
+		{"API.svc.testzone1.at", "a", true},
+		{"api.svc.testzone1.at.", "AAAA", false},
+		{"www.testzone1.at.", "A", false},
+		{"svc.testzone1.at.", "A", false},
+	}
+
+	for _, test := range tests {
+		if got := scope.Contains("testzone1.at", test.name, test.rrType); got != test.want {
+			t.Errorf("SyncScope.Contains(%s, %s) returned %v, want %v", test.name, test.rrType, got, test.want)
+		}
+	}
+}
+
+func TestRecordSync_Sync(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var received []*RRSetChange
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method == "PATCH" {
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &received)
+			_, _ = fmt.Fprint(w, `{"status": "ok", "message": "RRsets updated"}`)
+			return
+		}
+
+		data := getTestDataPaginated(reflect.TypeOf(RRType{}))
+		data["data"] = []interface{}{
+			getSampleRRSet(),
+			map[string]interface{}{
+				"name":    "old.svc.testzone1.at.",
+				"type":    "A",
+				"ttl":     3600,
+				"records": []interface{}{map[string]interface{}{"content": "10.10.1.9"}},
+			},
+		}
+
+		dat, _ := json.Marshal(data)
+		_, _ = fmt.Fprint(w, string(dat))
+	})
+
+	sync := NewRecordSync(client.RRSet, &SyncScope{Names: []string{"*.svc"}})
+
+	desired := []*RRType{
+		{Name: "api.svc.testzone1.at.", Type: "A", TTL: 300, Records: []*Record{{Content: "10.10.1.1"}}},
+	}
+
+	plan, status, err := sync.Sync(context.Background(), "testzone1.at", desired)
+	if err != nil {
+		t.Fatalf("RecordSync.Sync returned error: %v", err)
+	}
+
+	if status == nil || status.HasError() {
+		t.Errorf("RecordSync.Sync returned status %+v", status)
+	}
+
+	want := []*RRSetChange{
+		{Name: "api.svc.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 300, Records: []*Record{{Content: "10.10.1.1"}}},
+		{Name: "old.svc.testzone1.at.", Type: "A", ChangeType: ChangeTypeDELETE},
+	}
+
+	if !reflect.DeepEqual(plan.Diff.Changes, want) {
+		t.Errorf("RecordSync.Plan returned %+v, want %+v", plan.Diff.Changes, want)
+	}
+
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("RecordSync.Apply submitted %+v, want %+v", received, want)
+	}
+}
+
+func TestRecordSync_OutOfScope(t *testing.T) {
+
+	client, _, _, teardown := setup()
+	defer teardown()
+
+	sync := NewRecordSync(client.RRSet, &SyncScope{Names: []string{"*.svc"}})
+
+	desired := []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 300, Records: []*Record{{Content: "10.10.1.1"}}},
+	}
+
+	if _, err := sync.Plan(context.Background(), "testzone1.at", desired); err == nil {
+		t.Errorf("RecordSync.Plan returned no error for rrset outside of the scope")
+	}
+
+	plan := &SyncPlan{
+		Zone: "testzone1.at",
+		Diff: &RRSetDiff{Changes: []*RRSetChange{{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeDELETE}}},
+	}
+
+	if _, err := sync.Apply(context.Background(), plan); err == nil {
+		t.Errorf("RecordSync.Apply returned no error for change outside of the scope")
+	}
+}
+
+func TestRecordSync_KeepsApex(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{rrsets: []*RRType{
+		{Name: "testzone1.at.", Type: "SOA", TTL: 3600, Records: []*Record{{Content: "sec1.rcode0.net. rcode0.nic.at. 2019010101 10800 3600 604800 3600"}}},
+		{Name: "testzone1.at.", Type: "NS", TTL: 3600, Records: []*Record{{Content: "sec1.rcode0.net."}}},
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+	}}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	// a nil scope covers the whole zone except the apex SOA and NS rrsets
+	sync := NewRecordSync(client.RRSet, nil)
+
+	plan, err := sync.Plan(context.Background(), "testzone1.at", nil)
+	if err != nil {
+		t.Fatalf("RecordSync.Plan returned error: %v", err)
+	}
+
+	want := []*RRSetChange{{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeDELETE}}
+
+	if !reflect.DeepEqual(plan.Diff.Changes, want) {
+		t.Errorf("RecordSync.Plan returned %+v, want %+v", plan.Diff.Changes, want)
+	}
+
+	desired := []*RRType{{Name: "testzone1.at.", Type: "NS", TTL: 3600, Records: []*Record{{Content: "sec2.rcode0.net."}}}}
+
+	if _, err := sync.Plan(context.Background(), "testzone1.at", desired); err == nil {
+		t.Errorf("RecordSync.Plan returned no error for the apex NS rrset")
+	}
+
+	plan = &SyncPlan{
+		Zone: "testzone1.at",
+		Diff: &RRSetDiff{Changes: []*RRSetChange{{Name: "testzone1.at.", Type: "SOA", ChangeType: ChangeTypeDELETE}}},
+	}
+
+	if _, err := sync.Apply(context.Background(), plan); err == nil {
+		t.Errorf("RecordSync.Apply returned no error for the apex SOA rrset")
+	}
+
+	if len(zone.patches) != 0 {
+		t.Errorf("RecordSync sent %d PATCH requests", len(zone.patches))
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package takeover
+
+import (
+	"github.com/nic-at/rc0go"
+	"strings"
+)
+
+// Fingerprint describes targets of a provider where a deleted resource (f.e. a bucket or an
+// app) can be claimed by someone else, who then serves content for the pointing name
+type Fingerprint struct {
+	Provider string
+
+	// Suffixes of the target names, f.e. ".s3.amazonaws.com."
+	Suffixes []string
+}
+
+// Matches reports whether the target belongs to the provider
+func (f *Fingerprint) Matches(target string) bool {
+
+	target = rc0go.NormalizeName(target)
+
+	for _, suffix := range f.Suffixes {
+		if strings.HasSuffix(target, rc0go.NormalizeName(suffix)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DefaultFingerprints returns takeover-prone providers known at the time of writing
+func DefaultFingerprints() []*Fingerprint {
+	return []*Fingerprint{
+		{Provider: "AWS S3", Suffixes: []string{".s3.amazonaws.com", ".s3-website.amazonaws.com"}},
+		{Provider: "AWS CloudFront", Suffixes: []string{".cloudfront.net"}},
+		{Provider: "AWS Elastic Beanstalk", Suffixes: []string{".elasticbeanstalk.com"}},
+		{Provider: "Azure", Suffixes: []string{
+			".azurewebsites.net", ".cloudapp.net", ".cloudapp.azure.com", ".trafficmanager.net",
+			".blob.core.windows.net", ".azureedge.net", ".azure-api.net",
+		}},
+		{Provider: "GitHub Pages", Suffixes: []string{".github.io"}},
+		{Provider: "Heroku", Suffixes: []string{".herokuapp.com", ".herokudns.com"}},
+		{Provider: "Fastly", Suffixes: []string{".fastly.net"}},
+		{Provider: "Netlify", Suffixes: []string{".netlify.app", ".netlify.com"}},
+		{Provider: "Shopify", Suffixes: []string{".myshopify.com"}},
+		{Provider: "Google Cloud Storage", Suffixes: []string{"c.storage.googleapis.com"}},
+	}
+}
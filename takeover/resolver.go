@@ -0,0 +1,74 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package takeover
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"time"
+)
+
+// Resolver sends DNS queries for the scanner
+type Resolver interface {
+
+	// Resolve asks the recursive resolver
+	Resolve(ctx context.Context, name string, qtype uint16) (*dns.Msg, error)
+
+	// Query asks the server (f.e. "193.0.2.53:53") directly without recursion
+	Query(ctx context.Context, server string, name string, qtype uint16) (*dns.Msg, error)
+}
+
+// DNSResolver is a Resolver using a recursive name server, f.e. "193.0.2.53:53" or a local
+// stub in tests
+type DNSResolver struct {
+	Nameserver string
+	Client     *dns.Client
+}
+
+// NewDNSResolver returns a DNSResolver using UDP with a timeout of 5 seconds
+func NewDNSResolver(nameserver string) *DNSResolver {
+	return &DNSResolver{Nameserver: nameserver, Client: &dns.Client{Timeout: 5 * time.Second}}
+}
+
+// Resolve implements Resolver
+func (r *DNSResolver) Resolve(ctx context.Context, name string, qtype uint16) (*dns.Msg, error) {
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+
+	return r.exchange(ctx, msg, r.Nameserver)
+}
+
+// Query implements Resolver
+func (r *DNSResolver) Query(ctx context.Context, server string, name string, qtype uint16) (*dns.Msg, error) {
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+	msg.RecursionDesired = false
+
+	return r.exchange(ctx, msg, server)
+}
+
+func (r *DNSResolver) exchange(ctx context.Context, msg *dns.Msg, server string) (*dns.Msg, error) {
+
+	client := r.Client
+	if client == nil {
+		client = new(dns.Client)
+	}
+
+	resp, _, err := client.ExchangeContext(ctx, msg, server)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Truncated {
+		tcp := *client
+		tcp.Net = "tcp"
+		resp, _, err = tcp.ExchangeContext(ctx, msg, server)
+	}
+
+	return resp, err
+}
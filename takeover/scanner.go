@@ -0,0 +1,335 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package takeover finds dangling CNAME and NS records which could be used to take over
+// names of rcode0 zones
+package takeover
+
+import (
+	"context"
+	"fmt"
+	"github.com/miekg/dns"
+	"github.com/nic-at/rc0go"
+	"net"
+	"strings"
+	"text/tabwriter"
+)
+
+// Severity of a finding
+type Severity string
+
+const (
+	SeverityCritical Severity = "critical"
+	SeverityHigh     Severity = "high"
+	SeverityMedium   Severity = "medium"
+	SeverityLow      Severity = "low"
+)
+
+// Kinds of findings
+const (
+	// KindTakeover is a target of a takeover-prone provider which does not exist
+	KindTakeover = "takeover"
+
+	// KindDangling is a target which does not exist (NXDOMAIN)
+	KindDangling = "dangling"
+
+	// KindTakeoverProne is an existing target of a takeover-prone provider, which has to
+	// be removed from the zone before the resource at the provider is deleted
+	KindTakeoverProne = "takeover-prone"
+
+	// KindLameDelegation is a nameserver which does not answer authoritatively for the delegation
+	KindLameDelegation = "lame-delegation"
+
+	// KindUnresolved is a target which could not be resolved, f.e. because of a timeout
+	KindUnresolved = "unresolved"
+)
+
+// aliasTypes are the rrset types whose content is a single target name
+var aliasTypes = []string{"CNAME", "ALIAS", "ANAME", "DNAME"}
+
+// Finding is a suspicious record
+type Finding struct {
+	Kind     string
+	Severity Severity
+	Name     string
+	Type     string
+	Target   string
+	Message  string
+}
+
+// ZoneReport holds the findings of a zone
+type ZoneReport struct {
+	Zone     string
+	Findings []*Finding
+	Err      error
+}
+
+// Report holds the findings of all scanned zones
+type Report struct {
+	Zones []*ZoneReport
+}
+
+// Findings returns the number of findings of all zones
+func (r *Report) Findings() int {
+
+	n := 0
+	for _, z := range r.Zones {
+		n += len(z.Findings)
+	}
+
+	return n
+}
+
+// String returns one line per finding, grouped by zone
+func (r *Report) String() string {
+
+	var b strings.Builder
+
+	w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+
+	for _, z := range r.Zones {
+
+		switch {
+		case z.Err != nil:
+			fmt.Fprintf(w, "%s\tfailed: %v\n", z.Zone, z.Err)
+		case len(z.Findings) == 0:
+			fmt.Fprintf(w, "%s\tok\n", z.Zone)
+		}
+
+		for _, f := range z.Findings {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s %s -> %s\t%s\n", z.Zone, f.Severity, f.Kind, f.Name, f.Type, f.Target, f.Message)
+		}
+	}
+
+	_ = w.Flush()
+
+	return b.String()
+}
+
+// Scanner checks the CNAME-like and NS rrsets of zones
+type Scanner struct {
+	Zones    rc0go.ZoneManagementServiceInterface
+	RRSet    rc0go.RRSetServiceInterface
+	Resolver Resolver
+
+	Fingerprints []*Fingerprint
+
+	// NameserverPort is used to query delegated nameservers (defaults to "53")
+	NameserverPort string
+
+	// CheckApexNS checks the nameservers of the zone apex as well as the delegations
+	CheckApexNS bool
+}
+
+// NewScanner returns a scanner with the default fingerprints
+func NewScanner(client *rc0go.Client, resolver Resolver) *Scanner {
+	return &Scanner{
+		Zones:          client.Zones,
+		RRSet:          client.RRSet,
+		Resolver:       resolver,
+		Fingerprints:   DefaultFingerprints(),
+		NameserverPort: "53",
+	}
+}
+
+// Scan checks the zones, or all zones of the account if none are given. A zone which cannot
+// be listed is reported with its error and does not stop the others.
+func (s *Scanner) Scan(ctx context.Context, zones ...string) (*Report, error) {
+
+	if len(zones) == 0 {
+		var err error
+		if zones, err = rc0go.ZoneNames(ctx, s.Zones); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &Report{}
+
+	for _, zone := range zones {
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		z := &ZoneReport{Zone: zone}
+		report.Zones = append(report.Zones, z)
+
+		rrsets, err := s.RRSet.ListAll(ctx, zone)
+		if err != nil {
+			z.Err = err
+			continue
+		}
+
+		z.Findings = s.Check(ctx, zone, rrsets)
+	}
+
+	return report, nil
+}
+
+// Check returns the findings of the zone's rrsets
+func (s *Scanner) Check(ctx context.Context, zone string, rrsets []*rc0go.RRType) []*Finding {
+
+	var findings []*Finding
+
+	origin := rc0go.NormalizeName(zone)
+
+	for _, rrset := range rrsets {
+
+		rrType := strings.ToUpper(rrset.Type)
+
+		for _, r := range rrset.Records {
+
+			if r.Disabled {
+				continue
+			}
+
+			switch {
+			case containsType(aliasTypes, rrType):
+				if f := s.checkAlias(ctx, rrset, r.Content); f != nil {
+					findings = append(findings, f)
+				}
+
+			case rrType == "NS" && (s.CheckApexNS || rc0go.NormalizeName(rrset.Name) != origin):
+				if f := s.checkNS(ctx, rrset, r.Content); f != nil {
+					findings = append(findings, f)
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+func (s *Scanner) checkAlias(ctx context.Context, rrset *rc0go.RRType, target string) *Finding {
+
+	target = rc0go.NormalizeName(target)
+
+	f := &Finding{Name: rrset.Name, Type: rrset.Type, Target: target}
+
+	resp, err := s.Resolver.Resolve(ctx, target, dns.TypeA)
+	if err != nil || (resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError) {
+		f.Kind, f.Severity, f.Message = KindUnresolved, SeverityLow, resolveError(resp, err)
+		return f
+	}
+
+	// the chain of the target may lead to a provider, f.e. shop.example.at -> shop.cdn.example.at -> x.cloudfront.net
+	provider := s.provider(target)
+	for _, rr := range resp.Answer {
+		if cname, ok := rr.(*dns.CNAME); ok && provider == nil {
+			provider = s.provider(cname.Target)
+		}
+	}
+
+	switch {
+	case resp.Rcode == dns.RcodeNameError && provider != nil:
+		f.Kind, f.Severity = KindTakeover, SeverityCritical
+		f.Message = fmt.Sprintf("target does not exist, the name can be claimed at %s", provider.Provider)
+
+	case resp.Rcode == dns.RcodeNameError:
+		f.Kind, f.Severity, f.Message = KindDangling, SeverityHigh, "target does not exist (NXDOMAIN)"
+
+	case provider != nil:
+		f.Kind, f.Severity = KindTakeoverProne, SeverityMedium
+		f.Message = fmt.Sprintf("target at %s, remove the record before deleting the resource", provider.Provider)
+
+	default:
+		return nil
+	}
+
+	return f
+}
+
+func (s *Scanner) checkNS(ctx context.Context, rrset *rc0go.RRType, nameserver string) *Finding {
+
+	nameserver = rc0go.NormalizeName(nameserver)
+
+	f := &Finding{Name: rrset.Name, Type: rrset.Type, Target: nameserver}
+
+	var addresses []string
+
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+
+		resp, err := s.Resolver.Resolve(ctx, nameserver, qtype)
+		if err != nil || (resp.Rcode != dns.RcodeSuccess && resp.Rcode != dns.RcodeNameError) {
+			f.Kind, f.Severity, f.Message = KindUnresolved, SeverityLow, resolveError(resp, err)
+			return f
+		}
+
+		if resp.Rcode == dns.RcodeNameError {
+			f.Kind, f.Severity = KindDangling, SeverityHigh
+			f.Message = "nameserver does not exist (NXDOMAIN), its domain may be registrable"
+			return f
+		}
+
+		for _, rr := range resp.Answer {
+			switch a := rr.(type) {
+			case *dns.A:
+				addresses = append(addresses, a.A.String())
+			case *dns.AAAA:
+				addresses = append(addresses, a.AAAA.String())
+			}
+		}
+	}
+
+	if len(addresses) == 0 {
+		f.Kind, f.Severity, f.Message = KindLameDelegation, SeverityHigh, "nameserver has no address"
+		return f
+	}
+
+	port := s.NameserverPort
+	if port == "" {
+		port = "53"
+	}
+
+	var reasons []string
+
+	for _, address := range addresses {
+
+		resp, err := s.Resolver.Query(ctx, net.JoinHostPort(address, port), rrset.Name, dns.TypeSOA)
+
+		switch {
+		case err != nil:
+			reasons = append(reasons, fmt.Sprintf("%s: %v", address, err))
+		case resp.Rcode != dns.RcodeSuccess:
+			reasons = append(reasons, fmt.Sprintf("%s: %s", address, dns.RcodeToString[resp.Rcode]))
+		case !resp.Authoritative:
+			reasons = append(reasons, fmt.Sprintf("%s: not authoritative", address))
+		default:
+			return nil
+		}
+	}
+
+	f.Kind, f.Severity = KindLameDelegation, SeverityHigh
+	f.Message = "no authoritative answer (" + strings.Join(reasons, ", ") + ")"
+
+	return f
+}
+
+func (s *Scanner) provider(target string) *Fingerprint {
+
+	for _, fp := range s.Fingerprints {
+		if fp.Matches(target) {
+			return fp
+		}
+	}
+
+	return nil
+}
+
+func resolveError(resp *dns.Msg, err error) string {
+	if err != nil {
+		return fmt.Sprintf("resolving failed: %v", err)
+	}
+	return fmt.Sprintf("resolving failed: %s", dns.RcodeToString[resp.Rcode])
+}
+
+func containsType(types []string, rrType string) bool {
+	for _, t := range types {
+		if t == rrType {
+			return true
+		}
+	}
+	return false
+}
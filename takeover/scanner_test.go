@@ -0,0 +1,240 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package takeover
+
+import (
+	"context"
+	"github.com/miekg/dns"
+	"github.com/nic-at/rc0go/rc0test"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stub is a local DNS server answering from a fixed set of records. Names without records
+// are NXDOMAIN, names in servfail fail and SOA queries for names in authoritative are
+// answered with the AA flag.
+type stub struct {
+	records       map[string][]dns.RR
+	authoritative map[string]bool
+	servfail      map[string]bool
+}
+
+func newStub(t *testing.T, records ...string) *stub {
+
+	s := &stub{
+		records:       make(map[string][]dns.RR),
+		authoritative: make(map[string]bool),
+		servfail:      make(map[string]bool),
+	}
+
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil {
+			t.Fatalf("invalid stub record %q: %v", record, err)
+		}
+		name := strings.ToLower(rr.Header().Name)
+		s.records[name] = append(s.records[name], rr)
+	}
+
+	return s
+}
+
+func (s *stub) ServeDNS(w dns.ResponseWriter, req *dns.Msg) {
+
+	resp := new(dns.Msg)
+	resp.SetReply(req)
+
+	q := req.Question[0]
+	name := strings.ToLower(q.Name)
+
+	switch {
+	case s.servfail[name]:
+		resp.Rcode = dns.RcodeServerFailure
+
+	case q.Qtype == dns.TypeSOA && s.authoritative[name]:
+		resp.Authoritative = true
+		soa, _ := dns.NewRR(name + " 300 IN SOA ns1." + name + " hostmaster." + name + " 1 3600 600 604800 300")
+		resp.Answer = append(resp.Answer, soa)
+
+	case q.Qtype == dns.TypeSOA && len(s.records[name]) > 0:
+		// a lame server answers from its cache without AA
+
+	default:
+		for i := 0; i < 8; i++ {
+			rrs, ok := s.records[name]
+			if !ok {
+				resp.Rcode = dns.RcodeNameError
+				break
+			}
+			var next string
+			for _, rr := range rrs {
+				if rr.Header().Rrtype == q.Qtype {
+					resp.Answer = append(resp.Answer, rr)
+				} else if cname, ok := rr.(*dns.CNAME); ok {
+					resp.Answer = append(resp.Answer, rr)
+					next = strings.ToLower(cname.Target)
+				}
+			}
+			if next == "" {
+				break
+			}
+			name = next
+		}
+	}
+
+	_ = w.WriteMsg(resp)
+}
+
+// start serves the stub on a local UDP port and returns its address
+func (s *stub) start(t *testing.T) (string, func()) {
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	started := make(chan struct{})
+	server := &dns.Server{PacketConn: conn, Handler: s, NotifyStartedFunc: func() { close(started) }}
+
+	go func() { _ = server.ActivateAndServe() }()
+	<-started
+
+	return conn.LocalAddr().String(), func() { _ = server.Shutdown() }
+}
+
+func TestScanner_Scan(t *testing.T) {
+
+	dnsStub := newStub(t,
+		"www.example.at. 300 IN A 192.0.2.1",
+		"assets.example.at. 300 IN CNAME example-assets.s3.amazonaws.com.",
+		"example-assets.s3.amazonaws.com. 300 IN A 192.0.2.2",
+		"shop.cdn.example.net. 300 IN CNAME d111.cloudfront.net.",
+		"ns1.example.net. 300 IN A 127.0.0.1",
+		"ns2.example.net. 300 IN A 127.0.0.1",
+	)
+	dnsStub.authoritative["sub.example.at."] = true
+	dnsStub.servfail["broken.example.net."] = true
+
+	address, shutdown := dnsStub.start(t)
+	defer shutdown()
+
+	_, port, _ := net.SplitHostPort(address)
+
+	api := rc0test.NewServer()
+	defer api.Close()
+
+	api.AddZone("example.at",
+		rc0test.RRSet("example.at.", "NS", "ns1.rcode0.net.", "ns2.rcode0.net."),
+		rc0test.RRSet("www.example.at.", "A", "192.0.2.1"),
+		rc0test.RRSet("app.example.at.", "CNAME", "www.example.at."),
+		rc0test.RRSet("old.example.at.", "CNAME", "gone.example.net."),
+		rc0test.RRSet("bucket.example.at.", "CNAME", "deleted-bucket.s3.amazonaws.com."),
+		rc0test.RRSet("static.example.at.", "CNAME", "assets.example.at."),
+		rc0test.RRSet("shop.example.at.", "CNAME", "shop.cdn.example.net."),
+		rc0test.RRSet("flaky.example.at.", "CNAME", "broken.example.net."),
+		rc0test.RRSet("sub.example.at.", "NS", "ns1.example.net."),
+		rc0test.RRSet("lame.example.at.", "NS", "ns2.example.net."),
+		rc0test.RRSet("expired.example.at.", "NS", "ns.expired-domain.example."),
+	)
+	api.AddZone("example.com",
+		rc0test.RRSet("www.example.com.", "CNAME", "www.example.at."),
+	)
+
+	scanner := NewScanner(api.Client(), &DNSResolver{Nameserver: address, Client: &dns.Client{Timeout: 2 * time.Second}})
+	scanner.NameserverPort = port
+
+	report, err := scanner.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(report.Zones) != 2 || report.Zones[0].Zone != "example.at" || len(report.Zones[1].Findings) != 0 {
+		t.Fatalf("Scan returned %s", report)
+	}
+
+	var got []string
+	for _, f := range report.Zones[0].Findings {
+		got = append(got, string(f.Severity)+" "+f.Kind+" "+f.Name)
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"critical takeover bucket.example.at.",
+		"critical takeover shop.example.at.",
+		"high dangling expired.example.at.",
+		"high dangling old.example.at.",
+		"high lame-delegation lame.example.at.",
+		"low unresolved flaky.example.at.",
+		"medium takeover-prone static.example.at.",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scan found\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if report.Findings() != len(want) {
+		t.Errorf("Findings returned %d, want %d", report.Findings(), len(want))
+	}
+
+	if s := report.String(); !strings.Contains(s, "example.com  ok") || !strings.Contains(s, "bucket.example.at. CNAME -> deleted-bucket.s3.amazonaws.com.") {
+		t.Errorf("String returned\n%s", s)
+	}
+}
+
+func TestScanner_ScanApexNS(t *testing.T) {
+
+	dnsStub := newStub(t, "ns1.example.net. 300 IN A 127.0.0.1")
+
+	address, shutdown := dnsStub.start(t)
+	defer shutdown()
+
+	_, port, _ := net.SplitHostPort(address)
+
+	api := rc0test.NewServer()
+	defer api.Close()
+
+	api.AddZone("example.at", rc0test.RRSet("example.at.", "NS", "ns1.example.net."))
+
+	scanner := NewScanner(api.Client(), NewDNSResolver(address))
+	scanner.NameserverPort = port
+
+	report, err := scanner.Scan(context.Background(), "example.at")
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if report.Findings() != 0 {
+		t.Errorf("apex NS were checked without CheckApexNS: %s", report)
+	}
+
+	scanner.CheckApexNS = true
+
+	report, _ = scanner.Scan(context.Background(), "example.at")
+
+	if report.Findings() != 1 || report.Zones[0].Findings[0].Kind != KindLameDelegation {
+		t.Errorf("Scan with CheckApexNS returned %s", report)
+	}
+}
+
+func TestFingerprint_Matches(t *testing.T) {
+
+	fp := &Fingerprint{Provider: "GitHub Pages", Suffixes: []string{".github.io"}}
+
+	for target, want := range map[string]bool{
+		"example.github.io.":  true,
+		"Example.GitHub.io":   true,
+		"github.io.":          false,
+		"example.github.com.": false,
+	} {
+		if got := fp.Matches(target); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", target, got, want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// rollbackTimeout limits the rollback of a failed Apply, which does not use the caller's context
+const rollbackTimeout = 2 * time.Minute
+
+// Transaction applies a change set to a zone and restores the affected rrsets if it fails
+type Transaction struct {
+	rrset   RRSetServiceInterface
+	zone    string
+	options *ChangeSetChunkOptions
+
+	// keys of the rrsets affected by the change set
+	affected map[rrsetKey]bool
+
+	// snapshot of the affected rrsets taken before the change set was applied
+	snapshot []*RRType
+}
+
+// TransactionResult reports the outcome of a transaction and of its rollback
+type TransactionResult struct {
+
+	// Summary of the submitted change set chunks
+	Summary *ChangeSetSummary
+
+	// Rollback holds the inverse change set submitted to restore the snapshot
+	Rollback *RRSetDiff
+
+	// RolledBack is set if the snapshot was restored successfully
+	RolledBack bool
+
+	// RollbackErr is set if the rollback failed and the zone is left half-changed
+	RollbackErr error
+}
+
+// NewTransaction returns a transaction for the given zone. The change set is submitted
+// with SubmitChangeSetInChunks using the given options (which may be nil).
+func NewTransaction(rrset RRSetServiceInterface, zone string, options *ChangeSetChunkOptions) *Transaction {
+	return &Transaction{rrset: rrset, zone: zone, options: options}
+}
+
+// Apply takes a snapshot of the rrsets affected by the change set and submits it.
+// If any chunk fails, the inverse change set is computed from the current zone content
+// and submitted to restore the snapshot. The returned error is the one of the failed
+// change set; the outcome of the rollback is reported in the result.
+// The rollback runs even if ctx was cancelled in the meantime (f.e. on SIGINT).
+func (t *Transaction) Apply(ctx context.Context, changeSet []*RRSetChange) (*TransactionResult, error) {
+
+	if t.snapshot != nil {
+		return nil, fmt.Errorf("transaction for zone %s was already applied", t.zone)
+	}
+
+	t.affected = make(map[rrsetKey]bool, len(changeSet))
+	for _, change := range changeSet {
+		t.affected[rrsetKey{name: NormalizeName(change.Name), rrType: strings.ToUpper(change.Type)}] = true
+	}
+
+	snapshot, err := t.affectedRRSets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot of zone %s failed: %v", t.zone, err)
+	}
+	t.snapshot = snapshot
+
+	result := &TransactionResult{}
+
+	result.Summary, err = t.rrset.SubmitChangeSetInChunks(t.zone, changeSet, t.options)
+	if err == nil {
+		return result, nil
+	}
+
+	rollbackCtx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+
+	result.Rollback, result.RollbackErr = t.restore(rollbackCtx)
+	result.RolledBack = result.RollbackErr == nil
+
+	return result, err
+}
+
+// Rollback explicitly restores the snapshot taken by Apply, f.e. if a check after a
+// successful Apply failed
+func (t *Transaction) Rollback(ctx context.Context) (*TransactionResult, error) {
+
+	if t.snapshot == nil {
+		return nil, fmt.Errorf("transaction for zone %s was not applied", t.zone)
+	}
+
+	result := &TransactionResult{}
+
+	result.Rollback, result.RollbackErr = t.restore(ctx)
+	result.RolledBack = result.RollbackErr == nil
+
+	return result, result.RollbackErr
+}
+
+// restore submits the changes needed to get from the current content of the affected rrsets to the snapshot
+func (t *Transaction) restore(ctx context.Context) (*RRSetDiff, error) {
+
+	current, err := t.affectedRRSets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("rollback of zone %s failed: %v", t.zone, err)
+	}
+
+	diff := DiffRRSets(current, t.snapshot)
+
+	if diff.IsEmpty() {
+		return diff, nil
+	}
+
+	options := &ChangeSetChunkOptions{}
+	if t.options != nil {
+		options.ChunkSize = t.options.ChunkSize
+	}
+
+	if _, err := t.rrset.SubmitChangeSetInChunks(t.zone, diff.Changes, options); err != nil {
+		return diff, fmt.Errorf("rollback of zone %s failed: %v", t.zone, err)
+	}
+
+	return diff, nil
+}
+
+func (t *Transaction) affectedRRSets(ctx context.Context) ([]*RRType, error) {
+
+	all, err := t.rrset.ListAll(ctx, t.zone)
+	if err != nil {
+		return nil, err
+	}
+
+	affected := []*RRType{}
+
+	for _, rrset := range all {
+		if t.affected[rrsetKey{name: NormalizeName(rrset.Name), rrType: strings.ToUpper(rrset.Type)}] {
+			affected = append(affected, rrset)
+		}
+	}
+
+	return affected, nil
+}
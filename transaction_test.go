@@ -0,0 +1,149 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTransaction_ApplyRollback(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+			{Name: "old.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.9"}}},
+		},
+		failPatch: func(n int, changeSet []*RRSetChange) bool {
+			return n == 2
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	before := append([]*RRType{}, zone.rrsets...)
+
+	changeSet := []*RRSetChange{
+		{Name: "old.testzone1.at.", Type: "A", ChangeType: ChangeTypeDELETE},
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeUPDATE, TTL: 300, Records: []*Record{{Content: "10.10.0.2"}}},
+		{Name: "new.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 300, Records: []*Record{{Content: "10.10.0.3"}}},
+	}
+
+	tx := NewTransaction(client.RRSet, "testzone1.at", &ChangeSetChunkOptions{ChunkSize: 1})
+
+	result, err := tx.Apply(context.Background(), changeSet)
+	if err == nil {
+		t.Fatalf("Transaction.Apply returned no error")
+	}
+
+	if !result.RolledBack || result.RollbackErr != nil {
+		t.Fatalf("Transaction.Apply did not roll back: %v", result.RollbackErr)
+	}
+
+	// Only the delete of the first chunk was applied and has to be reverted
+	want := []*RRSetChange{
+		{Name: "old.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "10.10.0.9"}}},
+	}
+
+	if !reflect.DeepEqual(result.Rollback.Changes, want) {
+		t.Errorf("Transaction.Apply rolled back with %+v, want %+v", result.Rollback.Changes, want)
+	}
+
+	if diff := DiffRRSets(zone.rrsets, before); !diff.IsEmpty() {
+		t.Errorf("Transaction.Apply left zone changed:\n%s", diff)
+	}
+}
+
+func TestTransaction_ApplyRollbackCancelled(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+		},
+		failPatch: func(n int, changeSet []*RRSetChange) bool {
+			// the caller gives up while the second chunk is submitted
+			if n == 2 {
+				cancel()
+				return true
+			}
+			return false
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	before := append([]*RRType{}, zone.rrsets...)
+
+	changeSet := []*RRSetChange{
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeUPDATE, TTL: 300, Records: []*Record{{Content: "10.10.0.2"}}},
+		{Name: "new.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 300, Records: []*Record{{Content: "10.10.0.3"}}},
+	}
+
+	tx := NewTransaction(client.RRSet, "testzone1.at", &ChangeSetChunkOptions{ChunkSize: 1})
+
+	result, err := tx.Apply(ctx, changeSet)
+	if err == nil {
+		t.Fatalf("Transaction.Apply returned no error")
+	}
+
+	if !result.RolledBack || result.RollbackErr != nil {
+		t.Fatalf("Transaction.Apply did not roll back after the context was cancelled: %v", result.RollbackErr)
+	}
+
+	if diff := DiffRRSets(zone.rrsets, before); !diff.IsEmpty() {
+		t.Errorf("Transaction.Apply left zone changed:\n%s", diff)
+	}
+}
+
+func TestTransaction_ExplicitRollback(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.1"}}},
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	before := append([]*RRType{}, zone.rrsets...)
+
+	tx := NewTransaction(client.RRSet, "testzone1.at", nil)
+
+	_, err := tx.Apply(context.Background(), []*RRSetChange{
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeUPDATE, TTL: 300, Records: []*Record{{Content: "10.10.0.2"}}},
+		{Name: "new.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 300, Records: []*Record{{Content: "10.10.0.3"}}},
+	})
+
+	if err != nil {
+		t.Fatalf("Transaction.Apply returned error: %v", err)
+	}
+
+	result, err := tx.Rollback(context.Background())
+	if err != nil || !result.RolledBack {
+		t.Fatalf("Transaction.Rollback returned error: %v", err)
+	}
+
+	if len(result.Rollback.Changes) != 2 {
+		t.Errorf("Transaction.Rollback submitted %d changes, want 2", len(result.Rollback.Changes))
+	}
+
+	if diff := DiffRRSets(zone.rrsets, before); !diff.IsEmpty() {
+		t.Errorf("Transaction.Rollback left zone changed:\n%s", diff)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TTLRule sets bounds for the TTL of the rrsets in its scope. A bound of 0 is not checked,
+// f.e. Min == Max requires an exact TTL.
+type TTLRule struct {
+	SyncScope
+
+	Min int
+	Max int
+}
+
+// TTLPolicy checks rrset TTLs against rules. Only the first rule containing an rrset
+// applies, so more specific rules (f.e. by name) should come first.
+type TTLPolicy struct {
+	Rules []*TTLRule
+}
+
+// TTLViolation is an rrset whose TTL is outside the bounds of its rule
+type TTLViolation struct {
+	Zone  string
+	RRSet *RRType
+	Rule  *TTLRule
+
+	// Want is the nearest TTL within the bounds of the rule
+	Want int
+}
+
+// DefaultTTLPolicy returns the policy: NS 86400, MX 3600, A and AAAA at least 300
+func DefaultTTLPolicy() *TTLPolicy {
+	return &TTLPolicy{Rules: []*TTLRule{
+		{SyncScope: SyncScope{Types: []string{"NS"}}, Min: 86400, Max: 86400},
+		{SyncScope: SyncScope{Types: []string{"MX"}}, Min: 3600, Max: 3600},
+		{SyncScope: SyncScope{Types: []string{"A", "AAAA"}}, Min: 300},
+	}}
+}
+
+// String returns f.e. "www.example.at. A: ttl 60, want 300"
+func (v *TTLViolation) String() string {
+	return fmt.Sprintf("%s %s: ttl %d, want %d", v.RRSet.Name, v.RRSet.Type, v.RRSet.TTL, v.Want)
+}
+
+// Change returns the update setting the TTL of the rrset to Want, keeping its records
+func (v *TTLViolation) Change() *RRSetChange {
+	return ttlChange(v.RRSet, v.Want)
+}
+
+// Check returns the violations of the zone's rrsets
+func (p *TTLPolicy) Check(zone string, rrsets []*RRType) []*TTLViolation {
+
+	var violations []*TTLViolation
+
+	for _, rrset := range rrsets {
+
+		rule := p.rule(zone, rrset)
+		if rule == nil {
+			continue
+		}
+
+		want := rrset.TTL
+		if rule.Min > 0 && want < rule.Min {
+			want = rule.Min
+		}
+		if rule.Max > 0 && want > rule.Max {
+			want = rule.Max
+		}
+
+		if want != rrset.TTL {
+			violations = append(violations, &TTLViolation{Zone: zone, RRSet: rrset, Rule: rule, Want: want})
+		}
+	}
+
+	return violations
+}
+
+// Scan lists the rrsets of each zone and returns the violations of all zones
+func (p *TTLPolicy) Scan(ctx context.Context, rrsetService RRSetServiceInterface, zones ...string) ([]*TTLViolation, error) {
+
+	var violations []*TTLViolation
+
+	for _, zone := range zones {
+
+		rrsets, err := rrsetService.ListAll(ctx, zone)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %v", zone, err)
+		}
+
+		violations = append(violations, p.Check(zone, rrsets)...)
+	}
+
+	return violations, nil
+}
+
+// TTLFixes groups the changes fixing the violations by zone
+func TTLFixes(violations []*TTLViolation) map[string][]*RRSetChange {
+
+	fixes := make(map[string][]*RRSetChange)
+
+	for _, v := range violations {
+		fixes[v.Zone] = append(fixes[v.Zone], v.Change())
+	}
+
+	return fixes
+}
+
+func (p *TTLPolicy) rule(zone string, rrset *RRType) *TTLRule {
+
+	for _, rule := range p.Rules {
+		if rule.Contains(zone, rrset.Name, rrset.Type) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+// TTLSnapshot holds the TTLs of rrsets before they were lowered, f.e. ahead of a migration.
+// It can be stored as JSON until the TTLs are restored.
+type TTLSnapshot struct {
+	Zone   string      `json:"zone"`
+	TTL    int         `json:"ttl"`
+	RRSets []*TTLEntry `json:"rrsets"`
+}
+
+// TTLEntry is the original TTL of an rrset
+type TTLEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	TTL  int    `json:"ttl"`
+}
+
+// LowerTTLs sets the TTL of the rrsets in scope to ttl, leaving rrsets with a lower TTL
+// unchanged, and returns a snapshot for RestoreTTLs
+func LowerTTLs(ctx context.Context, rrsetService RRSetServiceInterface, zone string, scope *SyncScope, ttl int) (*TTLSnapshot, error) {
+
+	rrsets, err := rrsetService.ListAll(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &TTLSnapshot{Zone: zone, TTL: ttl}
+
+	var changeSet []*RRSetChange
+
+	for _, rrset := range rrsets {
+
+		if rrset.TTL <= ttl || !scope.Contains(zone, rrset.Name, rrset.Type) || isApexSOA(zone, rrset) {
+			continue
+		}
+
+		snapshot.RRSets = append(snapshot.RRSets, &TTLEntry{Name: rrset.Name, Type: rrset.Type, TTL: rrset.TTL})
+		changeSet = append(changeSet, ttlChange(rrset, ttl))
+	}
+
+	if err := submitTTLChanges(rrsetService, zone, changeSet); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// RestoreTTLs sets the rrsets of the snapshot back to their original TTL. RRSets which were
+// removed or got another TTL since they were lowered are left alone.
+func RestoreTTLs(ctx context.Context, rrsetService RRSetServiceInterface, snapshot *TTLSnapshot) error {
+
+	rrsets, err := rrsetService.ListAll(ctx, snapshot.Zone)
+	if err != nil {
+		return err
+	}
+
+	index := indexRRSets(rrsets)
+
+	var changeSet []*RRSetChange
+
+	for _, entry := range snapshot.RRSets {
+
+		rrset, ok := index[rrsetKey{NormalizeName(entry.Name), strings.ToUpper(entry.Type)}]
+		if !ok || rrset.TTL != snapshot.TTL {
+			continue
+		}
+
+		changeSet = append(changeSet, ttlChange(rrset, entry.TTL))
+	}
+
+	return submitTTLChanges(rrsetService, snapshot.Zone, changeSet)
+}
+
+func ttlChange(rrset *RRType, ttl int) *RRSetChange {
+
+	records := make([]*Record, len(rrset.Records))
+	for i, r := range rrset.Records {
+		records[i] = &Record{Content: r.Content, Disabled: r.Disabled}
+	}
+
+	return &RRSetChange{
+		Name:       rrset.Name,
+		Type:       rrset.Type,
+		ChangeType: ChangeTypeUPDATE,
+		TTL:        ttl,
+		Records:    records,
+	}
+}
+
+func submitTTLChanges(rrsetService RRSetServiceInterface, zone string, changeSet []*RRSetChange) error {
+
+	if len(changeSet) == 0 {
+		return nil
+	}
+
+	status, err := rrsetService.SubmitChangeSet(zone, changeSet)
+	if err != nil {
+		return err
+	}
+
+	if status.HasError() {
+		return fmt.Errorf("zone %s: %s", zone, status.Message)
+	}
+
+	return nil
+}
+
+func isApexSOA(zone string, rrset *RRType) bool {
+	return strings.EqualFold(rrset.Type, "SOA") && NormalizeName(rrset.Name) == NormalizeName(zone)
+}
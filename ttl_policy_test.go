@@ -0,0 +1,146 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func ttlTestRRSets() []*RRType {
+	return []*RRType{
+		{Name: "testzone1.at.", Type: "SOA", TTL: 3600, Records: []*Record{{Content: "ns1.example.at. hostmaster.testzone1.at. 1 3600 600 604800 300"}}},
+		{Name: "testzone1.at.", Type: "NS", TTL: 3600, Records: []*Record{{Content: "ns1.example.at."}, {Content: "ns2.example.at."}}},
+		{Name: "testzone1.at.", Type: "MX", TTL: 3600, Records: []*Record{{Content: "10 mail.testzone1.at."}}},
+		{Name: "www.testzone1.at.", Type: "A", TTL: 60, Records: []*Record{{Content: "10.10.0.2"}, {Content: "10.10.0.3", Disabled: true}}},
+		{Name: "lb.testzone1.at.", Type: "AAAA", TTL: 30, Records: []*Record{{Content: "2001:db8::1"}}},
+		{Name: "mail.testzone1.at.", Type: "A", TTL: 86400, Records: []*Record{{Content: "10.10.0.4"}}},
+	}
+}
+
+func TestTTLPolicy_Check(t *testing.T) {
+
+	policy := DefaultTTLPolicy()
+	policy.Rules = append([]*TTLRule{
+		{SyncScope: SyncScope{Names: []string{"lb"}}, Min: 30, Max: 60},
+	}, policy.Rules...)
+
+	var got []string
+	for _, v := range policy.Check("testzone1.at", ttlTestRRSets()) {
+		got = append(got, v.String())
+	}
+
+	want := []string{
+		"testzone1.at. NS: ttl 3600, want 86400",
+		"www.testzone1.at. A: ttl 60, want 300",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check returned %v, want %v", got, want)
+	}
+}
+
+func TestTTLPolicy_ScanAndFix(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{rrsets: ttlTestRRSets()}
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	violations, err := DefaultTTLPolicy().Scan(context.Background(), client.RRSet, "testzone1.at")
+	if err != nil {
+		t.Fatalf("Scan returned error: %v", err)
+	}
+
+	if len(violations) != 3 {
+		t.Fatalf("Scan returned %d violations, want 3", len(violations))
+	}
+
+	fixes := TTLFixes(violations)
+
+	if len(fixes["testzone1.at"]) != 3 {
+		t.Fatalf("TTLFixes returned %+v", fixes)
+	}
+
+	www := fixes["testzone1.at"][1]
+	wantChange := &RRSetChange{
+		Name:       "www.testzone1.at.",
+		Type:       "A",
+		ChangeType: ChangeTypeUPDATE,
+		TTL:        300,
+		Records:    []*Record{{Content: "10.10.0.2"}, {Content: "10.10.0.3", Disabled: true}},
+	}
+
+	if !reflect.DeepEqual(www, wantChange) {
+		t.Errorf("fix is %+v, want %+v", www, wantChange)
+	}
+
+	if _, err := client.RRSet.SubmitChangeSet("testzone1.at", fixes["testzone1.at"]); err != nil {
+		t.Fatalf("SubmitChangeSet returned error: %v", err)
+	}
+
+	violations, _ = DefaultTTLPolicy().Scan(context.Background(), client.RRSet, "testzone1.at")
+	if len(violations) != 0 {
+		t.Errorf("Scan after fix returned %v", violations)
+	}
+}
+
+func TestLowerAndRestoreTTLs(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{rrsets: ttlTestRRSets()}
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	snapshot, err := LowerTTLs(context.Background(), client.RRSet, "testzone1.at", &SyncScope{Types: []string{"A", "AAAA", "MX", "SOA"}}, 60)
+	if err != nil {
+		t.Fatalf("LowerTTLs returned error: %v", err)
+	}
+
+	want := []*TTLEntry{
+		{Name: "testzone1.at.", Type: "MX", TTL: 3600},
+		{Name: "mail.testzone1.at.", Type: "A", TTL: 86400},
+	}
+
+	if !reflect.DeepEqual(snapshot.RRSets, want) {
+		t.Fatalf("LowerTTLs snapshot has %+v, want %+v", snapshot.RRSets, want)
+	}
+
+	ttls := func() map[string]int {
+		m := make(map[string]int)
+		for _, rrset := range zone.rrsets {
+			m[rrset.Name+" "+rrset.Type] = rrset.TTL
+		}
+		return m
+	}
+
+	if got := ttls(); got["testzone1.at. MX"] != 60 || got["mail.testzone1.at. A"] != 60 || got["testzone1.at. SOA"] != 3600 {
+		t.Fatalf("TTLs after LowerTTLs: %v", got)
+	}
+
+	// an rrset changed during the migration keeps its new TTL
+	if _, err := client.RRSet.SubmitChangeSet("testzone1.at", []*RRSetChange{{
+		Name: "mail.testzone1.at.", Type: "A", ChangeType: ChangeTypeUPDATE, TTL: 120,
+		Records: []*Record{{Content: "10.10.0.5"}},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RestoreTTLs(context.Background(), client.RRSet, snapshot); err != nil {
+		t.Fatalf("RestoreTTLs returned error: %v", err)
+	}
+
+	if got := ttls(); got["testzone1.at. MX"] != 3600 || got["mail.testzone1.at. A"] != 120 {
+		t.Errorf("TTLs after RestoreTTLs: %v", got)
+	}
+
+	if len(zone.patches) != 3 {
+		t.Errorf("got %d PATCH requests, want 3", len(zone.patches))
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"strings"
+)
+
+// QuoteTXT returns plain text as TXT record content. Quotes, backslashes and non-printable
+// bytes are escaped and text longer than 255 bytes is split into several character-strings,
+// f.e. for long DKIM keys.
+func QuoteTXT(text string) string {
+
+	if len(text) <= maxTXTStringLength {
+		return quoteTXT(text)
+	}
+
+	var chunks []string
+
+	for len(text) > maxTXTStringLength {
+		chunks = append(chunks, quoteTXT(text[:maxTXTStringLength]))
+		text = text[maxTXTStringLength:]
+	}
+
+	if len(text) > 0 {
+		chunks = append(chunks, quoteTXT(text))
+	}
+
+	return strings.Join(chunks, " ")
+}
+
+// UnquoteTXT returns the plain text of TXT record content. Multiple character-strings
+// are concatenated without separator.
+func UnquoteTXT(content string) (string, error) {
+
+	strs, err := parseTXTStrings(content)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(strs, ""), nil
+}
+
+// HasTXTVersion reports whether the plain text starts with the version tag, f.e. "v=spf1"
+// or "v=DMARC1". The tag is compared case-insensitively and has to end the text or be
+// followed by a space or semicolon.
+func HasTXTVersion(text string, version string) bool {
+
+	text = strings.TrimSpace(text)
+
+	if len(text) < len(version) || !strings.EqualFold(text[:len(version)], version) {
+		return false
+	}
+
+	return len(text) == len(version) || text[len(version)] == ' ' || text[len(version)] == ';'
+}
+
+// AddTXT appends a record for each of the given plain texts to the change (see QuoteTXT)
+func (c *RRSetChange) AddTXT(texts ...string) {
+
+	for _, text := range texts {
+		c.Records = append(c.Records, &Record{Content: QuoteTXT(text)})
+	}
+}
+
+// AddTXT appends a record for each of the given plain texts to the rrset (see QuoteTXT)
+func (r *RRType) AddTXT(texts ...string) {
+
+	for _, text := range texts {
+		r.Records = append(r.Records, &Record{Content: QuoteTXT(text)})
+	}
+}
+
+// TXT returns the plain text of each record of the change (see UnquoteTXT)
+func (c *RRSetChange) TXT() ([]string, error) {
+	return unquoteRecords(c.Records)
+}
+
+// TXT returns the plain text of each record of the rrset with chunked character-strings
+// reassembled (see UnquoteTXT)
+func (r *RRType) TXT() ([]string, error) {
+	return unquoteRecords(r.Records)
+}
+
+func unquoteRecords(records []*Record) ([]string, error) {
+
+	texts := make([]string, 0, len(records))
+
+	for _, record := range records {
+		text, err := UnquoteTXT(record.Content)
+		if err != nil {
+			return nil, err
+		}
+		texts = append(texts, text)
+	}
+
+	return texts, nil
+}
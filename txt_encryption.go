@@ -0,0 +1,315 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// encryptedTXTPrefix marks TXT content encrypted with AES-GCM: ENC2:<key id>:<base64url(nonce|ciphertext)>
+	encryptedTXTPrefix = "ENC2:"
+
+	// legacyEncryptedTXTPrefix marks TXT content encrypted with unauthenticated AES-CFB by earlier rc0go versions
+	legacyEncryptedTXTPrefix = "ENC:"
+)
+
+// KeyID returns the identifier stored with TXT records encrypted with the given key.
+// It is derived from the key and does not reveal it.
+func KeyID(key []byte) string {
+
+	sum := sha256.Sum256(key)
+
+	return hex.EncodeToString(sum[:4])
+}
+
+// EncryptTXTValue encrypts the plaintext with AES-GCM and returns it as TXT content in the
+// "ENC2:<key id>:<ciphertext>" format. The key must be 16, 24 or 32 bytes long.
+func EncryptTXTValue(key []byte, plaintext string) (string, error) {
+	return encryptTXTValue(KeyID(key), key, plaintext)
+}
+
+func encryptTXTValue(keyID string, key []byte, plaintext string) (string, error) {
+
+	aead, err := newTXTCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	header := encryptedTXTPrefix + keyID + ":"
+
+	// The header is authenticated, so the key id cannot be swapped
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(header))
+
+	return QuoteTXT(header + base64.RawURLEncoding.EncodeToString(sealed)), nil
+}
+
+// DecryptTXTValue decrypts TXT content in the "ENC2:" or legacy "ENC:" format. An error is
+// returned if the content was encrypted with another key or was tampered with.
+func DecryptTXTValue(key []byte, content string) (string, error) {
+
+	text, err := encryptedTXTText(content)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(text, legacyEncryptedTXTPrefix) {
+		return decryptLegacyTXT(key, strings.TrimPrefix(text, legacyEncryptedTXTPrefix))
+	}
+
+	keyID, _ := EncryptedTXTKeyID(content)
+
+	if want := KeyID(key); keyID != want {
+		return "", fmt.Errorf("TXT content was encrypted with key %s, not with key %s", keyID, want)
+	}
+
+	return decryptTXTValue(key, text)
+}
+
+func decryptTXTValue(key []byte, text string) (string, error) {
+
+	i := strings.Index(text[len(encryptedTXTPrefix):], ":")
+	if i < 0 {
+		return "", fmt.Errorf("encrypted TXT content without key id")
+	}
+
+	header := text[:len(encryptedTXTPrefix)+i+1]
+
+	sealed, err := base64.RawURLEncoding.DecodeString(text[len(header):])
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted TXT content: %v", err)
+	}
+
+	aead, err := newTXTCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return "", fmt.Errorf("encrypted TXT content is too short")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(header))
+	if err != nil {
+		return "", fmt.Errorf("encrypted TXT content failed authentication")
+	}
+
+	return string(plaintext), nil
+}
+
+// IsEncryptedTXT reports whether the TXT content is encrypted ("ENC2:" or legacy "ENC:" format)
+func IsEncryptedTXT(content string) bool {
+	_, err := encryptedTXTText(content)
+	return err == nil
+}
+
+// IsLegacyEncryptedTXT reports whether the TXT content is encrypted in the legacy "ENC:" format
+func IsLegacyEncryptedTXT(content string) bool {
+	text, err := encryptedTXTText(content)
+	return err == nil && strings.HasPrefix(text, legacyEncryptedTXTPrefix)
+}
+
+// EncryptedTXTKeyID returns the key id of TXT content in the "ENC2:" format
+func EncryptedTXTKeyID(content string) (string, bool) {
+
+	text, err := encryptedTXTText(content)
+	if err != nil || !strings.HasPrefix(text, encryptedTXTPrefix) {
+		return "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(text, encryptedTXTPrefix), ":", 2)
+	if len(parts) != 2 {
+		return "", false
+	}
+
+	return parts[0], true
+}
+
+// MigrateEncryptedTXT re-encrypts all TXT records of the zone in the legacy "ENC:" format
+// with AES-GCM and submits them. Other records of the affected rrsets are kept as they are.
+// The submitted changes are returned. As the legacy format decrypts with a wrong key to
+// garbage instead of failing, verify has to confirm every decrypted text (f.e. a known
+// prefix); if it rejects one, nothing is submitted and an error is returned.
+func (s *RRSetService) MigrateEncryptedTXT(ctx context.Context, zone string, key []byte, verify func(plaintext string) bool) ([]*RRSetChange, error) {
+
+	if verify == nil {
+		return nil, fmt.Errorf("records in legacy \"ENC:\" format cannot be checked for the right key, verify is required")
+	}
+
+	return reencryptTXT(ctx, s, zone, IsLegacyEncryptedTXT, func(content string) (string, error) {
+
+		plaintext, err := DecryptTXTValue(key, content)
+		if err != nil {
+			return "", err
+		}
+
+		if !verify(plaintext) {
+			return "", fmt.Errorf("decrypted text was rejected, the key is probably wrong")
+		}
+
+		return EncryptTXTValue(key, plaintext)
+	})
+}
+
+// reencryptTXT replaces the content of all TXT records selected by match and submits the affected rrsets
+func reencryptTXT(ctx context.Context, s RRSetServiceInterface, zone string, match func(string) bool, reencrypt func(string) (string, error)) ([]*RRSetChange, error) {
+
+	rrsets, err := s.ListAll(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var changeSet []*RRSetChange
+
+	for _, rrset := range rrsets {
+
+		if !strings.EqualFold(rrset.Type, "TXT") {
+			continue
+		}
+
+		records := copyRecords(rrset.Records)
+		changed := false
+
+		for _, r := range records {
+
+			if !match(r.Content) {
+				continue
+			}
+
+			content, err := reencrypt(r.Content)
+			if err != nil {
+				return nil, fmt.Errorf("rrset %s %s: %v", rrset.Name, rrset.Type, err)
+			}
+
+			r.Content = content
+			changed = true
+		}
+
+		if changed {
+			changeSet = append(changeSet, &RRSetChange{
+				Name:       rrset.Name,
+				Type:       rrset.Type,
+				ChangeType: ChangeTypeUPDATE,
+				TTL:        rrset.TTL,
+				Records:    records,
+			})
+		}
+	}
+
+	if len(changeSet) == 0 {
+		return nil, nil
+	}
+
+	status, err := s.SubmitChangeSet(zone, changeSet)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.HasError() {
+		return nil, fmt.Errorf("re-encryption of zone %s failed: %s", zone, status.Message)
+	}
+
+	return changeSet, nil
+}
+
+// encryptedTXTText returns the unquoted text of encrypted TXT content. Encrypted content is
+// always quoted and carries a well-formed payload, so plain text starting with "ENC:" is not
+// mistaken for it.
+func encryptedTXTText(content string) (string, error) {
+
+	notEncrypted := fmt.Errorf("TXT content is not encrypted")
+
+	if !strings.HasPrefix(strings.TrimSpace(content), "\"") {
+		return "", notEncrypted
+	}
+
+	text, err := UnquoteTXT(content)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+
+	case strings.HasPrefix(text, encryptedTXTPrefix):
+		parts := strings.SplitN(strings.TrimPrefix(text, encryptedTXTPrefix), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || strings.ContainsAny(parts[0], " \t") {
+			return "", notEncrypted
+		}
+		// nonce and tag of AES-GCM
+		if sealed, err := base64.RawURLEncoding.DecodeString(parts[1]); err != nil || len(sealed) < 12+16 {
+			return "", notEncrypted
+		}
+
+	case strings.HasPrefix(text, legacyEncryptedTXTPrefix):
+		// IV of AES-CFB
+		if ciphertext, err := base64.URLEncoding.DecodeString(strings.TrimPrefix(text, legacyEncryptedTXTPrefix)); err != nil || len(ciphertext) < aes.BlockSize {
+			return "", notEncrypted
+		}
+
+	default:
+		return "", notEncrypted
+	}
+
+	return text, nil
+}
+
+func newTXTCipher(key []byte) (cipher.AEAD, error) {
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TXT encryption key: %v", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// decryptLegacyTXT decrypts TXT content written with AES-CFB by earlier rc0go versions.
+// The format is not authenticated, so tampered content cannot be detected.
+//
+// https://gist.github.com/manishtpatel/8222606
+func decryptLegacyTXT(key []byte, cryptoText string) (string, error) {
+
+	ciphertext, err := base64.URLEncoding.DecodeString(cryptoText)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted TXT content: %v", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid TXT encryption key: %v", err)
+	}
+
+	// The IV is stored at the beginning of the ciphertext
+	if len(ciphertext) < aes.BlockSize {
+		return "", fmt.Errorf("encrypted TXT content is too short")
+	}
+
+	iv := ciphertext[:aes.BlockSize]
+	ciphertext = ciphertext[aes.BlockSize:]
+
+	stream := cipher.NewCFBDecrypter(block, iv)
+
+	// XORKeyStream can work in-place if the two arguments are the same.
+	stream.XORKeyStream(ciphertext, ciphertext)
+
+	return string(ciphertext), nil
+}
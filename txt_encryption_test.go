@@ -0,0 +1,204 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+var testTXTKey = []byte("0123456789abcdef0123456789abcdef")
+
+// legacyEncryptTXT writes TXT content in the AES-CFB "ENC:" format of earlier rc0go versions
+func legacyEncryptTXT(key []byte, text string) string {
+
+	block, _ := aes.NewCipher(key)
+
+	ciphertext := make([]byte, aes.BlockSize+len(text))
+	iv := ciphertext[:aes.BlockSize]
+	copy(iv, "0123456789abcdef")
+
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(ciphertext[aes.BlockSize:], []byte(text))
+
+	return "\"ENC:" + base64.URLEncoding.EncodeToString(ciphertext) + "\""
+}
+
+func TestEncryptTXTValue(t *testing.T) {
+
+	content, err := EncryptTXTValue(testTXTKey, "secret")
+	if err != nil {
+		t.Fatalf("EncryptTXTValue returned error: %v", err)
+	}
+
+	if !strings.HasPrefix(content, "\"ENC2:"+KeyID(testTXTKey)+":") {
+		t.Errorf("EncryptTXTValue returned %q without ENC2 header", content)
+	}
+
+	if keyID, ok := EncryptedTXTKeyID(content); !ok || keyID != KeyID(testTXTKey) {
+		t.Errorf("EncryptedTXTKeyID returned %q, %v", keyID, ok)
+	}
+
+	plaintext, err := DecryptTXTValue(testTXTKey, content)
+	if err != nil {
+		t.Fatalf("DecryptTXTValue returned error: %v", err)
+	}
+
+	if plaintext != "secret" {
+		t.Errorf("DecryptTXTValue returned %q, want %q", plaintext, "secret")
+	}
+}
+
+func TestDecryptTXTValue_Errors(t *testing.T) {
+
+	content, _ := EncryptTXTValue(testTXTKey, "secret")
+
+	// Flip a character of the ciphertext
+	i := len(content) - 5
+	c := byte('A')
+	if content[i] == 'A' {
+		c = 'B'
+	}
+	tampered := content[:i] + string(c) + content[i+1:]
+
+	tests := map[string]struct {
+		key     []byte
+		content string
+	}{
+		"tampered":     {testTXTKey, tampered},
+		"wrong key":    {[]byte("fedcba9876543210fedcba9876543210"), content},
+		"bad base64":   {testTXTKey, "\"ENC2:" + KeyID(testTXTKey) + ":!!!\""},
+		"too short":    {testTXTKey, "\"ENC2:" + KeyID(testTXTKey) + ":AAAA\""},
+		"legacy short": {testTXTKey, "\"ENC:AAAA\""},
+		"legacy key":   {[]byte("short"), legacyEncryptTXT(testTXTKey, "secret")},
+		"plain":        {testTXTKey, "\"not encrypted\""},
+	}
+
+	for name, test := range tests {
+		if _, err := DecryptTXTValue(test.key, test.content); err == nil {
+			t.Errorf("DecryptTXTValue(%s) returned no error", name)
+		}
+	}
+
+	if _, err := EncryptTXTValue([]byte("short"), "secret"); err == nil {
+		t.Errorf("EncryptTXTValue returned no error for an invalid key")
+	}
+}
+
+func TestDecryptTXTValue_Legacy(t *testing.T) {
+
+	plaintext, err := DecryptTXTValue(testTXTKey, legacyEncryptTXT(testTXTKey, "secret"))
+	if err != nil {
+		t.Fatalf("DecryptTXTValue returned error: %v", err)
+	}
+
+	if plaintext != "secret" {
+		t.Errorf("DecryptTXTValue returned %q, want %q", plaintext, "secret")
+	}
+}
+
+func TestRRSetService_MigrateEncryptedTXT(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	current, _ := EncryptTXTValue(testTXTKey, "already migrated")
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: "secret.testzone1.at.", Type: "TXT", TTL: 3600, Records: []*Record{
+				{Content: legacyEncryptTXT(testTXTKey, "legacy")},
+				{Content: "\"plain\""},
+			}},
+			{Name: "new.testzone1.at.", Type: "TXT", TTL: 3600, Records: []*Record{{Content: current}}},
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	changeSet, err := client.RRSet.MigrateEncryptedTXT(context.Background(), "testzone1.at", testTXTKey, func(plaintext string) bool {
+		return plaintext == "legacy"
+	})
+	if err != nil {
+		t.Fatalf("RRSet.MigrateEncryptedTXT returned error: %v", err)
+	}
+
+	if len(changeSet) != 1 || changeSet[0].Name != "secret.testzone1.at." {
+		t.Fatalf("RRSet.MigrateEncryptedTXT returned %+v", changeSet)
+	}
+
+	rrset, err := client.RRSet.Get(context.Background(), "testzone1.at", "secret.testzone1.at.", "TXT")
+	if err != nil {
+		t.Fatalf("RRSet.Get returned error: %v", err)
+	}
+
+	if IsLegacyEncryptedTXT(rrset.Records[0].Content) || rrset.Records[1].Content != "\"plain\"" {
+		t.Errorf("RRSet.MigrateEncryptedTXT stored %+v", rrset.Records)
+	}
+
+	if err := client.RRSet.DecryptTXT(testTXTKey, rrset); err != nil {
+		t.Fatalf("RRSet.DecryptTXT returned error: %v", err)
+	}
+
+	if rrset.Records[0].Content != "legacy" {
+		t.Errorf("RRSet.DecryptTXT returned %q, want %q", rrset.Records[0].Content, "legacy")
+	}
+}
+
+func TestRRSetService_MigrateEncryptedTXTWrongKey(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	zone := &fakeZone{
+		rrsets: []*RRType{
+			{Name: "secret.testzone1.at.", Type: "TXT", TTL: 3600, Records: []*Record{{Content: legacyEncryptTXT(testTXTKey, "v=legacy")}}},
+		},
+	}
+
+	mux.HandleFunc(RC0ZoneRRSets, zone.handle)
+
+	wrongKey := []byte("fedcba9876543210fedcba9876543210")
+	verify := func(plaintext string) bool { return plaintext == "v=legacy" }
+
+	if _, err := client.RRSet.MigrateEncryptedTXT(context.Background(), "testzone1.at", wrongKey, verify); err == nil {
+		t.Errorf("RRSet.MigrateEncryptedTXT with a wrong key returned no error")
+	}
+
+	if _, err := client.RRSet.MigrateEncryptedTXT(context.Background(), "testzone1.at", testTXTKey, nil); err == nil {
+		t.Errorf("RRSet.MigrateEncryptedTXT without verify returned no error")
+	}
+
+	if len(zone.patches) != 0 {
+		t.Errorf("RRSet.MigrateEncryptedTXT submitted %d change sets", len(zone.patches))
+	}
+}
+
+func TestIsEncryptedTXT(t *testing.T) {
+
+	current, _ := EncryptTXTValue(testTXTKey, "secret")
+
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{current, true},
+		{legacyEncryptTXT(testTXTKey, "secret"), true},
+		{"ENC:just some text", false},
+		{"\"ENC: just some text\"", false},
+		{"\"ENC2:abc:short\"", false},
+		{"\"plain\"", false},
+	}
+
+	for _, test := range tests {
+		if got := IsEncryptedTXT(test.content); got != test.want {
+			t.Errorf("IsEncryptedTXT(%q) returned %v, want %v", test.content, got, test.want)
+		}
+	}
+}
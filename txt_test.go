@@ -0,0 +1,145 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestQuoteTXT(t *testing.T) {
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"v=spf1 mx -all", `"v=spf1 mx -all"`},
+		{`say "hi" \o/`, `"say \"hi\" \\o/"`},
+		{"tab\there", `"tab\009here"`},
+		{strings.Repeat("a", 300), `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("a", 45) + `"`},
+		{strings.Repeat("b", 510), `"` + strings.Repeat("b", 255) + `" "` + strings.Repeat("b", 255) + `"`},
+	}
+
+	for _, test := range tests {
+		if got := QuoteTXT(test.text); got != test.want {
+			t.Errorf("QuoteTXT(%q) returned %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestUnquoteTXT(t *testing.T) {
+
+	dkim := "v=DKIM1; k=rsa; p=" + strings.Repeat("MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8A", 12)
+
+	for _, text := range []string{"v=spf1 mx -all", `say "hi" \o/`, "tab\there", dkim} {
+
+		got, err := UnquoteTXT(QuoteTXT(text))
+		if err != nil {
+			t.Errorf("UnquoteTXT returned error: %v", err)
+		}
+
+		if got != text {
+			t.Errorf("UnquoteTXT(QuoteTXT(%q)) returned %q", text, got)
+		}
+	}
+
+	if _, err := UnquoteTXT(`"unterminated`); err == nil {
+		t.Errorf("UnquoteTXT returned no error for unterminated content")
+	}
+}
+
+func TestHasTXTVersion(t *testing.T) {
+
+	tests := []struct {
+		text    string
+		version string
+		want    bool
+	}{
+		{"v=spf1 mx -all", "v=spf1", true},
+		{" V=SPF1", "v=spf1", true},
+		{"v=DMARC1; p=none", "v=DMARC1", true},
+		{"v=spf10 mx", "v=spf1", false},
+		{"mx v=spf1", "v=spf1", false},
+		{"v=spf", "v=spf1", false},
+	}
+
+	for _, test := range tests {
+		if got := HasTXTVersion(test.text, test.version); got != test.want {
+			t.Errorf("HasTXTVersion(%q, %q) returned %v, want %v", test.text, test.version, got, test.want)
+		}
+	}
+}
+
+func TestRRSetChange_AddTXT(t *testing.T) {
+
+	dkim := "v=DKIM1; k=rsa; p=" + strings.Repeat("A", 400)
+
+	change := &RRSetChange{Name: "mail._domainkey.testzone1.at.", Type: "TXT", ChangeType: ChangeTypeADD}
+	change.AddTXT(dkim, "second")
+
+	if err := change.Validate(); err != nil {
+		t.Errorf("RRSetChange.Validate returned error: %v", err)
+	}
+
+	if n := strings.Count(change.Records[0].Content, `" "`); n != 1 {
+		t.Errorf("RRSetChange.AddTXT split into %d character-strings, want 2", n+1)
+	}
+
+	texts, err := change.TXT()
+	if err != nil {
+		t.Errorf("RRSetChange.TXT returned error: %v", err)
+	}
+
+	if want := []string{dkim, "second"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("RRSetChange.TXT returned %v, want %v", texts, want)
+	}
+}
+
+func TestRRType_TXT(t *testing.T) {
+
+	rrset := &RRType{
+		Name:    "testzone1.at.",
+		Type:    "TXT",
+		Records: []*Record{{Content: `"v=spf1 " "mx -all"`}},
+	}
+
+	texts, err := rrset.TXT()
+	if err != nil {
+		t.Errorf("RRType.TXT returned error: %v", err)
+	}
+
+	if want := []string{"v=spf1 mx -all"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("RRType.TXT returned %v, want %v", texts, want)
+	}
+}
+
+func TestRRSetService_EncryptTXT_Chunked(t *testing.T) {
+
+	client, _, _, teardown := setup()
+	defer teardown()
+
+	key := []byte("0123456789abcdef")
+	text := strings.Repeat("secret ", 50)
+
+	change := &RRSetChange{Records: []*Record{{Content: text}}}
+	if err := client.RRSet.EncryptTXT(key, change); err != nil {
+		t.Fatalf("RRSet.EncryptTXT returned error: %v", err)
+	}
+
+	if err := ValidateContent("TXT", change.Records[0].Content); err != nil {
+		t.Errorf("RRSet.EncryptTXT returned invalid content: %v", err)
+	}
+
+	rrset := &RRType{Records: change.Records}
+	if err := client.RRSet.DecryptTXT(key, rrset); err != nil {
+		t.Fatalf("RRSet.DecryptTXT returned error: %v", err)
+	}
+
+	if got := rrset.Records[0].Content; got != text {
+		t.Errorf("RRSet.DecryptTXT returned %q, want %q", got, text)
+	}
+}
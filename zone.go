@@ -6,6 +6,7 @@
 package rc0go
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/mitchellh/mapstructure"
 )
@@ -18,6 +19,7 @@ type ZoneManagementService service
 
 type ZoneManagementServiceInterface interface {
 	List(options *ListOptions) (zones []*Zone, page *Page, err error)
+	ListAll(ctx context.Context) ([]*Zone, error)
 	Get(zone string) (*Zone, error)
 	Create(zoneCreate *ZoneCreate) (*StatusResponse, error)
 	Edit(zone string, zoneEdit *ZoneEdit) (*StatusResponse,  error)
@@ -87,6 +89,53 @@ func (s *ZoneManagementService) List(options *ListOptions) (zones []*Zone, page
 	return zones, page, nil
 }
 
+// ListAll walks all pages of the account's zones and returns them at once.
+// The context is checked between the page requests.
+func (s *ZoneManagementService) ListAll(ctx context.Context) ([]*Zone, error) {
+
+	var all []*Zone
+
+	options := NewListOptions()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		zones, page, err := s.List(options)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, zones...)
+
+		if page.IsLastPage() || len(zones) == 0 {
+			break
+		}
+
+		options.SetPageNumber(page.CurrentPage + 1)
+	}
+
+	return all, nil
+}
+
+// ZoneNames returns the domains of all zones of the account (see ListAll)
+func ZoneNames(ctx context.Context, zones ZoneManagementServiceInterface) ([]string, error) {
+
+	list, err := zones.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, z := range list {
+		names = append(names, z.Domain)
+	}
+
+	return names, nil
+}
+
 // Get a single zone
 //
 // rcode0 API docs: https://my.rcodezero.at/api-doc/#api-zone-management-zone-details-get
@@ -6,6 +6,7 @@
 package rc0go
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/mitchellh/mapstructure"
@@ -235,4 +236,57 @@ func TestZoneManagementService_Transfer(t *testing.T) {
 		t.Errorf("Zones.Transfer returned %+v, want %+v", status, want)
 	}
 
-}
\ No newline at end of file
+}
+
+func TestZoneManagementService_ListAll(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(RC0Zones, func(w http.ResponseWriter, r *http.Request) {
+
+		testMethod(t, r, "GET")
+
+		page := getTestDataPaginated(reflect.TypeOf(Zone{}))
+		page["last_page"] = 2
+
+		if r.URL.Query().Get("page") == "2" {
+			page["current_page"] = 2
+			page["data"] = []interface{}{map[string]interface{}{"id": 2, "domain": "testzone2.at", "type": "SLAVE"}}
+		}
+
+		dat, _ := json.Marshal(page)
+		_, _ = fmt.Fprint(w, string(dat))
+	})
+
+	zones, err := client.Zones.ListAll(context.Background())
+	if err != nil {
+		t.Fatalf("Zones.ListAll returned error: %v", err)
+	}
+
+	if len(zones) != 2 || zones[1].Domain != "testzone2.at" {
+		t.Errorf("Zones.ListAll returned %+v, want the zones of both pages", zones)
+	}
+}
+
+func TestZoneNames(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(RC0Zones, func(w http.ResponseWriter, r *http.Request) {
+		page := getTestDataPaginated(reflect.TypeOf(Zone{}))
+		page["data"] = []interface{}{map[string]interface{}{"id": 1, "domain": "testzone1.at", "type": "MASTER"}}
+		dat, _ := json.Marshal(page)
+		_, _ = fmt.Fprint(w, string(dat))
+	})
+
+	names, err := ZoneNames(context.Background(), client.Zones)
+	if err != nil {
+		t.Fatalf("ZoneNames returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"testzone1.at"}) {
+		t.Errorf("ZoneNames returned %q, want [testzone1.at]", names)
+	}
+}
@@ -0,0 +1,785 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const defaultZoneFileTTL = 3600
+
+// ZoneFileOptions controls how an RFC 1035 master file is turned into rrset changes
+type ZoneFileOptions struct {
+
+	// Origin of the zone (f.e. "rcodezero.at."). Used for "@" and relative names
+	// until the first $ORIGIN directive and to detect apex records.
+	Origin string
+
+	// TTL for records without an explicit TTL if no $TTL directive precedes them
+	DefaultTTL int
+
+	// SkipSOA drops the SOA record, which is managed by rcode0 for master zones
+	SkipSOA bool
+
+	// SkipApexNS drops the NS rrset at the zone apex, which is managed by rcode0 for master zones
+	SkipApexNS bool
+
+	// ChangeType used for the generated rrset changes (defaults to ChangeTypeADD)
+	ChangeType string
+
+	// IncludeDir is the directory relative $INCLUDE file names are resolved against
+	IncludeDir string
+
+	// Open is used to read files referenced by $INCLUDE. Defaults to os.Open.
+	Open func(name string) (io.ReadCloser, error)
+}
+
+// NewZoneFileOptions returns the default options for importing a zone file into rcode0
+func NewZoneFileOptions(origin string) *ZoneFileOptions {
+	return &ZoneFileOptions{
+		Origin:     origin,
+		DefaultTTL: defaultZoneFileTTL,
+		SkipSOA:    true,
+		SkipApexNS: true,
+		ChangeType: ChangeTypeADD,
+	}
+}
+
+// zoneFileRecord is a single resource record read from a zone file
+type zoneFileRecord struct {
+	Name    string
+	Type    string
+	TTL     int
+	Content string
+}
+
+// ParseZoneFile reads an RFC 1035 master file and groups its records into rrset
+// changes (one per name and type). $ORIGIN, $TTL and $INCLUDE directives,
+// relative names and multi-line records in parentheses are supported.
+//
+// If the records of an rrset have different TTLs, the lowest one is used.
+func ParseZoneFile(r io.Reader, options *ZoneFileOptions) ([]*RRSetChange, error) {
+
+	if options == nil {
+		return nil, fmt.Errorf("zone file options are not provided")
+	}
+
+	if options.Origin == "" {
+		return nil, fmt.Errorf("zone file origin is not provided")
+	}
+
+	p := &zoneFileParser{
+		options: options,
+		apex:    canonicalOrigin(options.Origin),
+		ttl:     options.DefaultTTL,
+	}
+
+	if p.ttl <= 0 {
+		p.ttl = defaultZoneFileTTL
+	}
+
+	if err := p.parse(r, "", p.apex, 0); err != nil {
+		return nil, err
+	}
+
+	changeType := options.ChangeType
+	if changeType == "" {
+		changeType = ChangeTypeADD
+	}
+
+	var changeSet []*RRSetChange
+	index := make(map[string]*RRSetChange)
+
+	for _, rr := range p.records {
+
+		if options.SkipSOA && rr.Type == "SOA" {
+			continue
+		}
+
+		if options.SkipApexNS && rr.Type == "NS" && rr.Name == p.apex {
+			continue
+		}
+
+		key := rr.Name + " " + rr.Type
+
+		change, ok := index[key]
+		if !ok {
+			change = &RRSetChange{
+				Name:       rr.Name,
+				Type:       rr.Type,
+				ChangeType: changeType,
+				TTL:        rr.TTL,
+			}
+			index[key] = change
+			changeSet = append(changeSet, change)
+		}
+
+		if rr.TTL < change.TTL {
+			change.TTL = rr.TTL
+		}
+
+		change.Records = append(change.Records, &Record{Content: rr.Content})
+	}
+
+	return changeSet, nil
+}
+
+// ImportZoneFile parses an RFC 1035 master file and submits the resulting rrsets to the given zone.
+// If options is nil, NewZoneFileOptions is used with the zone as origin.
+func (s *RRSetService) ImportZoneFile(zone string, r io.Reader, options *ZoneFileOptions) (*StatusResponse, error) {
+
+	if options == nil {
+		options = NewZoneFileOptions(zone)
+	}
+
+	if options.Origin == "" {
+		o := *options
+		o.Origin = zone
+		options = &o
+	}
+
+	changeSet, err := ParseZoneFile(r, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(changeSet) == 0 {
+		return nil, fmt.Errorf("zone file does not contain any importable records")
+	}
+
+	return s.SubmitChangeSet(zone, changeSet)
+}
+
+// ZoneFileExportOptions controls how rrsets are written as an RFC 1035 master file
+type ZoneFileExportOptions struct {
+
+	// Relativize writes owner names relative to the zone origin ("@" for the apex)
+	Relativize bool
+
+	// TTL written as $TTL directive. Records with this TTL are written without one.
+	// Defaults to the most common TTL of the zone.
+	TTL int
+}
+
+// ExportZoneFile walks all rrset pages of the zone and writes them as an RFC 1035 master file.
+// Records are sorted canonically (RFC 4034, section 6.1) and disabled records are written as comments.
+// If options is nil, owner names are written absolute.
+func (s *RRSetService) ExportZoneFile(ctx context.Context, zone string, w io.Writer, options *ZoneFileExportOptions) error {
+
+	rrsets, err := s.ListAll(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	return WriteZoneFile(w, zone, rrsets, options)
+}
+
+// WriteZoneFile writes the given rrsets as an RFC 1035 master file for the zone
+func WriteZoneFile(w io.Writer, zone string, rrsets []*RRType, options *ZoneFileExportOptions) error {
+
+	if options == nil {
+		options = &ZoneFileExportOptions{}
+	}
+
+	origin := canonicalOrigin(zone)
+
+	sorted := make([]*RRType, len(rrsets))
+	copy(sorted, rrsets)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := compareCanonicalNames(sorted[i].Name, sorted[j].Name); c != 0 {
+			return c < 0
+		}
+		return typeOrder(sorted[i].Type) < typeOrder(sorted[j].Type)
+	})
+
+	ttl := options.TTL
+	if ttl <= 0 {
+		ttl = mostCommonTTL(sorted)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, '\t', 0)
+
+	fmt.Fprintf(tw, "$ORIGIN %s\n", origin)
+	fmt.Fprintf(tw, "$TTL %d\n", ttl)
+
+	for _, rrset := range sorted {
+
+		owner := rrset.Name
+		if options.Relativize {
+			owner = relativeName(owner, origin)
+		}
+
+		rrTTL := ""
+		if rrset.TTL != ttl {
+			rrTTL = strconv.Itoa(rrset.TTL)
+		}
+
+		contents := make([]*Record, len(rrset.Records))
+		copy(contents, rrset.Records)
+		sort.SliceStable(contents, func(i, j int) bool {
+			return contents[i].Content < contents[j].Content
+		})
+
+		for _, r := range contents {
+			prefix := ""
+			if r.Disabled {
+				prefix = ";"
+			}
+			fmt.Fprintf(tw, "%s%s\t%s\tIN\t%s\t%s\n", prefix, owner, rrTTL, strings.ToUpper(rrset.Type), r.Content)
+		}
+	}
+
+	return tw.Flush()
+}
+
+// relativeName returns the name relative to the origin or the absolute name if it is outside of the origin
+func relativeName(name string, origin string) string {
+
+	if strings.EqualFold(name, origin) {
+		return "@"
+	}
+
+	if strings.HasSuffix(strings.ToLower(name), "."+origin) {
+		return name[:len(name)-len(origin)-1]
+	}
+
+	return name
+}
+
+// compareCanonicalNames compares two domain names in canonical DNS name order (RFC 4034, section 6.1)
+func compareCanonicalNames(a string, b string) int {
+
+	la := strings.Split(strings.TrimSuffix(strings.ToLower(a), "."), ".")
+	lb := strings.Split(strings.TrimSuffix(strings.ToLower(b), "."), ".")
+
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(la[i], lb[j]); c != 0 {
+			return c
+		}
+	}
+
+	return len(la) - len(lb)
+}
+
+// typeOrder sorts SOA and NS in front of the other types of an owner name
+func typeOrder(rrType string) string {
+
+	switch strings.ToUpper(rrType) {
+	case "SOA":
+		return "0"
+	case "NS":
+		return "1"
+	}
+
+	return "2" + strings.ToUpper(rrType)
+}
+
+func mostCommonTTL(rrsets []*RRType) int {
+
+	counts := make(map[int]int)
+	ttl := defaultZoneFileTTL
+
+	for _, rrset := range rrsets {
+		counts[rrset.TTL]++
+		if counts[rrset.TTL] > counts[ttl] || (counts[rrset.TTL] == counts[ttl] && rrset.TTL < ttl) {
+			ttl = rrset.TTL
+		}
+	}
+
+	return ttl
+}
+
+// maxZoneFileIncludeDepth guards against $INCLUDE loops
+const maxZoneFileIncludeDepth = 16
+
+type zoneFileParser struct {
+	options *ZoneFileOptions
+	apex    string
+	records []*zoneFileRecord
+
+	// TTL for records without an explicit one ($TTL or last explicit TTL)
+	ttl int
+
+	// dollarTTL is set once a $TTL directive was read
+	dollarTTL bool
+}
+
+type zoneFileToken struct {
+	text   string
+	quoted bool
+}
+
+type zoneFileLine struct {
+	number     int
+	blankOwner bool
+	tokens     []zoneFileToken
+}
+
+func (p *zoneFileParser) parse(r io.Reader, file string, origin string, depth int) error {
+
+	data, err := ioutil.ReadAll(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+
+	lines, err := tokenizeZoneFile(string(data))
+	if err != nil {
+		return zoneFileError(file, 0, err)
+	}
+
+	owner := ""
+
+	for _, line := range lines {
+
+		first := line.tokens[0]
+
+		if !first.quoted && !line.blankOwner && strings.HasPrefix(first.text, "$") {
+
+			switch strings.ToUpper(first.text) {
+
+			case "$ORIGIN":
+				if len(line.tokens) < 2 {
+					return zoneFileError(file, line.number, fmt.Errorf("$ORIGIN without domain name"))
+				}
+				origin = absoluteName(line.tokens[1].text, origin)
+
+			case "$TTL":
+				if len(line.tokens) < 2 {
+					return zoneFileError(file, line.number, fmt.Errorf("$TTL without value"))
+				}
+				ttl, err := parseZoneFileTTL(line.tokens[1].text)
+				if err != nil {
+					return zoneFileError(file, line.number, err)
+				}
+				p.ttl = ttl
+				p.dollarTTL = true
+
+			case "$INCLUDE":
+				if len(line.tokens) < 2 {
+					return zoneFileError(file, line.number, fmt.Errorf("$INCLUDE without file name"))
+				}
+				includeOrigin := origin
+				if len(line.tokens) > 2 {
+					includeOrigin = absoluteName(line.tokens[2].text, origin)
+				}
+				if err := p.include(line.tokens[1].text, includeOrigin, depth); err != nil {
+					return zoneFileError(file, line.number, err)
+				}
+
+			default:
+				return zoneFileError(file, line.number, fmt.Errorf("unsupported directive %s", first.text))
+			}
+
+			continue
+		}
+
+		rr, err := p.parseRecord(line, &owner, origin)
+		if err != nil {
+			return zoneFileError(file, line.number, err)
+		}
+
+		p.records = append(p.records, rr)
+	}
+
+	return nil
+}
+
+func (p *zoneFileParser) include(name string, origin string, depth int) error {
+
+	if depth+1 > maxZoneFileIncludeDepth {
+		return fmt.Errorf("$INCLUDE nested too deeply")
+	}
+
+	path := name
+	if !filepath.IsAbs(path) && p.options.IncludeDir != "" {
+		path = filepath.Join(p.options.IncludeDir, path)
+	}
+
+	open := p.options.Open
+	if open == nil {
+		open = func(name string) (io.ReadCloser, error) {
+			return os.Open(name)
+		}
+	}
+
+	f, err := open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// The origin of the including file is restored after the include (RFC 1035, section 5.1)
+	return p.parse(f, name, origin, depth+1)
+}
+
+func (p *zoneFileParser) parseRecord(line zoneFileLine, owner *string, origin string) (*zoneFileRecord, error) {
+
+	tokens := line.tokens
+
+	if line.blankOwner {
+		if *owner == "" {
+			return nil, fmt.Errorf("record without owner name")
+		}
+	} else {
+		*owner = strings.ToLower(absoluteName(tokens[0].text, origin))
+		tokens = tokens[1:]
+	}
+
+	ttl := -1
+	rrType := ""
+
+	// [<TTL>] [<class>] <type> or [<class>] [<TTL>] <type>
+	for len(tokens) > 0 && rrType == "" {
+
+		t := tokens[0].text
+		tokens = tokens[1:]
+
+		if isZoneFileClass(t) {
+			if !strings.EqualFold(t, "IN") {
+				return nil, fmt.Errorf("unsupported class %s", t)
+			}
+			continue
+		}
+
+		if ttl < 0 && startsWithDigit(t) {
+			v, err := parseZoneFileTTL(t)
+			if err != nil {
+				return nil, err
+			}
+			ttl = v
+			continue
+		}
+
+		rrType = strings.ToUpper(t)
+	}
+
+	if rrType == "" {
+		return nil, fmt.Errorf("record for %s without type", *owner)
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%s record for %s without data", rrType, *owner)
+	}
+
+	if ttl < 0 {
+		ttl = p.ttl
+	} else if !p.dollarTTL {
+		// Records without TTL inherit the last explicitly stated TTL (RFC 1035, section 5.1)
+		// unless a $TTL directive is in effect (RFC 2308, section 4).
+		p.ttl = ttl
+	}
+
+	content, err := zoneFileContent(rrType, tokens, origin)
+	if err != nil {
+		return nil, fmt.Errorf("%s record for %s: %v", rrType, *owner, err)
+	}
+
+	return &zoneFileRecord{
+		Name:    *owner,
+		Type:    rrType,
+		TTL:     ttl,
+		Content: content,
+	}, nil
+}
+
+// zoneFileNameFields lists the rdata fields holding domain names, which must be made absolute
+var zoneFileNameFields = map[string][]int{
+	"NS":    {0},
+	"CNAME": {0},
+	"DNAME": {0},
+	"PTR":   {0},
+	"MX":    {1},
+	"KX":    {1},
+	"RT":    {1},
+	"AFSDB": {1},
+	"SRV":   {3},
+	"NAPTR": {5},
+	"RP":    {0, 1},
+	"SOA":   {0, 1},
+	"SVCB":  {1},
+	"HTTPS": {1},
+}
+
+// zoneFileContent renders the rdata tokens of a record as rcode0 record content
+func zoneFileContent(rrType string, tokens []zoneFileToken, origin string) (string, error) {
+
+	fields := make([]string, len(tokens))
+
+	for i, t := range tokens {
+		if t.quoted {
+			fields[i] = "\"" + t.text + "\""
+		} else {
+			fields[i] = t.text
+		}
+	}
+
+	switch rrType {
+
+	case "TXT", "SPF":
+		// Every character-string is quoted
+		for i, t := range tokens {
+			if !t.quoted {
+				fields[i] = "\"" + t.text + "\""
+			}
+		}
+
+	case "SOA":
+		if len(tokens) != 7 {
+			return "", fmt.Errorf("expected 7 fields, got %d", len(tokens))
+		}
+		for i := 2; i < 7; i++ {
+			v, err := parseZoneFileTTL(tokens[i].text)
+			if err != nil {
+				return "", err
+			}
+			fields[i] = strconv.Itoa(v)
+		}
+	}
+
+	for _, i := range zoneFileNameFields[rrType] {
+		if i >= len(tokens) {
+			return "", fmt.Errorf("expected at least %d fields, got %d", i+1, len(tokens))
+		}
+		if !tokens[i].quoted {
+			fields[i] = absoluteName(tokens[i].text, origin)
+		}
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// tokenizeZoneFile splits a zone file into logical lines, joining lines within parentheses
+// and dropping comments.
+func tokenizeZoneFile(data string) ([]zoneFileLine, error) {
+
+	var lines []zoneFileLine
+
+	number := 1
+	depth := 0
+	current := zoneFileLine{number: 1}
+	lineStart := true
+
+	var token strings.Builder
+	inToken := false
+
+	flush := func() {
+		if inToken {
+			current.tokens = append(current.tokens, zoneFileToken{text: token.String()})
+			token.Reset()
+			inToken = false
+		}
+	}
+
+	for i := 0; i < len(data); i++ {
+
+		c := data[i]
+
+		if lineStart {
+			lineStart = false
+			if depth == 0 {
+				current = zoneFileLine{number: number, blankOwner: c == ' ' || c == '\t'}
+			}
+		}
+
+		switch {
+
+		case c == '\\':
+			inToken = true
+			token.WriteByte(c)
+			if i+1 < len(data) {
+				i++
+				token.WriteByte(data[i])
+			}
+
+		case c == '"':
+			flush()
+			var quoted strings.Builder
+			closed := false
+			for i++; i < len(data); i++ {
+				if data[i] == '\\' && i+1 < len(data) {
+					quoted.WriteByte(data[i])
+					i++
+				} else if data[i] == '"' {
+					closed = true
+					break
+				}
+				if data[i] == '\n' {
+					number++
+				}
+				quoted.WriteByte(data[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("line %d: unterminated quoted string", current.number)
+			}
+			current.tokens = append(current.tokens, zoneFileToken{text: quoted.String(), quoted: true})
+
+		case c == ';':
+			flush()
+			for i+1 < len(data) && data[i+1] != '\n' {
+				i++
+			}
+
+		case c == '(':
+			flush()
+			depth++
+
+		case c == ')':
+			flush()
+			if depth == 0 {
+				return nil, fmt.Errorf("line %d: unbalanced parentheses", number)
+			}
+			depth--
+
+		case c == '\n':
+			flush()
+			number++
+			lineStart = true
+			if depth == 0 && len(current.tokens) > 0 {
+				lines = append(lines, current)
+				current = zoneFileLine{number: number}
+			}
+
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+
+		default:
+			inToken = true
+			token.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	if depth != 0 {
+		return nil, fmt.Errorf("line %d: unbalanced parentheses", current.number)
+	}
+
+	if len(current.tokens) > 0 {
+		lines = append(lines, current)
+	}
+
+	return lines, nil
+}
+
+// canonicalOrigin returns the given zone name in lower case with a trailing dot
+func canonicalOrigin(origin string) string {
+
+	origin = strings.ToLower(origin)
+
+	if !strings.HasSuffix(origin, ".") {
+		origin += "."
+	}
+
+	return origin
+}
+
+// absoluteName resolves "@" and relative names against the given origin
+func absoluteName(name string, origin string) string {
+
+	if name == "@" {
+		return origin
+	}
+
+	if strings.HasSuffix(name, ".") && !strings.HasSuffix(name, "\\.") {
+		return name
+	}
+
+	if origin == "." {
+		return name + "."
+	}
+
+	return name + "." + origin
+}
+
+func isZoneFileClass(s string) bool {
+
+	switch strings.ToUpper(s) {
+	case "IN", "CH", "CS", "HS":
+		return true
+	}
+
+	return false
+}
+
+func startsWithDigit(s string) bool {
+	return len(s) > 0 && s[0] >= '0' && s[0] <= '9'
+}
+
+// parseZoneFileTTL parses a TTL in seconds or in BIND notation (f.e. "1h30m", "2W")
+func parseZoneFileTTL(s string) (int, error) {
+
+	if v, err := strconv.Atoi(s); err == nil {
+		if v < 0 {
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+		return v, nil
+	}
+
+	total := 0
+	value := -1
+
+	for _, c := range strings.ToLower(s) {
+
+		if c >= '0' && c <= '9' {
+			if value < 0 {
+				value = 0
+			}
+			value = value*10 + int(c-'0')
+			continue
+		}
+
+		if value < 0 {
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+
+		switch c {
+		case 's':
+		case 'm':
+			value *= 60
+		case 'h':
+			value *= 3600
+		case 'd':
+			value *= 86400
+		case 'w':
+			value *= 604800
+		default:
+			return 0, fmt.Errorf("invalid TTL %q", s)
+		}
+
+		total += value
+		value = -1
+	}
+
+	if value >= 0 {
+		return 0, fmt.Errorf("invalid TTL %q", s)
+	}
+
+	return total, nil
+}
+
+func zoneFileError(file string, line int, err error) error {
+
+	if file == "" {
+		file = "zone file"
+	}
+
+	if line == 0 {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+
+	return fmt.Errorf("%s:%d: %v", file, line, err)
+}
@@ -0,0 +1,272 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package rc0go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testZoneFile = `
+$ORIGIN testzone1.at.
+$TTL 1h
+@	IN	SOA	ns1 hostmaster (
+		2019010101 ; serial
+		1d         ; refresh
+		2h         ; retry
+		4w         ; expire
+		1h )       ; minimum
+	IN	NS	ns1.example.net.
+	IN	NS	ns2.example.net.
+	IN	MX	10 mail
+	IN	TXT	"v=spf1 mx -all"
+www	300	IN	A	10.10.0.1
+	300	IN	A	10.10.0.2
+www	IN	AAAA	2001:db8::1
+ftp	CNAME	www
+_sip._tcp	SRV	10 60 5060 sip.testzone1.at.
+sub	NS	ns1.sub
+$ORIGIN sub.testzone1.at.
+ns1	A	10.10.1.1
+txt	TXT	foo "bar baz" ; comment
+`
+
+func TestParseZoneFile(t *testing.T) {
+
+	changeSet, err := ParseZoneFile(strings.NewReader(testZoneFile), NewZoneFileOptions("testzone1.at"))
+
+	if err != nil {
+		t.Fatalf("ParseZoneFile returned error: %v", err)
+	}
+
+	want := []*RRSetChange{
+		{Name: "testzone1.at.", Type: "MX", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "10 mail.testzone1.at."}}},
+		{Name: "testzone1.at.", Type: "TXT", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "\"v=spf1 mx -all\""}}},
+		{Name: "www.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 300, Records: []*Record{{Content: "10.10.0.1"}, {Content: "10.10.0.2"}}},
+		{Name: "www.testzone1.at.", Type: "AAAA", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "2001:db8::1"}}},
+		{Name: "ftp.testzone1.at.", Type: "CNAME", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "www.testzone1.at."}}},
+		{Name: "_sip._tcp.testzone1.at.", Type: "SRV", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "10 60 5060 sip.testzone1.at."}}},
+		{Name: "sub.testzone1.at.", Type: "NS", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "ns1.sub.testzone1.at."}}},
+		{Name: "ns1.sub.testzone1.at.", Type: "A", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "10.10.1.1"}}},
+		{Name: "txt.sub.testzone1.at.", Type: "TXT", ChangeType: ChangeTypeADD, TTL: 3600, Records: []*Record{{Content: "\"foo\" \"bar baz\""}}},
+	}
+
+	if !reflect.DeepEqual(changeSet, want) {
+		got, _ := json.Marshal(changeSet)
+		exp, _ := json.Marshal(want)
+		t.Errorf("ParseZoneFile returned %s, want %s", got, exp)
+	}
+}
+
+func TestParseZoneFile_SOAAndApexNS(t *testing.T) {
+
+	options := NewZoneFileOptions("testzone1.at.")
+	options.SkipSOA = false
+	options.SkipApexNS = false
+
+	changeSet, err := ParseZoneFile(strings.NewReader(testZoneFile), options)
+
+	if err != nil {
+		t.Fatalf("ParseZoneFile returned error: %v", err)
+	}
+
+	if changeSet[0].Type != "SOA" {
+		t.Fatalf("ParseZoneFile returned %v as first rrset, want SOA", changeSet[0].Type)
+	}
+
+	wantSOA := "ns1.testzone1.at. hostmaster.testzone1.at. 2019010101 86400 7200 2419200 3600"
+	if got := changeSet[0].Records[0].Content; got != wantSOA {
+		t.Errorf("ParseZoneFile returned SOA %q, want %q", got, wantSOA)
+	}
+
+	if changeSet[1].Type != "NS" || len(changeSet[1].Records) != 2 {
+		t.Errorf("ParseZoneFile returned %+v, want apex NS rrset with 2 records", changeSet[1])
+	}
+}
+
+func TestParseZoneFile_Include(t *testing.T) {
+
+	files := map[string]string{
+		"/zones/hosts.inc": "host1 A 10.10.0.3\nhost2 A 10.10.0.4\n",
+	}
+
+	options := NewZoneFileOptions("testzone1.at")
+	options.IncludeDir = "/zones"
+	options.Open = func(name string) (io.ReadCloser, error) {
+		content, ok := files[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return ioutil.NopCloser(strings.NewReader(content)), nil
+	}
+
+	zone := "$TTL 600\n$INCLUDE hosts.inc lab.testzone1.at.\nwww A 10.10.0.1\n"
+
+	changeSet, err := ParseZoneFile(strings.NewReader(zone), options)
+
+	if err != nil {
+		t.Fatalf("ParseZoneFile returned error: %v", err)
+	}
+
+	var names []string
+	for _, c := range changeSet {
+		names = append(names, c.Name)
+		if c.TTL != 600 {
+			t.Errorf("ParseZoneFile returned TTL %d for %s, want 600", c.TTL, c.Name)
+		}
+	}
+
+	wantNames := []string{"host1.lab.testzone1.at.", "host2.lab.testzone1.at.", "www.testzone1.at."}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("ParseZoneFile returned names %v, want %v", names, wantNames)
+	}
+}
+
+func TestParseZoneFile_Errors(t *testing.T) {
+
+	tests := map[string]string{
+		"unbalanced":   "@ SOA ns1 hostmaster ( 1 2 3 4 5\n",
+		"unterminated": "txt TXT \"foo\n",
+		"no owner":     "  A 10.10.0.1\n",
+		"no type":      "www 300 IN\n",
+		"bad ttl":      "$TTL 1x\n",
+		"directive":    "$GENERATE 1-10 host$ A 10.0.0.$\n",
+		"class":        "www CH A 10.10.0.1\n",
+	}
+
+	for name, zone := range tests {
+		if _, err := ParseZoneFile(strings.NewReader(zone), NewZoneFileOptions("testzone1.at")); err == nil {
+			t.Errorf("ParseZoneFile(%s) returned no error", name)
+		}
+	}
+}
+
+func TestRRSetService_ImportZoneFile(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	want := &StatusResponse{Status: "ok", Message: "RRsets updated"}
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PATCH")
+
+		body, _ := ioutil.ReadAll(r.Body)
+
+		var received []*RRSetChange
+		_ = json.Unmarshal(body, &received)
+
+		if len(received) != 9 {
+			t.Errorf("RRSet.ImportZoneFile submitted %d rrsets, want 9", len(received))
+		}
+
+		_json, _ := json.Marshal(want)
+		_, _ = fmt.Fprint(w, string(_json))
+	})
+
+	status, err := client.RRSet.ImportZoneFile("testzone1.at", strings.NewReader(testZoneFile), nil)
+	if err != nil {
+		t.Errorf("RRSet.ImportZoneFile returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("RRSet.ImportZoneFile returned %+v, want %+v", status, want)
+	}
+}
+
+func TestWriteZoneFile(t *testing.T) {
+
+	rrsets := []*RRType{
+		{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*Record{{Content: "10.10.0.2"}, {Content: "10.10.0.1"}}},
+		{Name: "testzone1.at.", Type: "MX", TTL: 3600, Records: []*Record{{Content: "10 mail.testzone1.at."}}},
+		{Name: "old.testzone1.at.", Type: "A", TTL: 300, Records: []*Record{{Content: "10.10.0.9", Disabled: true}}},
+		{Name: "testzone1.at.", Type: "NS", TTL: 86400, Records: []*Record{{Content: "sec1.rcode0.net."}}},
+	}
+
+	var buf strings.Builder
+
+	err := WriteZoneFile(&buf, "testzone1.at", rrsets, &ZoneFileExportOptions{Relativize: true})
+	if err != nil {
+		t.Fatalf("WriteZoneFile returned error: %v", err)
+	}
+
+	want := "$ORIGIN testzone1.at.\n" +
+		"$TTL 3600\n" +
+		"@\t86400\tIN\tNS\tsec1.rcode0.net.\n" +
+		"@\t\tIN\tMX\t10 mail.testzone1.at.\n" +
+		";old\t300\tIN\tA\t10.10.0.9\n" +
+		"www\t\tIN\tA\t10.10.0.1\n" +
+		"www\t\tIN\tA\t10.10.0.2\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("WriteZoneFile returned\n%s\nwant\n%s", got, want)
+	}
+
+	// The exported zone file can be imported again
+	options := NewZoneFileOptions("testzone1.at")
+	options.SkipApexNS = false
+
+	changeSet, err := ParseZoneFile(strings.NewReader(buf.String()), options)
+	if err != nil {
+		t.Fatalf("ParseZoneFile returned error: %v", err)
+	}
+
+	if len(changeSet) != 3 {
+		t.Errorf("ParseZoneFile returned %d rrsets, want 3", len(changeSet))
+	}
+}
+
+func TestRRSetService_ExportZoneFile(t *testing.T) {
+
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc(RC0ZoneRRSets, func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+
+		data := getTestDataPaginated(reflect.TypeOf(RRType{}))
+		data["last_page"] = 2
+
+		if r.URL.Query().Get("page") == "2" {
+			data["current_page"] = 2
+			data["data"] = []interface{}{
+				map[string]interface{}{
+					"name":    "mail.testzone1.at.",
+					"type":    "A",
+					"ttl":     3600,
+					"records": []interface{}{map[string]interface{}{"content": "10.10.0.3"}},
+				},
+			}
+		}
+
+		dat, _ := json.Marshal(data)
+		_, _ = fmt.Fprint(w, string(dat))
+	})
+
+	var buf strings.Builder
+
+	err := client.RRSet.ExportZoneFile(context.Background(), "testzone1.at", &buf, nil)
+	if err != nil {
+		t.Fatalf("RRSet.ExportZoneFile returned error: %v", err)
+	}
+
+	want := "$ORIGIN testzone1.at.\n" +
+		"$TTL 3600\n" +
+		"mail.testzone1.at.\t\tIN\tA\t10.10.0.3\n" +
+		"www.testzone1.at.\t\tIN\tA\t10.10.0.2\n"
+
+	if got := buf.String(); got != want {
+		t.Errorf("RRSet.ExportZoneFile returned\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,279 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package zonetemplate
+
+import (
+	"context"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// ConflictPolicy decides what happens to existing rrsets which differ from the template
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the existing rrset
+	ConflictSkip ConflictPolicy = "skip"
+
+	// ConflictOverwrite replaces the existing rrset with the one of the template
+	ConflictOverwrite ConflictPolicy = "overwrite"
+
+	// ConflictFail applies nothing to the zone
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// Applier applies templates to zones
+type Applier struct {
+	RRSet rc0go.RRSetServiceInterface
+
+	// Policy for conflicting rrsets (defaults to ConflictFail)
+	Policy ConflictPolicy
+
+	// MarkerName, if set, adds a TXT rrset with this name (f.e. "_template") to each zone
+	// recording template name and version, f.e. "template=customer-default version=3"
+	MarkerName string
+}
+
+// ZoneReport is the result of applying a template to a single zone
+type ZoneReport struct {
+	Zone    string
+	Version string
+
+	// Applied holds the submitted changes, Conflicts the rrsets which differed from the
+	// template or cannot coexist with an existing CNAME and Unchanged the ones which
+	// already matched it
+	Applied   []*rc0go.RRSetChange
+	Conflicts []*rc0go.RRSetChange
+	Unchanged []*rc0go.RRSetChange
+
+	Err error
+}
+
+// Report lists which template version was applied to which zone
+type Report struct {
+	Template string
+	Version  string
+	Policy   ConflictPolicy
+	Time     time.Time
+	Zones    []*ZoneReport
+}
+
+// Failed returns the zones the template could not be applied to
+func (r *Report) Failed() []*ZoneReport {
+
+	var failed []*ZoneReport
+
+	for _, z := range r.Zones {
+		if z.Err != nil {
+			failed = append(failed, z)
+		}
+	}
+
+	return failed
+}
+
+// String returns one line per zone with its version and the number of applied,
+// conflicting and unchanged rrsets
+func (r *Report) String() string {
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "template %s version %s (conflicts: %s)\n", r.Template, r.Version, r.Policy)
+
+	w := tabwriter.NewWriter(&b, 0, 8, 2, ' ', 0)
+
+	for _, z := range r.Zones {
+
+		result := "ok"
+		if z.Err != nil {
+			result = "failed: " + z.Err.Error()
+		}
+
+		fmt.Fprintf(w, "%s\t%s\tapplied=%d\tconflicts=%d\tunchanged=%d\t%s\n",
+			z.Zone, z.Version, len(z.Applied), len(z.Conflicts), len(z.Unchanged), result)
+	}
+
+	_ = w.Flush()
+
+	return b.String()
+}
+
+// Apply renders the template for every zone and submits the missing and, depending on the
+// policy, the conflicting rrsets with one SubmitChangeSet per zone. vars holds the variables
+// per zone. A failing zone does not stop the others; the returned error lists the failed zones.
+// An unknown policy is rejected before any zone is changed.
+func (a *Applier) Apply(ctx context.Context, t *Template, zones []string, vars map[string]map[string]string) (*Report, error) {
+
+	policy := a.Policy
+	if policy == "" {
+		policy = ConflictFail
+	}
+
+	switch policy {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return nil, fmt.Errorf("unknown conflict policy %q (must be %s, %s or %s)", policy, ConflictSkip, ConflictOverwrite, ConflictFail)
+	}
+
+	report := &Report{Template: t.Name, Version: t.Version, Policy: policy, Time: time.Now()}
+
+	var failed []string
+
+	for _, zone := range zones {
+
+		z := &ZoneReport{Zone: strings.TrimSuffix(strings.ToLower(zone), ".")}
+		report.Zones = append(report.Zones, z)
+
+		if err := ctx.Err(); err != nil {
+			z.Err = err
+		} else {
+			z.Err = a.applyZone(ctx, t, policy, z, vars[z.Zone])
+		}
+
+		if z.Err != nil {
+			failed = append(failed, z.Zone)
+		}
+	}
+
+	if len(failed) > 0 {
+		return report, fmt.Errorf("template %s could not be applied to %d zone(s): %s",
+			t.Name, len(failed), strings.Join(failed, ", "))
+	}
+
+	return report, nil
+}
+
+func (a *Applier) applyZone(ctx context.Context, t *Template, policy ConflictPolicy, z *ZoneReport, vars map[string]string) error {
+
+	rendered, err := t.Render(z.Zone, vars)
+	if err != nil {
+		return err
+	}
+
+	marker := ""
+
+	if a.MarkerName != "" {
+		marker = absoluteName(a.MarkerName, rc0go.NormalizeName(z.Zone))
+		rendered = append(rendered, &rc0go.RRSetChange{
+			Name:       marker,
+			Type:       "TXT",
+			ChangeType: rc0go.ChangeTypeADD,
+			TTL:        t.TTL,
+			Records:    []*rc0go.Record{{Content: rc0go.QuoteTXT(fmt.Sprintf("template=%s version=%s", t.Name, t.Version))}},
+		})
+	}
+
+	current, err := a.RRSet.ListAll(ctx, z.Zone)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]*rc0go.RRType)
+	types := make(map[string][]string)
+	for _, rrset := range current {
+		name, rrType := rc0go.NormalizeName(rrset.Name), strings.ToUpper(rrset.Type)
+		existing[name+" "+rrType] = rrset
+		types[name] = append(types[name], rrType)
+	}
+
+	var changeSet []*rc0go.RRSetChange
+	deleted := make(map[string]bool)
+
+	for _, change := range rendered {
+
+		rrset, ok := existing[change.Name+" "+change.Type]
+
+		// existing rrsets which cannot coexist with the new one because of a CNAME
+		var blocking []string
+		if !ok {
+			blocking = cnameConflicts(change.Type, types[change.Name])
+		}
+
+		switch {
+
+		case !ok && len(blocking) == 0:
+			changeSet = append(changeSet, change)
+
+		case !ok:
+			z.Conflicts = append(z.Conflicts, change)
+
+			if policy == ConflictOverwrite {
+				for _, rrType := range blocking {
+					if key := change.Name + " " + rrType; !deleted[key] {
+						deleted[key] = true
+						changeSet = append(changeSet, &rc0go.RRSetChange{Name: change.Name, Type: rrType, ChangeType: rc0go.ChangeTypeDELETE})
+					}
+				}
+				changeSet = append(changeSet, change)
+			}
+
+		case rrset.TTL == change.TTL && rc0go.EqualRecords(change.Type, rrset.Records, change.Records):
+			z.Unchanged = append(z.Unchanged, change)
+
+		case change.Name == marker && change.Type == "TXT":
+			// the marker always records the latest version
+			change.ChangeType = rc0go.ChangeTypeUPDATE
+			changeSet = append(changeSet, change)
+
+		default:
+			z.Conflicts = append(z.Conflicts, change)
+
+			if policy == ConflictOverwrite {
+				change.ChangeType = rc0go.ChangeTypeUPDATE
+				changeSet = append(changeSet, change)
+			}
+		}
+	}
+
+	if len(z.Conflicts) > 0 && policy == ConflictFail {
+		return fmt.Errorf("%d rrset(s) differ from the template, f.e. %s %s",
+			len(z.Conflicts), z.Conflicts[0].Name, z.Conflicts[0].Type)
+	}
+
+	for _, finding := range rc0go.CheckChangeSet(z.Zone, current, changeSet) {
+		if finding.Severity == rc0go.SeverityError {
+			return fmt.Errorf("invalid change set: %s", finding)
+		}
+	}
+
+	if len(changeSet) > 0 {
+
+		status, err := a.RRSet.SubmitChangeSet(z.Zone, changeSet)
+		if err != nil {
+			return err
+		}
+
+		if status.HasError() {
+			return fmt.Errorf("%s", status.Message)
+		}
+	}
+
+	z.Applied = changeSet
+	z.Version = t.Version
+
+	return nil
+}
+
+// cnameConflicts returns the existing types at a name which cannot coexist with an rrset of
+// the type, f.e. an A rrset next to an existing CNAME. DNSSEC types may coexist with a CNAME.
+func cnameConflicts(rrType string, existing []string) []string {
+
+	var conflicts []string
+
+	for _, t := range existing {
+		switch {
+		case t == rrType, t == "RRSIG", t == "NSEC", t == "NSEC3":
+		case rrType == "RRSIG", rrType == "NSEC", rrType == "NSEC3":
+		case rrType == "CNAME", t == "CNAME":
+			conflicts = append(conflicts, t)
+		}
+	}
+
+	return conflicts
+}
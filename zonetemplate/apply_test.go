@@ -0,0 +1,193 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package zonetemplate
+
+import (
+	"context"
+	"github.com/nic-at/rc0go"
+	"github.com/nic-at/rc0go/rc0test"
+	"strings"
+	"testing"
+)
+
+func setup() *rc0test.Server {
+
+	server := rc0test.NewServer()
+
+	server.AddZone("testzone1.at")
+	server.AddZone("testzone2.at",
+		&rc0go.RRType{Name: "testzone2.at.", Type: "MX", TTL: 3600, Records: []*rc0go.Record{{Content: "10 mx.other.net."}}},
+		&rc0go.RRType{Name: "www.testzone2.at.", Type: "CNAME", TTL: 3600, Records: []*rc0go.Record{{Content: "testzone2.at."}}},
+	)
+
+	return server
+}
+
+func TestApplier_Apply(t *testing.T) {
+
+	tmpl := mustParse(t, testTemplate)
+
+	tests := []struct {
+		policy  ConflictPolicy
+		applied int
+		failed  bool
+	}{
+		{ConflictSkip, 4, false},
+		{ConflictOverwrite, 5, false},
+		{ConflictFail, 0, true},
+	}
+
+	for _, test := range tests {
+
+		server := setup()
+
+		applier := &Applier{RRSet: server.Client().RRSet, Policy: test.policy, MarkerName: "_template"}
+
+		report, err := applier.Apply(context.Background(), tmpl, []string{"testzone1.at", "testzone2.at"}, nil)
+
+		if (err != nil) != test.failed {
+			t.Errorf("Applier.Apply with policy %s returned error %v", test.policy, err)
+		}
+
+		if got := len(report.Zones[0].Applied); got != 6 {
+			t.Errorf("Applier.Apply with policy %s applied %d rrsets to testzone1.at, want 6", test.policy, got)
+		}
+
+		z := report.Zones[1]
+
+		if len(z.Conflicts) != 1 || len(z.Unchanged) != 1 {
+			t.Errorf("Applier.Apply with policy %s reported %d conflicts and %d unchanged rrsets, want 1 and 1",
+				test.policy, len(z.Conflicts), len(z.Unchanged))
+		}
+
+		// the template rrsets plus the marker
+		if got := len(z.Applied); test.applied > 0 && got != test.applied {
+			t.Errorf("Applier.Apply with policy %s applied %d rrsets to testzone2.at, want %d", test.policy, got, test.applied)
+		}
+
+		if test.failed && (len(server.Patches("testzone2.at")) != 0 || z.Version != "") {
+			t.Errorf("Applier.Apply with policy %s changed testzone2.at", test.policy)
+		}
+
+		if !test.failed && z.Version != "3" {
+			t.Errorf("Applier.Apply with policy %s reported version %q, want 3", test.policy, z.Version)
+		}
+
+		if !strings.Contains(report.String(), "testzone1.at  3") {
+			t.Errorf("Report.String returned %q", report.String())
+		}
+
+		server.Close()
+	}
+}
+
+func TestApplier_Apply_Idempotent(t *testing.T) {
+
+	server := setup()
+	defer server.Close()
+
+	applier := &Applier{RRSet: server.Client().RRSet, Policy: ConflictSkip}
+
+	tmpl := mustParse(t, testTemplate)
+
+	if _, err := applier.Apply(context.Background(), tmpl, []string{"testzone1.at"}, nil); err != nil {
+		t.Fatalf("Applier.Apply returned error: %v", err)
+	}
+
+	report, err := applier.Apply(context.Background(), tmpl, []string{"testzone1.at"}, nil)
+	if err != nil {
+		t.Fatalf("Applier.Apply returned error: %v", err)
+	}
+
+	if z := report.Zones[0]; len(z.Applied) != 0 || len(z.Unchanged) != 5 {
+		t.Errorf("Applier.Apply reapplied %d rrsets, %d unchanged", len(z.Applied), len(z.Unchanged))
+	}
+
+	if got := len(server.Patches("testzone1.at")); got != 1 {
+		t.Errorf("Applier.Apply submitted %d change sets, want 1", got)
+	}
+}
+
+const cnameTemplate = `
+name: cname
+version: "1"
+ttl: 3600
+rrsets:
+  - name: www
+    type: CNAME
+    records: ["{{ .Origin }}"]
+  - name: mail
+    type: A
+    records: ["192.0.2.25"]
+`
+
+func TestApplier_Apply_CNAMEConflicts(t *testing.T) {
+
+	tmpl := mustParse(t, cnameTemplate)
+
+	tests := []struct {
+		policy ConflictPolicy
+		failed bool
+		want   map[string]bool
+	}{
+		{ConflictSkip, false, map[string]bool{"www.testzone1.at. A": true, "mail.testzone1.at. CNAME": true}},
+		{ConflictOverwrite, false, map[string]bool{"www.testzone1.at. CNAME": true, "mail.testzone1.at. A": true}},
+		{ConflictFail, true, map[string]bool{"www.testzone1.at. A": true, "mail.testzone1.at. CNAME": true}},
+	}
+
+	for _, test := range tests {
+
+		server := rc0test.NewServer()
+		server.AddZone("testzone1.at",
+			&rc0go.RRType{Name: "www.testzone1.at.", Type: "A", TTL: 3600, Records: []*rc0go.Record{{Content: "192.0.2.80"}}},
+			&rc0go.RRType{Name: "mail.testzone1.at.", Type: "CNAME", TTL: 3600, Records: []*rc0go.Record{{Content: "mail.example.net."}}},
+		)
+
+		applier := &Applier{RRSet: server.Client().RRSet, Policy: test.policy}
+
+		report, err := applier.Apply(context.Background(), tmpl, []string{"testzone1.at"}, nil)
+
+		if (err != nil) != test.failed {
+			t.Errorf("Applier.Apply with policy %s returned error %v", test.policy, err)
+		}
+
+		if got := len(report.Zones[0].Conflicts); got != 2 {
+			t.Errorf("Applier.Apply with policy %s reported %d conflicts, want 2", test.policy, got)
+		}
+
+		got := make(map[string]bool)
+		for _, rrset := range server.RRSets("testzone1.at") {
+			got[rrset.Name+" "+rrset.Type] = true
+		}
+
+		if len(got) != len(test.want) {
+			t.Errorf("Applier.Apply with policy %s left rrsets %v, want %v", test.policy, got, test.want)
+		}
+		for key := range test.want {
+			if !got[key] {
+				t.Errorf("Applier.Apply with policy %s left rrsets %v, want %v", test.policy, got, test.want)
+			}
+		}
+
+		server.Close()
+	}
+}
+
+func TestApplier_Apply_UnknownPolicy(t *testing.T) {
+
+	server := setup()
+	defer server.Close()
+
+	applier := &Applier{RRSet: server.Client().RRSet, Policy: "replace"}
+
+	if _, err := applier.Apply(context.Background(), mustParse(t, testTemplate), []string{"testzone1.at"}, nil); err == nil {
+		t.Errorf("Applier.Apply with an unknown policy returned no error")
+	}
+
+	if got := len(server.Patches("testzone1.at")); got != 0 {
+		t.Errorf("Applier.Apply with an unknown policy submitted %d change sets", got)
+	}
+}
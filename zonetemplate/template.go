@@ -0,0 +1,207 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+// Package zonetemplate applies a standard bundle of rrsets (f.e. MX, SPF, DMARC, CAA and www)
+// to many zones. Templates are written in YAML, names and records may use text/template
+// variables:
+//
+//	name: customer-default
+//	version: "3"
+//	ttl: 3600
+//	variables:
+//	  mx: mail.example.net.
+//	rrsets:
+//	  - name: "@"
+//	    type: MX
+//	    records: ["10 {{ .Vars.mx }}"]
+//	  - name: _dmarc
+//	    type: TXT
+//	    records: ['"v=DMARC1; p=reject; rua=mailto:dmarc@{{ .Zone }}"']
+//
+// The template data holds the zone name without (Zone) and with trailing dot (Origin) and
+// the variables (Vars), defaults from the template merged with the ones given per zone.
+package zonetemplate
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/nic-at/rc0go"
+	"gopkg.in/yaml.v3"
+	"io"
+	"strings"
+	"text/template"
+)
+
+const defaultTTL = 3600
+
+// Template is a bundle of rrsets applied to zones
+type Template struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+
+	// TTL of rrsets without own TTL (defaults to 3600)
+	TTL int `yaml:"ttl"`
+
+	// Variables holds default values for the template variables
+	Variables map[string]string `yaml:"variables"`
+
+	RRSets []*TemplateRRSet `yaml:"rrsets"`
+
+	names   []*template.Template
+	records [][]*template.Template
+}
+
+// TemplateRRSet is an rrset of a template. Name is relative to the zone, "@" is the apex.
+type TemplateRRSet struct {
+	Name    string   `yaml:"name"`
+	Type    string   `yaml:"type"`
+	TTL     int      `yaml:"ttl"`
+	Records []string `yaml:"records"`
+}
+
+// templateData is passed to the name and record templates
+type templateData struct {
+	Zone   string
+	Origin string
+	Vars   map[string]string
+}
+
+// ParseTemplate reads a YAML template and compiles its names and records. Unknown keys
+// and missing variables are errors.
+func ParseTemplate(r io.Reader) (*Template, error) {
+
+	var t Template
+
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(&t); err != nil {
+		return nil, fmt.Errorf("template: %v", err)
+	}
+
+	if t.Name == "" || t.Version == "" {
+		return nil, fmt.Errorf("template: name and version are required")
+	}
+
+	if len(t.RRSets) == 0 {
+		return nil, fmt.Errorf("template %s: no rrsets", t.Name)
+	}
+
+	if t.TTL <= 0 {
+		t.TTL = defaultTTL
+	}
+
+	for i, rrset := range t.RRSets {
+
+		if rrset.Type == "" || len(rrset.Records) == 0 {
+			return nil, fmt.Errorf("template %s: rrset %d needs a type and records", t.Name, i+1)
+		}
+
+		name, err := compile(rrset.Name)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: rrset %d: %v", t.Name, i+1, err)
+		}
+
+		var records []*template.Template
+		for _, record := range rrset.Records {
+			tmpl, err := compile(record)
+			if err != nil {
+				return nil, fmt.Errorf("template %s: rrset %d: %v", t.Name, i+1, err)
+			}
+			records = append(records, tmpl)
+		}
+
+		t.names = append(t.names, name)
+		t.records = append(t.records, records)
+	}
+
+	return &t, nil
+}
+
+// Render returns the rrsets of the template for the zone as ChangeTypeADD changes.
+// vars overrides the default variables of the template. Rendered records are validated.
+func (t *Template) Render(zone string, vars map[string]string) ([]*rc0go.RRSetChange, error) {
+
+	data := &templateData{
+		Zone:   strings.TrimSuffix(strings.ToLower(zone), "."),
+		Origin: rc0go.NormalizeName(zone),
+		Vars:   make(map[string]string),
+	}
+
+	for k, v := range t.Variables {
+		data.Vars[k] = v
+	}
+
+	for k, v := range vars {
+		data.Vars[k] = v
+	}
+
+	var changes []*rc0go.RRSetChange
+
+	for i, rrset := range t.RRSets {
+
+		name, err := execute(t.names[i], data)
+		if err != nil {
+			return nil, fmt.Errorf("template %s, zone %s: %v", t.Name, data.Zone, err)
+		}
+
+		change := &rc0go.RRSetChange{
+			Name:       absoluteName(name, data.Origin),
+			Type:       strings.ToUpper(rrset.Type),
+			ChangeType: rc0go.ChangeTypeADD,
+			TTL:        rrset.TTL,
+		}
+
+		if change.TTL <= 0 {
+			change.TTL = t.TTL
+		}
+
+		for _, tmpl := range t.records[i] {
+
+			content, err := execute(tmpl, data)
+			if err != nil {
+				return nil, fmt.Errorf("template %s, zone %s: %v", t.Name, data.Zone, err)
+			}
+
+			change.Records = append(change.Records, &rc0go.Record{Content: content})
+		}
+
+		if err := change.Validate(); err != nil {
+			return nil, fmt.Errorf("template %s, zone %s: %v", t.Name, data.Zone, err)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, nil
+}
+
+func compile(text string) (*template.Template, error) {
+	return template.New("").Option("missingkey=error").Parse(text)
+}
+
+func execute(tmpl *template.Template, data *templateData) (string, error) {
+
+	var b bytes.Buffer
+
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// absoluteName returns the fully qualified name, "@" and "" are the origin
+func absoluteName(name string, origin string) string {
+
+	switch {
+	case name == "@" || name == "":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return rc0go.NormalizeName(name)
+	}
+
+	return rc0go.NormalizeName(name + "." + origin)
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 nic.at GmbH. All rights reserved.
+//
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package zonetemplate
+
+import (
+	"github.com/nic-at/rc0go"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testTemplate = `
+name: customer-default
+version: "3"
+ttl: 3600
+variables:
+  mx: mail.example.net.
+  spf: _spf.example.net
+rrsets:
+  - name: "@"
+    type: MX
+    records: ["10 {{ .Vars.mx }}"]
+  - name: "@"
+    type: TXT
+    records: ['"v=spf1 include:{{ .Vars.spf }} -all"']
+  - name: _dmarc
+    type: TXT
+    ttl: 300
+    records: ['"v=DMARC1; p=reject; rua=mailto:dmarc@{{ .Zone }}"']
+  - name: "@"
+    type: CAA
+    records: ['0 issue "letsencrypt.org"']
+  - name: www
+    type: CNAME
+    records: ["{{ .Origin }}"]
+`
+
+func mustParse(t *testing.T, text string) *Template {
+
+	tmpl, err := ParseTemplate(strings.NewReader(text))
+	if err != nil {
+		t.Fatalf("ParseTemplate returned error: %v", err)
+	}
+
+	return tmpl
+}
+
+func TestTemplate_Render(t *testing.T) {
+
+	tmpl := mustParse(t, testTemplate)
+
+	changes, err := tmpl.Render("Testzone1.at", map[string]string{"mx": "mx.testzone1.at."})
+	if err != nil {
+		t.Fatalf("Template.Render returned error: %v", err)
+	}
+
+	want := []*rc0go.RRSetChange{
+		{Name: "testzone1.at.", Type: "MX", ChangeType: rc0go.ChangeTypeADD, TTL: 3600, Records: []*rc0go.Record{{Content: "10 mx.testzone1.at."}}},
+		{Name: "testzone1.at.", Type: "TXT", ChangeType: rc0go.ChangeTypeADD, TTL: 3600, Records: []*rc0go.Record{{Content: `"v=spf1 include:_spf.example.net -all"`}}},
+		{Name: "_dmarc.testzone1.at.", Type: "TXT", ChangeType: rc0go.ChangeTypeADD, TTL: 300, Records: []*rc0go.Record{{Content: `"v=DMARC1; p=reject; rua=mailto:dmarc@testzone1.at"`}}},
+		{Name: "testzone1.at.", Type: "CAA", ChangeType: rc0go.ChangeTypeADD, TTL: 3600, Records: []*rc0go.Record{{Content: `0 issue "letsencrypt.org"`}}},
+		{Name: "www.testzone1.at.", Type: "CNAME", ChangeType: rc0go.ChangeTypeADD, TTL: 3600, Records: []*rc0go.Record{{Content: "testzone1.at."}}},
+	}
+
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Template.Render returned %+v, want %+v", changes, want)
+	}
+}
+
+func TestTemplate_Invalid(t *testing.T) {
+
+	invalid := []string{
+		"name: x\nversion: 1\nrrsets: []\n",
+		"name: x\nversion: 1\nunknown: true\nrrsets: [{name: www, type: A, records: [10.0.0.1]}]\n",
+		"name: x\nversion: 1\nrrsets: [{name: www, type: A, records: ['{{ .Vars.ip']}]\n",
+	}
+
+	for _, text := range invalid {
+		if _, err := ParseTemplate(strings.NewReader(text)); err == nil {
+			t.Errorf("ParseTemplate(%q) returned no error", text)
+		}
+	}
+
+	tmpl := mustParse(t, "name: x\nversion: 1\nrrsets: [{name: www, type: A, records: ['{{ .Vars.ip }}']}]\n")
+
+	if _, err := tmpl.Render("testzone1.at", nil); err == nil {
+		t.Errorf("Template.Render returned no error for a missing variable")
+	}
+
+	if _, err := tmpl.Render("testzone1.at", map[string]string{"ip": "10.0.0.256"}); err == nil {
+		t.Errorf("Template.Render returned no error for an invalid record")
+	}
+}